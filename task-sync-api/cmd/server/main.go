@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/config"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/handlers"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/middleware"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/server"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/tracing"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/webhook"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,50 +25,194 @@ import (
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid config:\n", err)
+	}
+	models.MaxTitleLength = cfg.MaxTitleLength
+	models.MaxDescriptionLength = cfg.MaxDescriptionLength
+	models.TaskDataCompressThreshold = cfg.TaskDataCompressThreshold
+	models.MaxMetadataBytes = cfg.MaxMetadataBytes
+	models.DescriptionOverflow = models.DescriptionOverflowPolicy(cfg.DescriptionOverflowPolicy)
+	// Validate has already checked the time zone and log level
+	if cfg.ResponseTimeZone != "" {
+		models.ResponseTimeZone, _ = time.LoadLocation(cfg.ResponseTimeZone)
+	}
+
+	// run returns rather than exiting so its deferred cleanup always happens
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Server stopped")
+}
+
+// run serves the API until SIGINT or SIGTERM, then shuts down gracefully.
+func run(cfg *config.Config) error {
+	// Spans go nowhere unless a collector is configured
+	if cfg.OTLPEndpoint != "" {
+		exporter := tracing.NewOTLPExporter(cfg.OTLPEndpoint, cfg.ServiceName, nil)
+		defer exporter.Close()
+		tracing.SetProvider(tracing.NewProvider(exporter))
+	}
 
 	// Initialize database
-	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	pool := database.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+		BusyTimeoutMS:   cfg.DBBusyTimeoutMS,
+	}
+	db, err := database.NewSQLiteDBWithPool(cfg.DatabasePath, pool)
 	if err != nil {
-		log.Fatal("Failed to initialize database:", err)
+		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer db.Close()
+	if cfg.DatabaseReadPath != "" {
+		if err := db.OpenReader(cfg.DatabaseReadPath, pool); err != nil {
+			return fmt.Errorf("failed to open read database: %w", err)
+		}
+	}
+	stopBackups := db.StartBackups(cfg.BackupDir, cfg.BackupInterval, cfg.BackupKeep)
+	defer stopBackups()
 
 	// Initialize services
 	syncService := services.NewSyncService(db, cfg)
 	taskService := services.NewTaskService(db, syncService)
+	// Notifiers are closed once the server has drained, so the events of the
+	// last requests are still delivered
+	var notifiers []*webhook.WebhookNotifier
+	if cfg.WebhookURL != "" {
+		notifier := webhook.NewWebhookNotifier(cfg.WebhookURL, nil, cfg.MaxRetries)
+		notifiers = append(notifiers, notifier)
+		taskService.SetNotifier(notifier)
+	}
+	if cfg.SyncWebhookURL != "" {
+		notifier := webhook.NewWebhookNotifier(cfg.SyncWebhookURL, nil, cfg.MaxRetries)
+		notifiers = append(notifiers, notifier)
+		syncService.SetRunNotifier(notifier)
+	}
 
 	// Initialize handlers
 	taskHandler := handlers.NewTaskHandler(taskService)
+	taskHandler.SetHardDelete(cfg.HardDelete)
+	taskHandler.SetRequireIfMatch(cfg.RequireIfMatch)
 	syncHandler := handlers.NewSyncHandler(syncService)
+	syncHandler.SetPassTimeout(cfg.SyncPassTimeout)
+	healthHandler := handlers.NewHealthHandler(db, syncService)
+	adminHandler := handlers.NewAdminHandler(db)
+	adminHandler.SetBackup(cfg.BackupDir, cfg.BackupKeep)
 
 	// Setup router
-	router := gin.Default()
+	logLevel, _ := middleware.ParseLogLevel(cfg.LogLevel)
+	if logLevel != middleware.LogLevelDebug {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.New()
 
 	// Add logging middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.RequestLogger(logLevel, log.Default()))
+	// Recovery runs inside Gzip so a panic's JSON response is still compressed
+	router.Use(middleware.Gzip())
+	router.Use(middleware.Recovery(log.Default()))
+	router.Use(middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins: cfg.CORSAllowedOrigins,
+		AllowedMethods: cfg.CORSAllowedMethods,
+		AllowedHeaders: cfg.CORSAllowedHeaders,
+	}))
 
 	// Task routes
 	api := router.Group("/api")
+	if cfg.RateLimitPerSecond > 0 {
+		limiter := middleware.NewRateLimiter(float64(cfg.RateLimitPerSecond), cfg.RateLimitBurst)
+		api.Use(limiter.Middleware())
+	}
+	// A user's own key also gets past the shared API key check
+	apiKeys := []string{cfg.APIKey}
+	for _, key := range cfg.UserAPIKeys {
+		apiKeys = append(apiKeys, key)
+	}
+	api.Use(middleware.APIKeyAuth(apiKeys...))
+	api.Use(middleware.Timeout(cfg.RequestTimeout))
+	// Queued requests wait inside the request timeout
+	if cfg.MaxConcurrentRequests > 0 || cfg.MaxConcurrentReads > 0 {
+		limiter := middleware.NewConcurrencyLimiter(cfg.MaxConcurrentRequests, cfg.MaxConcurrentReads, cfg.ConcurrencyQueueTimeout)
+		api.Use(limiter.Middleware())
+	}
+	api.Use(middleware.BodyLimit(cfg.MaxBodyBytes))
+	api.Use(middleware.UserContext(cfg.UserAPIKeys))
+	api.Use(middleware.ResponseTimeFormat())
 	{
 		api.GET("/tasks", taskHandler.GetTasks)
+		api.GET("/tasks/stats", taskHandler.GetTaskStats)
+		api.GET("/tasks/export", taskHandler.ExportTasks)
+		api.GET("/tasks/changes", taskHandler.GetChanges)
+		api.GET("/tasks/by-server-id/:server_id", taskHandler.GetTaskByServerID)
 		api.GET("/tasks/:id", taskHandler.GetTask)
 		api.POST("/tasks", taskHandler.CreateTask)
+		api.POST("/tasks/import", taskHandler.ImportTasks)
+		api.POST("/tasks/bulk-complete", taskHandler.BulkComplete)
+		api.POST("/tasks/bulk-delete", taskHandler.BulkDelete)
+		api.POST("/tasks/batch", taskHandler.BatchCreateTasks)
 		api.PUT("/tasks/:id", taskHandler.UpdateTask)
+		api.PATCH("/tasks/:id", taskHandler.PatchTask)
 		api.DELETE("/tasks/:id", taskHandler.DeleteTask)
+		api.GET("/tasks/:id/sync-history", taskHandler.GetSyncHistory)
+		api.POST("/tasks/:id/resync", taskHandler.ResyncTask)
+		api.POST("/tasks/:id/restore", taskHandler.RestoreTask)
+		api.POST("/tasks/:id/archive", taskHandler.ArchiveTask)
+		api.POST("/tasks/:id/unarchive", taskHandler.UnarchiveTask)
+		api.GET("/tasks/:id/dependencies", taskHandler.GetDependencies)
+		api.POST("/tasks/:id/dependencies", taskHandler.AddDependency)
+		api.DELETE("/tasks/:id/dependencies/:depends_on_id", taskHandler.RemoveDependency)
+		api.GET("/tasks/:id/comments", taskHandler.GetComments)
+		api.POST("/tasks/:id/comments", taskHandler.AddComment)
+		api.GET("/activity", taskHandler.GetActivity)
 
 		// Sync routes
 		api.GET("/sync/queue", syncHandler.GetSyncQueue)
+		api.GET("/sync/queue/:id", syncHandler.GetQueueItem)
 		api.POST("/sync/trigger", syncHandler.TriggerSync)
 		api.GET("/sync/status", syncHandler.GetSyncStatus)
+		api.GET("/sync/plan", syncHandler.GetSyncPlan)
+		api.GET("/sync/conflicts", syncHandler.GetConflicts)
+		api.POST("/sync/conflicts/:id/resolve", syncHandler.ResolveConflict)
+		api.GET("/sync/dead-letter", syncHandler.GetDeadLetters)
+		api.GET("/sync/tasks", syncHandler.GetSyncTasks)
+		api.GET("/sync/runs", syncHandler.GetSyncRuns)
+		api.GET("/sync/attempts", syncHandler.GetSyncAttempts)
 		api.POST("/sync/batch", syncHandler.BatchSync)
+
 	}
 
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Operator routes act on every user's data
+	operator := api.Group("", middleware.AdminAuth(cfg.AdminAPIKey))
+	{
+		operator.POST("/tasks/purge", taskHandler.PurgeDeletedTasks)
+		operator.POST("/sync/reset", syncHandler.ResetQueue)
+		operator.POST("/sync/pause", syncHandler.PauseSync)
+		operator.POST("/sync/resume", syncHandler.ResumeSync)
+		operator.POST("/sync/pull", syncHandler.PullSync)
+		operator.POST("/admin/maintenance", adminHandler.RunMaintenance)
+		operator.POST("/admin/backup", adminHandler.RunBackup)
+	}
+
+	// Health checks
+	router.GET("/health", healthHandler.Health)
+	router.GET("/health/live", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
 
-	log.Printf("Server starting on port %s", cfg.Port)
-	log.Fatal(router.Run(":" + cfg.Port))
+	if cfg.TLSCertFile != "" {
+		log.Printf("Server starting on port %s with TLS", cfg.Port)
+	} else {
+		log.Printf("Server starting on port %s", cfg.Port)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = server.ListenAndServe(ctx, ":"+cfg.Port, router, cfg.TLSCertFile, cfg.TLSKeyFile, cfg.ShutdownTimeout)
+	for _, notifier := range notifiers {
+		notifier.Close()
+	}
+	return err
 }
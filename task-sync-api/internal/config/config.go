@@ -1,38 +1,388 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
 )
 
 type Config struct {
-	Port          string
-	DatabasePath  string
-	SyncBatchSize int
-	MaxRetries    int
+	Port                         string
+	DatabasePath                 string
+	DatabaseReadPath             string
+	SyncBatchSize                int
+	MaxRetries                   int
+	MaxRetriesByOperation        map[string]int
+	MaxTitleLength               int
+	MaxDescriptionLength         int
+	DescriptionOverflowPolicy    string
+	ConflictStrategy             string
+	RateLimitPerSecond           int
+	RateLimitBurst               int
+	MaxConcurrentRequests        int
+	MaxConcurrentReads           int
+	ConcurrencyQueueTimeout      time.Duration
+	APIKey                       string
+	UserAPIKeys                  map[string]string
+	AdminAPIKey                  string
+	SyncServerURL                string
+	CORSAllowedOrigins           []string
+	CORSAllowedMethods           []string
+	CORSAllowedHeaders           []string
+	WebhookURL                   string
+	SyncWebhookURL               string
+	DBMaxOpenConns               int
+	DBMaxIdleConns               int
+	DBConnMaxLifetime            time.Duration
+	DBBusyTimeoutMS              int
+	RetryBaseDelay               time.Duration
+	RetryMaxDelay                time.Duration
+	RetryJitterPercent           int
+	RetryStrategy                string
+	EnforceUniqueTitles          bool
+	SyncItemTimeout              time.Duration
+	SyncConcurrency              int
+	MaxBodyBytes                 int64
+	MaxQueueSize                 int
+	QueueSizeRefresh             time.Duration
+	ResponseTimeZone             string
+	LogLevel                     string
+	HardDelete                   bool
+	RequireIfMatch               bool
+	BackupDir                    string
+	BackupInterval               time.Duration
+	BackupKeep                   int
+	ManualReviewOnStatusConflict bool
+	TLSCertFile                  string
+	TLSKeyFile                   string
+	RequestTimeout               time.Duration
+	SyncPriorities               map[string]int
+	QueueItemTTL                 time.Duration
+	SyncLockTTL                  time.Duration
+	SyncClaimTimeout             time.Duration
+	TaskDataCompressThreshold    int
+	MaxMetadataBytes             int
+	DefaultTaskSort              string
+	OTLPEndpoint                 string
+	ServiceName                  string
+	ShutdownTimeout              time.Duration
+	SyncPassTimeout              time.Duration
+
+	// loadErrors records variables that were set but could not be parsed
+	loadErrors []error
 }
 
+// Load reads the configuration from the environment. Unset variables take
+// their defaults; variables that are set but malformed also take their
+// defaults, and are reported by Validate.
 func Load() *Config {
-	return &Config{
-		Port:          getEnv("PORT", "3000"),
-		DatabasePath:  getEnv("DATABASE_PATH", "./data/tasks.db"),
-		SyncBatchSize: getEnvAsInt("SYNC_BATCH_SIZE", 50),
-		MaxRetries:    getEnvAsInt("MAX_RETRIES", 3),
+	env := &envReader{}
+	cfg := &Config{
+		Port:                         env.getEnv("PORT", "3000"),
+		DatabasePath:                 env.getEnv("DATABASE_PATH", "./data/tasks.db"),
+		DatabaseReadPath:             env.getEnv("DATABASE_READ_PATH", ""),
+		SyncBatchSize:                env.getEnvAsInt("SYNC_BATCH_SIZE", 50),
+		MaxRetries:                   env.getEnvAsInt("MAX_RETRIES", 3),
+		MaxRetriesByOperation:        env.getEnvAsIntMap("MAX_RETRIES_BY_OPERATION", nil),
+		MaxTitleLength:               env.getEnvAsInt("MAX_TITLE_LENGTH", 500),
+		MaxDescriptionLength:         env.getEnvAsInt("MAX_DESCRIPTION_LENGTH", 0),
+		DescriptionOverflowPolicy:    env.getEnv("DESCRIPTION_OVERFLOW_POLICY", "reject"),
+		ConflictStrategy:             env.getEnv("CONFLICT_STRATEGY", "last_write_wins"),
+		RateLimitPerSecond:           env.getEnvAsInt("RATE_LIMIT_PER_SECOND", 20),
+		RateLimitBurst:               env.getEnvAsInt("RATE_LIMIT_BURST", 40),
+		MaxConcurrentRequests:        env.getEnvAsInt("MAX_CONCURRENT_REQUESTS", 0),
+		MaxConcurrentReads:           env.getEnvAsInt("MAX_CONCURRENT_READS", 0),
+		ConcurrencyQueueTimeout:      env.getEnvAsDuration("CONCURRENCY_QUEUE_TIMEOUT", 5*time.Second),
+		APIKey:                       env.getEnv("API_KEY", ""),
+		UserAPIKeys:                  env.getEnvAsStringMap("USER_API_KEYS", nil),
+		AdminAPIKey:                  env.getEnv("ADMIN_API_KEY", ""),
+		SyncServerURL:                env.getEnv("SYNC_SERVER_URL", ""),
+		CORSAllowedOrigins:           env.getEnvAsSlice("CORS_ALLOWED_ORIGINS", nil),
+		CORSAllowedMethods:           env.getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders:           env.getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "X-API-Key", "X-User-ID"}),
+		WebhookURL:                   env.getEnv("WEBHOOK_URL", ""),
+		SyncWebhookURL:               env.getEnv("SYNC_WEBHOOK_URL", ""),
+		DBMaxOpenConns:               env.getEnvAsInt("DB_MAX_OPEN_CONNS", 0),
+		DBMaxIdleConns:               env.getEnvAsInt("DB_MAX_IDLE_CONNS", 0),
+		DBConnMaxLifetime:            env.getEnvAsDuration("DB_CONN_MAX_LIFETIME", 0),
+		DBBusyTimeoutMS:              env.getEnvAsInt("DB_BUSY_TIMEOUT_MS", 5000),
+		RetryBaseDelay:               env.getEnvAsDuration("RETRY_BASE_DELAY", 5*time.Second),
+		RetryMaxDelay:                env.getEnvAsDuration("RETRY_MAX_DELAY", 5*time.Minute),
+		RetryJitterPercent:           env.getEnvAsInt("RETRY_JITTER_PERCENT", 20),
+		RetryStrategy:                env.getEnv("RETRY_STRATEGY", "exponential"),
+		EnforceUniqueTitles:          env.getEnvAsBool("ENFORCE_UNIQUE_TITLES", false),
+		SyncItemTimeout:              env.getEnvAsDuration("SYNC_ITEM_TIMEOUT", 30*time.Second),
+		SyncConcurrency:              env.getEnvAsInt("SYNC_CONCURRENCY", 1),
+		MaxBodyBytes:                 int64(env.getEnvAsInt("MAX_BODY_BYTES", 1<<20)),
+		MaxQueueSize:                 env.getEnvAsInt("MAX_QUEUE_SIZE", 0),
+		QueueSizeRefresh:             env.getEnvAsDuration("QUEUE_SIZE_REFRESH", 5*time.Second),
+		ResponseTimeZone:             env.getEnv("RESPONSE_TIME_ZONE", ""),
+		LogLevel:                     env.getEnv("LOG_LEVEL", "info"),
+		HardDelete:                   env.getEnvAsBool("HARD_DELETE", false),
+		RequireIfMatch:               env.getEnvAsBool("REQUIRE_IF_MATCH", false),
+		BackupDir:                    env.getEnv("BACKUP_DIR", "./data/backups"),
+		BackupInterval:               env.getEnvAsDuration("BACKUP_INTERVAL", 0),
+		BackupKeep:                   env.getEnvAsInt("BACKUP_KEEP", 5),
+		ManualReviewOnStatusConflict: env.getEnvAsBool("MANUAL_REVIEW_ON_STATUS_CONFLICT", false),
+		TLSCertFile:                  env.getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:                   env.getEnv("TLS_KEY_FILE", ""),
+		RequestTimeout:               env.getEnvAsDuration("REQUEST_TIMEOUT", 0),
+		SyncPriorities:               env.getEnvAsIntMap("SYNC_PRIORITIES", map[string]int{"delete": 1}),
+		QueueItemTTL:                 env.getEnvAsDuration("QUEUE_ITEM_TTL", 0),
+		SyncLockTTL:                  env.getEnvAsDuration("SYNC_LOCK_TTL", 10*time.Minute),
+		SyncClaimTimeout:             env.getEnvAsDuration("SYNC_CLAIM_TIMEOUT", 10*time.Minute),
+		TaskDataCompressThreshold:    env.getEnvAsInt("TASK_DATA_COMPRESS_THRESHOLD", 0),
+		MaxMetadataBytes:             env.getEnvAsInt("MAX_METADATA_BYTES", 4096),
+		DefaultTaskSort:              env.getEnv("DEFAULT_TASK_SORT", ""),
+		OTLPEndpoint:                 env.getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		ServiceName:                  env.getEnv("OTEL_SERVICE_NAME", "task-sync-api"),
+		ShutdownTimeout:              env.getEnvAsDuration("SHUTDOWN_TIMEOUT", 15*time.Second),
+		SyncPassTimeout:              env.getEnvAsDuration("SYNC_PASS_TIMEOUT", 5*time.Minute),
+	}
+	cfg.loadErrors = env.errs
+	return cfg
+}
+
+// Validate reports every problem with the configuration at once: variables
+// that were set but could not be parsed, and values that make no sense, such
+// as a negative batch size.
+func (c *Config) Validate() error {
+	errs := append([]error(nil), c.loadErrors...)
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Port)
+	check(c.Port != "", "PORT must not be empty")
+	check(c.Port == "" || (err == nil && port > 0 && port <= 65535), "PORT must be a number between 1 and 65535, got %q", c.Port)
+	check(c.DatabasePath != "", "DATABASE_PATH must not be empty")
+	check(c.SyncBatchSize > 0, "SYNC_BATCH_SIZE must be positive, got %d", c.SyncBatchSize)
+	check(c.MaxRetries > 0, "MAX_RETRIES must be positive, got %d", c.MaxRetries)
+	for _, op := range sortedKeys(c.MaxRetriesByOperation) {
+		check(models.OperationType(op).IsValid(), "MAX_RETRIES_BY_OPERATION has unknown operation type %q", op)
+		check(c.MaxRetriesByOperation[op] > 0, "MAX_RETRIES_BY_OPERATION must be positive for %s, got %d", op, c.MaxRetriesByOperation[op])
+	}
+	for _, op := range sortedKeys(c.SyncPriorities) {
+		check(models.OperationType(op).IsValid(), "SYNC_PRIORITIES has unknown operation type %q", op)
+	}
+	keyOwners := make(map[string]string)
+	for _, user := range sortedKeys(c.UserAPIKeys) {
+		key := c.UserAPIKeys[user]
+		check(user != "", "USER_API_KEYS must not have an empty user ID")
+		check(key != "", "USER_API_KEYS must not have an empty key for %s", user)
+		check(key == "" || key != c.APIKey, "USER_API_KEYS must not reuse API_KEY for %s", user)
+		check(key == "" || key != c.AdminAPIKey, "USER_API_KEYS must not reuse ADMIN_API_KEY for %s", user)
+		other, shared := keyOwners[key]
+		check(!shared || key == "", "USER_API_KEYS gives %s and %s the same key", other, user)
+		keyOwners[key] = user
+	}
+	check(c.MaxTitleLength > 0, "MAX_TITLE_LENGTH must be positive, got %d", c.MaxTitleLength)
+	check(c.MaxDescriptionLength >= 0, "MAX_DESCRIPTION_LENGTH must not be negative, got %d", c.MaxDescriptionLength)
+	check(c.MaxMetadataBytes > 0, "MAX_METADATA_BYTES must be positive, got %d", c.MaxMetadataBytes)
+	check(c.RateLimitPerSecond >= 0, "RATE_LIMIT_PER_SECOND must not be negative, got %d", c.RateLimitPerSecond)
+	check(c.RateLimitBurst >= 0, "RATE_LIMIT_BURST must not be negative, got %d", c.RateLimitBurst)
+	check(c.MaxConcurrentRequests >= 0, "MAX_CONCURRENT_REQUESTS must not be negative, got %d", c.MaxConcurrentRequests)
+	check(c.MaxConcurrentReads >= 0, "MAX_CONCURRENT_READS must not be negative, got %d", c.MaxConcurrentReads)
+	check(c.DBMaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative, got %d", c.DBMaxOpenConns)
+	check(c.DBMaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative, got %d", c.DBMaxIdleConns)
+	check(c.DBBusyTimeoutMS >= 0, "DB_BUSY_TIMEOUT_MS must not be negative, got %d", c.DBBusyTimeoutMS)
+	check(c.RetryBaseDelay >= 0, "RETRY_BASE_DELAY must not be negative, got %s", c.RetryBaseDelay)
+	check(c.RetryMaxDelay >= c.RetryBaseDelay, "RETRY_MAX_DELAY must be at least RETRY_BASE_DELAY, got %s < %s", c.RetryMaxDelay, c.RetryBaseDelay)
+	check(c.RetryJitterPercent >= 0 && c.RetryJitterPercent <= 100, "RETRY_JITTER_PERCENT must be between 0 and 100, got %d", c.RetryJitterPercent)
+	check(c.SyncConcurrency > 0, "SYNC_CONCURRENCY must be positive, got %d", c.SyncConcurrency)
+	check(c.MaxBodyBytes >= 0, "MAX_BODY_BYTES must not be negative, got %d", c.MaxBodyBytes)
+	check(c.MaxQueueSize >= 0, "MAX_QUEUE_SIZE must not be negative, got %d", c.MaxQueueSize)
+	check(c.BackupKeep >= 0, "BACKUP_KEEP must not be negative, got %d", c.BackupKeep)
+	check(c.TaskDataCompressThreshold >= 0, "TASK_DATA_COMPRESS_THRESHOLD must not be negative, got %d", c.TaskDataCompressThreshold)
+
+	// An empty strategy, sort or time zone means the default
+	check(models.DescriptionOverflowPolicy(c.DescriptionOverflowPolicy).IsValid(), "DESCRIPTION_OVERFLOW_POLICY must be reject or truncate, got %q", c.DescriptionOverflowPolicy)
+	check(oneOf(c.ConflictStrategy, "", "last_write_wins", "server_wins", "client_wins"), "CONFLICT_STRATEGY must be one of last_write_wins, server_wins, client_wins, got %q", c.ConflictStrategy)
+	check(oneOf(c.RetryStrategy, "", "fixed", "exponential", "linear"), "RETRY_STRATEGY must be one of fixed, exponential, linear, got %q", c.RetryStrategy)
+	check(oneOf(strings.ToLower(strings.TrimSpace(c.LogLevel)), "debug", "info", "warn", "error"), "LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel)
+	field, direction, _ := strings.Cut(c.DefaultTaskSort, ":")
+	check(c.DefaultTaskSort == "" || (oneOf(field, "created_at", "updated_at", "title", "priority") && oneOf(direction, "", "asc", "desc")),
+		"DEFAULT_TASK_SORT must be created_at, updated_at, title or priority, optionally followed by :asc or :desc, got %q", c.DefaultTaskSort)
+	if c.ResponseTimeZone != "" {
+		_, err := time.LoadLocation(c.ResponseTimeZone)
+		check(err == nil, "RESPONSE_TIME_ZONE must be an IANA time zone name, got %q", c.ResponseTimeZone)
+	}
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"CONCURRENCY_QUEUE_TIMEOUT", c.ConcurrencyQueueTimeout},
+		{"DB_CONN_MAX_LIFETIME", c.DBConnMaxLifetime},
+		{"SYNC_ITEM_TIMEOUT", c.SyncItemTimeout},
+		{"QUEUE_SIZE_REFRESH", c.QueueSizeRefresh},
+		{"BACKUP_INTERVAL", c.BackupInterval},
+		{"REQUEST_TIMEOUT", c.RequestTimeout},
+		{"QUEUE_ITEM_TTL", c.QueueItemTTL},
+		{"SYNC_LOCK_TTL", c.SyncLockTTL},
+		{"SYNC_CLAIM_TIMEOUT", c.SyncClaimTimeout},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+		{"SYNC_PASS_TIMEOUT", c.SyncPassTimeout},
+	} {
+		check(d.value >= 0, "%s must not be negative, got %s", d.name, d.value)
+	}
+
+	if err := c.ValidateTLS(); err != nil {
+		errs = append(errs, err)
 	}
+	return errors.Join(errs...)
 }
 
-func getEnv(key, defaultValue string) string {
+// ValidateTLS checks that TLS_CERT_FILE and TLS_KEY_FILE are either both set,
+// to serve HTTPS, or both unset, to serve plain HTTP.
+func (c *Config) ValidateTLS() error {
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	return nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// envReader reads typed environment variables, remembering the ones that
+// were set to values it could not parse.
+type envReader struct {
+	errs []error
+}
+
+func (r *envReader) invalid(key, value, want string) {
+	r.errs = append(r.errs, fmt.Errorf("%s=%q is not %s", key, value, want))
+}
+
+func (r *envReader) getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
+func (r *envReader) getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
+		intValue, err := strconv.Atoi(strings.TrimSpace(value))
+		if err == nil {
 			return intValue
 		}
+		r.invalid(key, value, "an integer")
 	}
 	return defaultValue
 }
+
+func (r *envReader) getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		boolValue, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err == nil {
+			return boolValue
+		}
+		r.invalid(key, value, "a boolean")
+	}
+	return defaultValue
+}
+
+func (r *envReader) getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err == nil {
+			return duration
+		}
+		r.invalid(key, value, "a duration")
+	}
+	return defaultValue
+}
+
+func (r *envReader) getEnvAsSlice(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+// getEnvAsStringMap reads a comma-separated list of key=value pairs, such as
+// "alice=k1,bob=k2". Any malformed pair makes the whole variable invalid, and
+// the default is used.
+func (r *envReader) getEnvAsStringMap(key string, defaultValue map[string]string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	values := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		name, text, ok := strings.Cut(part, "=")
+		if !ok {
+			// The values may be secrets, so they aren't echoed
+			r.errs = append(r.errs, fmt.Errorf("%s is not a list of name=value pairs", key))
+			return defaultValue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(text)
+	}
+	return values
+}
+
+// getEnvAsIntMap reads a comma-separated list of key=value pairs with integer
+// values, such as "delete=2,create=1". Any malformed pair makes the whole
+// variable invalid, and the default is used.
+func (r *envReader) getEnvAsIntMap(key string, defaultValue map[string]int) map[string]int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	values := make(map[string]int)
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		name, number, ok := strings.Cut(part, "=")
+		if !ok {
+			r.invalid(key, value, "a list of name=integer pairs")
+			return defaultValue
+		}
+		intValue, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil {
+			r.invalid(key, value, "a list of name=integer pairs")
+			return defaultValue
+		}
+		values[strings.TrimSpace(name)] = intValue
+	}
+	return values
+}
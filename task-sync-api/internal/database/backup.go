@@ -0,0 +1,115 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrBackupUnsupported is returned when backing up an in-memory database.
+var ErrBackupUnsupported = errors.New("backups are not available for in-memory databases")
+
+// backupTimeFormat sorts lexically in time order, so the oldest backups come
+// first when file names are sorted.
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// Backup snapshots the database with VACUUM INTO to a timestamped file in dir
+// and returns its path. Once the snapshot is written, all but the newest keep
+// backups are removed; a non-positive keep removes none.
+func (db *DB) Backup(dir string, keep int) (string, error) {
+	if db.path == ":memory:" {
+		return "", ErrBackupUnsupported
+	}
+
+	db.backupMu.Lock()
+	defer db.backupMu.Unlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	prefix := db.backupPrefix()
+	path := filepath.Join(dir, prefix+time.Now().UTC().Format(backupTimeFormat)+".db")
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return "", fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	if keep > 0 {
+		if err := pruneBackups(dir, prefix, keep); err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
+
+// StartBackups runs Backup every interval until the returned stop function is
+// called. Failures are logged and the next tick tries again. Nothing runs for
+// an in-memory database or a non-positive interval.
+func (db *DB) StartBackups(dir string, interval time.Duration, keep int) (stop func()) {
+	if db.path == ":memory:" || interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if path, err := db.Backup(dir, keep); err != nil {
+					log.Printf("Database backup failed: %v", err)
+				} else {
+					log.Printf("Database backed up to %s", path)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// backupPrefix names backups after the database file, e.g. "tasks-" for tasks.db.
+func (db *DB) backupPrefix() string {
+	base := filepath.Base(db.path)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "-"
+}
+
+func pruneBackups(dir, prefix string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ".db") {
+			backups = append(backups, name)
+		}
+	}
+	if len(backups) <= keep {
+		return nil
+	}
+
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-keep] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// operationTypeCheck limits sync_queue.operation_type to the known operations.
+// Tables created with an older list are rebuilt by rebuildSyncQueue.
+const operationTypeCheck = `CONSTRAINT chk_operation_type CHECK (operation_type IN ('create', 'update', 'delete', 'restore', 'archive'))`
+
+// migration is one schema change. Each is applied once, in version order, and
+// recorded in schema_migrations. Steps written before versioning existed are
+// idempotent, so databases created back then pass through them unharmed.
+type migration struct {
+	version     int
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change in the order it was made. Append new
+// steps with the next version; never edit or reorder a step that has shipped.
+var migrations = []migration{
+	{1, "create base tables", execAll(
+		`CREATE TABLE IF NOT EXISTS tasks (
+            id TEXT PRIMARY KEY,
+            title TEXT NOT NULL,
+            description TEXT,
+            completed BOOLEAN NOT NULL DEFAULT 0,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            is_deleted BOOLEAN NOT NULL DEFAULT 0,
+            sync_status TEXT NOT NULL DEFAULT 'pending',
+            server_id TEXT,
+            last_synced_at DATETIME,
+            CONSTRAINT chk_sync_status CHECK (sync_status IN ('pending', 'synced', 'error'))
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_sync_status ON tasks(sync_status)`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_is_deleted ON tasks(is_deleted)`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_updated_at ON tasks(updated_at)`,
+		// No foreign key on task_id so a hard delete's queued delete outlives the task
+		`CREATE TABLE IF NOT EXISTS sync_queue (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            task_id TEXT NOT NULL,
+            operation_type TEXT NOT NULL,
+            task_data TEXT NOT NULL,
+            retry_count INTEGER NOT NULL DEFAULT 0,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_attempt DATETIME,
+            error_message TEXT,
+            next_attempt_at DATETIME,
+            `+operationTypeCheck+`
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_queue_retry_count ON sync_queue(retry_count)`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_queue_created_at ON sync_queue(created_at)`,
+		`CREATE TABLE IF NOT EXISTS tags (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL UNIQUE
+        )`,
+		`CREATE TABLE IF NOT EXISTS task_tags (
+            task_id TEXT NOT NULL,
+            tag_id INTEGER NOT NULL,
+            PRIMARY KEY (task_id, tag_id),
+            FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+            FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags(tag_id)`,
+		// No foreign key on task_id so the audit trail outlives purged tasks
+		`CREATE TABLE IF NOT EXISTS sync_conflicts (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            task_id TEXT NOT NULL,
+            local_data TEXT NOT NULL,
+            remote_data TEXT NOT NULL,
+            winner TEXT NOT NULL,
+            resolved_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            CONSTRAINT chk_winner CHECK (winner IN ('local', 'remote'))
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_conflicts_resolved_at ON sync_conflicts(resolved_at)`,
+		`CREATE TABLE IF NOT EXISTS sync_attempts (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            task_id TEXT NOT NULL,
+            operation_type TEXT NOT NULL,
+            success BOOLEAN NOT NULL,
+            error_message TEXT,
+            attempted_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_attempts_task_id ON sync_attempts(task_id, attempted_at)`,
+		`CREATE TABLE IF NOT EXISTS sync_runs (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            started_at DATETIME NOT NULL,
+            finished_at DATETIME NOT NULL,
+            processed INTEGER NOT NULL DEFAULT 0,
+            succeeded INTEGER NOT NULL DEFAULT 0,
+            failed INTEGER NOT NULL DEFAULT 0
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_runs_started_at ON sync_runs(started_at)`,
+		// No foreign key on task_id so the feed outlives purged tasks
+		`CREATE TABLE IF NOT EXISTS task_events (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            task_id TEXT NOT NULL,
+            user_id TEXT NOT NULL DEFAULT '',
+            event_type TEXT NOT NULL,
+            task_data TEXT NOT NULL,
+            occurred_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_task_events_user_id ON task_events(user_id, id)`,
+		`CREATE TABLE IF NOT EXISTS task_dependencies (
+            task_id TEXT NOT NULL,
+            depends_on_id TEXT NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (task_id, depends_on_id),
+            FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE,
+            FOREIGN KEY (depends_on_id) REFERENCES tasks(id) ON DELETE CASCADE
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_task_dependencies_depends_on_id ON task_dependencies(depends_on_id)`,
+	)},
+	{2, "add sync_queue.next_attempt_at", addColumn("sync_queue", "next_attempt_at", "DATETIME")},
+	{3, "add tasks.user_id", addColumn("tasks", "user_id", "TEXT NOT NULL DEFAULT ''")},
+	{4, "add sync_queue.user_id", addColumn("sync_queue", "user_id", "TEXT NOT NULL DEFAULT ''")},
+	{5, "add tasks.due_date", addColumn("tasks", "due_date", "DATETIME")},
+	{6, "add tasks.recurrence_rule", addColumn("tasks", "recurrence_rule", "TEXT")},
+	{7, "add tasks.created_by", addColumn("tasks", "created_by", "TEXT NOT NULL DEFAULT 'system'")},
+	{8, "add tasks.updated_by", addColumn("tasks", "updated_by", "TEXT NOT NULL DEFAULT 'system'")},
+	{9, "add tasks.archived", addColumn("tasks", "archived", "BOOLEAN NOT NULL DEFAULT 0")},
+	{10, "add sync_queue.server_retry_after", addColumn("sync_queue", "server_retry_after", "INTEGER")},
+	{11, "add sync_queue.content_hash", addColumn("sync_queue", "content_hash", "TEXT")},
+	{12, "rebuild sync_queue constraints", rebuildSyncQueue},
+	{13, "index sync_queue.next_attempt_at", execAll(
+		`CREATE INDEX IF NOT EXISTS idx_sync_queue_next_attempt_at ON sync_queue(next_attempt_at)`,
+	)},
+	{14, "index tasks.user_id", execAll(
+		`CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id)`,
+	)},
+	// Rows queued before content_hash existed have NULL hashes, which never collide
+	{15, "unique sync_queue content hash", execAll(
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_sync_queue_content_hash
+            ON sync_queue(task_id, operation_type, content_hash)`,
+	)},
+	{16, "add tasks.deleted_at", addColumn("tasks", "deleted_at", "DATETIME")},
+	// The last edit of a task deleted before deleted_at existed was its deletion
+	{17, "backfill tasks.deleted_at", execAll(
+		`UPDATE tasks SET deleted_at = updated_at WHERE is_deleted = 1 AND deleted_at IS NULL`,
+	)},
+	{18, "index tasks.server_id", execAll(
+		`CREATE INDEX IF NOT EXISTS idx_tasks_server_id ON tasks(server_id)`,
+	)},
+	{19, "add sync_conflicts.needs_review", addColumn("sync_conflicts", "needs_review", "BOOLEAN NOT NULL DEFAULT 0")},
+	{20, "index sync_attempts.attempted_at", execAll(
+		`CREATE INDEX IF NOT EXISTS idx_sync_attempts_attempted_at ON sync_attempts(attempted_at, id)`,
+	)},
+	{21, "create sync_settings", execAll(
+		`CREATE TABLE IF NOT EXISTS sync_settings (
+            key TEXT PRIMARY KEY,
+            value TEXT NOT NULL,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+        )`,
+	)},
+	{22, "normalize stored timestamps", normalizeTimestamps},
+	{23, "add sync_queue.sync_priority", addColumn("sync_queue", "sync_priority", "INTEGER NOT NULL DEFAULT 0")},
+	{24, "index sync_queue.sync_priority", execAll(
+		`CREATE INDEX IF NOT EXISTS idx_sync_queue_sync_priority ON sync_queue(sync_priority DESC, created_at)`,
+	)},
+	{25, "create sync_locks", execAll(
+		`CREATE TABLE IF NOT EXISTS sync_locks (
+            name TEXT PRIMARY KEY,
+            holder TEXT NOT NULL,
+            acquired_at DATETIME NOT NULL,
+            expires_at DATETIME NOT NULL
+        )`,
+	)},
+	{26, "add sync_queue.claimed_at", addColumn("sync_queue", "claimed_at", "DATETIME")},
+	{27, "add sync_queue.claimed_by", addColumn("sync_queue", "claimed_by", "TEXT")},
+	{28, "add sync_queue.compressed", addColumn("sync_queue", "compressed", "BOOLEAN NOT NULL DEFAULT 0")},
+	{29, "add tasks.metadata", addColumn("tasks", "metadata", "TEXT NOT NULL DEFAULT '{}'")},
+	{30, "create task_comments", execAll(
+		`CREATE TABLE IF NOT EXISTS task_comments (
+            id TEXT PRIMARY KEY,
+            task_id TEXT NOT NULL,
+            body TEXT NOT NULL,
+            author TEXT NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+        )`,
+		`CREATE INDEX IF NOT EXISTS idx_task_comments_task_id ON task_comments(task_id, created_at)`,
+	)},
+	{31, "add tasks.priority", addColumn("tasks", "priority", "INTEGER NOT NULL DEFAULT 0")},
+}
+
+// Migrate applies every migration not yet recorded in schema_migrations, each
+// in its own transaction, and records it. Running it again applies nothing.
+func Migrate(db *DB) error {
+	// Enable foreign keys first
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		return fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+        version INTEGER PRIMARY KEY,
+        description TEXT NOT NULL,
+        applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    )`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	previous := 0
+	for _, m := range migrations {
+		if m.version <= previous {
+			return fmt.Errorf("migration %d is out of order", m.version)
+		}
+		previous = m.version
+
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the newest migration version applied to db, or 0 when
+// none has been.
+func SchemaVersion(db *DB) (int, error) {
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+func applyMigration(db *DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.description, time.Now()); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	return tx.Commit()
+}
+
+// execAll returns a migration step that runs each statement in order.
+func execAll(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumn returns a migration step that adds a column unless the table
+// already has it.
+func addColumn(table, column, definition string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+		if err != nil {
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var cid, notNull, pk int
+			var name, colType string
+			var defaultValue sql.NullString
+			if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+				return fmt.Errorf("failed to inspect table %s: %w", table, err)
+			}
+			if name == column {
+				return nil
+			}
+		}
+		rows.Close()
+
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+		}
+		return nil
+	}
+}
+
+// rebuildSyncQueue rebuilds a sync_queue created with a foreign key to tasks,
+// which would cascade a hard delete's queued delete away with the task, or with
+// an operation_type check that predates the restore and archive operations.
+// SQLite can't alter a constraint in place, so rows are copied into a new table.
+func rebuildSyncQueue(tx *sql.Tx) error {
+	var foreignKeys int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_foreign_key_list('sync_queue')`).Scan(&foreignKeys); err != nil {
+		return fmt.Errorf("failed to inspect sync_queue foreign keys: %w", err)
+	}
+	var definition string
+	if err := tx.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'sync_queue'`).Scan(&definition); err != nil {
+		return fmt.Errorf("failed to inspect sync_queue definition: %w", err)
+	}
+	if foreignKeys == 0 && strings.Contains(definition, operationTypeCheck) {
+		return nil
+	}
+
+	const columns = `id, task_id, user_id, operation_type, task_data, retry_count, created_at,
+        last_attempt, error_message, next_attempt_at, server_retry_after, content_hash`
+	statements := []string{
+		`CREATE TABLE sync_queue_rebuild (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            task_id TEXT NOT NULL,
+            operation_type TEXT NOT NULL,
+            task_data TEXT NOT NULL,
+            retry_count INTEGER NOT NULL DEFAULT 0,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            last_attempt DATETIME,
+            error_message TEXT,
+            next_attempt_at DATETIME,
+            user_id TEXT NOT NULL DEFAULT '',
+            server_retry_after INTEGER,
+            content_hash TEXT,
+            ` + operationTypeCheck + `
+        )`,
+		`INSERT INTO sync_queue_rebuild (` + columns + `) SELECT ` + columns + ` FROM sync_queue`,
+		`DROP TABLE sync_queue`,
+		`ALTER TABLE sync_queue_rebuild RENAME TO sync_queue`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_queue_retry_count ON sync_queue(retry_count)`,
+		`CREATE INDEX IF NOT EXISTS idx_sync_queue_created_at ON sync_queue(created_at)`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to rebuild sync_queue: %w", err)
+		}
+	}
+	return nil
+}
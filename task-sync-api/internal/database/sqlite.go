@@ -1,38 +1,192 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
-
-	_ "github.com/mattn/go-sqlite3"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type DB struct {
 	*sql.DB
+	path          string
+	maintenanceMu sync.Mutex
+	backupMu      sync.Mutex
+
+	// reader serves Reader; nil sends reads to the primary pool
+	reader Querier
+	// readerDB is the pool OpenReader opened, closed along with the primary
+	readerDB *sql.DB
+}
+
+// Querier is the read side of a connection pool.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Writer returns the primary pool, which every change must go through.
+func (db *DB) Writer() *sql.DB {
+	return db.DB
+}
+
+// Reader returns the pool for queries that can tolerate a replica, or the
+// primary when no reader is set.
+func (db *DB) Reader() Querier {
+	if db.reader != nil {
+		return db.reader
+	}
+	return db.DB
+}
+
+// SetReader routes Reader to r. A nil r sends reads back to the primary.
+func (db *DB) SetReader(r Querier) {
+	db.reader = r
+}
+
+// OpenReader opens a read-only pool on the SQLite file at dbPath and routes
+// Reader to it. dbPath may be the primary's own file or a replica of it. No
+// migrations are run, so a replica must already have the primary's schema.
+func (db *DB) OpenReader(dbPath string, pool PoolConfig) error {
+	if dbPath == ":memory:" {
+		return errors.New("an in-memory database can't be opened read-only")
+	}
+
+	busyTimeout := pool.BusyTimeoutMS
+	if busyTimeout == 0 {
+		busyTimeout = DefaultBusyTimeoutMS
+	} else if busyTimeout < 0 {
+		busyTimeout = 0
+	}
+	dsn := fmt.Sprintf("file:%s?mode=ro&_query_only=1&_busy_timeout=%d", dbPath, busyTimeout)
+
+	reader, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open read database: %w", err)
+	}
+	if pool.MaxOpenConns > 0 {
+		reader.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		reader.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		reader.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if err := reader.Ping(); err != nil {
+		reader.Close()
+		return fmt.Errorf("failed to open read database: %w", err)
+	}
+
+	if db.readerDB != nil {
+		db.readerDB.Close()
+	}
+	db.reader, db.readerDB = reader, reader
+	return nil
+}
+
+// Close closes the primary pool and the one OpenReader opened, if any.
+func (db *DB) Close() error {
+	if db.readerDB != nil {
+		db.readerDB.Close()
+	}
+	return db.DB.Close()
+}
+
+// DefaultBusyTimeoutMS is how long a connection waits for another writer's lock
+// before failing with "database is locked".
+const DefaultBusyTimeoutMS = 5000
+
+// PoolConfig tunes the database/sql connection pool. Zero values keep the
+// database/sql defaults. For file databases, MaxOpenConns = 1 serializes all
+// access through one connection, which avoids "database is locked" errors under
+// concurrent writes at the cost of read parallelism.
+//
+// BusyTimeoutMS sets PRAGMA busy_timeout on every connection so a writer waits
+// that many milliseconds for a lock instead of failing at once. Zero uses
+// DefaultBusyTimeoutMS; a negative value disables waiting.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	BusyTimeoutMS   int
 }
 
+// memoryDBCount numbers the in-memory databases opened by this process, so each
+// ":memory:" database gets a name, and so a store, of its own.
+var memoryDBCount atomic.Int64
+
+// NewSQLiteDB opens the database at dbPath. Each call with ":memory:" opens a
+// fresh, isolated in-memory database.
 func NewSQLiteDB(dbPath string) (*DB, error) {
+	return NewSQLiteDBWithPool(dbPath, PoolConfig{})
+}
+
+// NewSQLiteDBNamed opens the in-memory database called name. Calls with the same
+// name share one database for as long as any of them keeps it open.
+func NewSQLiteDBNamed(name string) (*DB, error) {
+	return openSQLite(":memory:", name, PoolConfig{})
+}
+
+func NewSQLiteDBWithPool(dbPath string, pool PoolConfig) (*DB, error) {
+	var memoryName string
+	if dbPath == ":memory:" {
+		memoryName = fmt.Sprintf("memdb%d", memoryDBCount.Add(1))
+	}
+	return openSQLite(dbPath, memoryName, pool)
+}
+
+// openSQLite opens dbPath, or the in-memory database memoryName when dbPath is
+// ":memory:".
+func openSQLite(dbPath, memoryName string, pool PoolConfig) (*DB, error) {
 	var dsn string
 
+	busyTimeout := pool.BusyTimeoutMS
+	if busyTimeout == 0 {
+		busyTimeout = DefaultBusyTimeoutMS
+	} else if busyTimeout < 0 {
+		busyTimeout = 0
+	}
+
 	if dbPath == ":memory:" {
 		// Use shared cache for in-memory databases to allow multiple connections
-		dsn = "file:memdb1?mode=memory&cache=shared"
+		dsn = "file:" + url.PathEscape(memoryName) + "?mode=memory&cache=shared&_foreign_keys=on"
 	} else {
 		// Create directory if it doesn't exist for file databases
 		dir := filepath.Dir(dbPath)
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return nil, fmt.Errorf("failed to create database directory: %w", err)
 		}
-		dsn = dbPath
+		// Foreign keys are a per-connection setting, so request them for every
+		// connection the pool opens rather than relying on the pragma below
+		dsn = dbPath + "?_foreign_keys=on"
 	}
+	// Like foreign keys, the busy timeout is per connection, so it goes in the DSN
+	dsn += fmt.Sprintf("&_busy_timeout=%d", busyTimeout)
 
-	db, err := sql.Open("sqlite3", dsn)
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	// An in-memory database disappears when its last connection closes, so
+	// connections are never recycled there
+	if pool.ConnMaxLifetime > 0 && dbPath != ":memory:" {
+		db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+
 	// Enable foreign keys and other optimizations
 	pragmas := []string{
 		"PRAGMA foreign_keys = ON",
@@ -47,62 +201,10 @@ func NewSQLiteDB(dbPath string) (*DB, error) {
 		}
 	}
 
-	dbConn := &DB{db}
-	if err := dbConn.migrate(); err != nil {
+	dbConn := &DB{DB: db, path: dbPath}
+	if err := Migrate(dbConn); err != nil {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
 	return dbConn, nil
 }
-
-func (db *DB) migrate() error {
-	// Enable foreign keys first
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return fmt.Errorf("failed to enable foreign keys: %w", err)
-	}
-
-	migrations := []string{
-		`CREATE TABLE IF NOT EXISTS tasks (
-            id TEXT PRIMARY KEY,
-            title TEXT NOT NULL,
-            description TEXT,
-            completed BOOLEAN NOT NULL DEFAULT 0,
-            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-            is_deleted BOOLEAN NOT NULL DEFAULT 0,
-            sync_status TEXT NOT NULL DEFAULT 'pending',
-            server_id TEXT,
-            last_synced_at DATETIME,
-            CONSTRAINT chk_sync_status CHECK (sync_status IN ('pending', 'synced', 'error'))
-        )`,
-		`CREATE INDEX IF NOT EXISTS idx_tasks_sync_status ON tasks(sync_status)`,
-		`CREATE INDEX IF NOT EXISTS idx_tasks_is_deleted ON tasks(is_deleted)`,
-		`CREATE INDEX IF NOT EXISTS idx_tasks_updated_at ON tasks(updated_at)`,
-		`CREATE TABLE IF NOT EXISTS sync_queue (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            task_id TEXT NOT NULL,
-            operation_type TEXT NOT NULL,
-            task_data TEXT NOT NULL,
-            retry_count INTEGER NOT NULL DEFAULT 0,
-            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-            last_attempt DATETIME,
-            error_message TEXT,
-            CONSTRAINT chk_operation_type CHECK (operation_type IN ('create', 'update', 'delete')),
-            FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
-        )`,
-		`CREATE INDEX IF NOT EXISTS idx_sync_queue_retry_count ON sync_queue(retry_count)`,
-		`CREATE INDEX IF NOT EXISTS idx_sync_queue_created_at ON sync_queue(created_at)`,
-	}
-
-	for i, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
-			return fmt.Errorf("migration %d failed: %w", i+1, err)
-		}
-	}
-
-	return nil
-}
-
-func (db *DB) Close() error {
-	return db.DB.Close()
-}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// TimestampFormat is how every time value is written to the database: UTC
+// RFC3339 with a fixed nine fractional digits, so text comparison and ORDER BY
+// agree with time order down to the nanosecond.
+const TimestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// driverName is the go-sqlite3 driver with time arguments written in
+// TimestampFormat. go-sqlite3 on its own writes them in the time's own zone and
+// trims trailing zeros from the fraction, which doesn't sort as text.
+const driverName = "sqlite3_timestamps"
+
+func init() {
+	sql.Register(driverName, timestampDriver{})
+}
+
+type timestampDriver struct{}
+
+func (timestampDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := (&sqlite3.SQLiteDriver{}).Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &timestampConn{SQLiteConn: conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+// timestampConn is a go-sqlite3 connection that formats time arguments itself.
+type timestampConn struct {
+	*sqlite3.SQLiteConn
+}
+
+// CheckNamedValue applies the standard argument conversion, then replaces a
+// time with its TimestampFormat text.
+func (c *timestampConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := value.(time.Time); ok {
+		value = FormatTimestamp(t)
+	}
+	nv.Value = value
+	return nil
+}
+
+// FormatTimestamp renders t the way it is stored in the database.
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(TimestampFormat)
+}
+
+// ParseTimestamp parses a timestamp read back as plain text, as happens with
+// aggregates such as MIN and MAX that lose the column's declared type. Values
+// written before TimestampFormat, including SQLite's own CURRENT_TIMESTAMP,
+// are accepted too; those without a zone are taken as UTC.
+func ParseTimestamp(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed, nil
+	}
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if parsed, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", value)
+}
+
+// timestampColumns lists every column holding a time, by table.
+var timestampColumns = map[string][]string{
+	"tasks":             {"created_at", "updated_at", "deleted_at", "last_synced_at", "due_date"},
+	"sync_queue":        {"created_at", "last_attempt", "next_attempt_at"},
+	"sync_conflicts":    {"resolved_at"},
+	"sync_attempts":     {"attempted_at"},
+	"sync_runs":         {"started_at", "finished_at"},
+	"task_events":       {"occurred_at"},
+	"task_dependencies": {"created_at"},
+	"sync_settings":     {"updated_at"},
+	"schema_migrations": {"applied_at"},
+}
+
+// normalizeTimestamps rewrites every stored time in TimestampFormat, so rows
+// written before it compare correctly with rows written after.
+func normalizeTimestamps(tx *sql.Tx) error {
+	for table, columns := range timestampColumns {
+		for _, column := range columns {
+			if err := normalizeColumn(tx, table, column); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeColumn(tx *sql.Tx, table, column string) error {
+	rows, err := tx.Query(fmt.Sprintf("SELECT rowid, %s FROM %s WHERE %s IS NOT NULL", column, table, column))
+	if err != nil {
+		return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+	}
+	defer rows.Close()
+
+	updates := map[int64]time.Time{}
+	for rows.Next() {
+		var rowID int64
+		var value interface{}
+		if err := rows.Scan(&rowID, &value); err != nil {
+			return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+		}
+		// DATETIME columns come back as time.Time when go-sqlite3 can parse them
+		switch v := value.(type) {
+		case time.Time:
+			updates[rowID] = v
+		case string:
+			if parsed, err := ParseTimestamp(v); err == nil {
+				updates[rowID] = parsed
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+	}
+	rows.Close()
+
+	query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE rowid = ?", table, column)
+	for rowID, value := range updates {
+		if _, err := tx.Exec(query, value, rowID); err != nil {
+			return fmt.Errorf("failed to rewrite %s.%s: %w", table, column, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	db         *database.DB
+	backupDir  string
+	backupKeep int
+}
+
+func NewAdminHandler(db *database.DB) *AdminHandler {
+	return &AdminHandler{db: db}
+}
+
+// SetBackup sets where on-demand backups are written and how many are kept.
+func (h *AdminHandler) SetBackup(dir string, keep int) {
+	h.backupDir = dir
+	h.backupKeep = keep
+}
+
+func (h *AdminHandler) RunMaintenance(c *gin.Context) {
+	result, err := h.db.Maintenance()
+	if err != nil {
+		if errors.Is(err, database.ErrMaintenanceBusy) {
+			respondServiceError(c, http.StatusConflict, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "maintenance completed", "maintenance": result})
+}
+
+func (h *AdminHandler) RunBackup(c *gin.Context) {
+	path, err := h.db.Backup(h.backupDir, h.backupKeep)
+	if err != nil {
+		if errors.Is(err, database.ErrBackupUnsupported) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "backup created", "path": path})
+}
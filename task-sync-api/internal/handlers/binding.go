@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/middleware"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// bindJSON decodes the request body into obj. On failure it writes an error
+// response, with field set when the failure belongs to one field, and returns
+// false.
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return true
+	}
+
+	status, apiErr := bindingErrorResponse(obj, err)
+	middleware.RespondAPIError(c, status, apiErr)
+	return false
+}
+
+// bindingErrorResponse turns a binding error into a status and API error.
+func bindingErrorResponse(obj interface{}, err error) (int, models.APIError) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge, models.APIError{Code: models.ErrorCodeBodyTooLarge, Message: "request body too large"}
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) && len(validationErrs) > 0 {
+		fieldErr := validationErrs[0]
+		field := jsonFieldName(obj, fieldErr.StructField())
+		return http.StatusBadRequest, models.APIError{
+			Code:    models.ErrorCodeValidationFailed,
+			Message: validationMessage(field, fieldErr),
+			Field:   field,
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return http.StatusBadRequest, models.APIError{
+			Code:    models.ErrorCodeValidationFailed,
+			Message: fmt.Sprintf("%s must be of type %s", typeErr.Field, typeErr.Type),
+			Field:   typeErr.Field,
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return http.StatusBadRequest, models.APIError{Code: models.ErrorCodeValidationFailed, Message: "request body is not valid JSON"}
+	}
+	if errors.Is(err, io.EOF) {
+		return http.StatusBadRequest, models.APIError{Code: models.ErrorCodeValidationFailed, Message: "request body is required"}
+	}
+
+	return http.StatusBadRequest, models.APIError{Code: models.ErrorCodeValidationFailed, Message: err.Error()}
+}
+
+// validationMessage describes a failed binding tag in plain words.
+func validationMessage(field string, fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return field + " is required"
+	default:
+		return fmt.Sprintf("%s failed the %s check", field, fieldErr.Tag())
+	}
+}
+
+// jsonFieldName returns the JSON key of obj's struct field, falling back to the
+// Go field name when it has no json tag.
+func jsonFieldName(obj interface{}, structField string) string {
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return structField
+	}
+
+	field, ok := t.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return structField
+	}
+	return name
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/middleware"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/syncclient"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorCode names the code clients see for a service error. Errors without a
+// code of their own are internal.
+func errorCode(err error) models.ErrorCode {
+	switch {
+	case errors.Is(err, services.ErrTaskNotFound):
+		return models.ErrorCodeTaskNotFound
+	case errors.Is(err, services.ErrDependencyNotFound):
+		return models.ErrorCodeDependencyNotFound
+	case errors.Is(err, services.ErrConflictNotFound):
+		return models.ErrorCodeConflictNotFound
+	case errors.Is(err, services.ErrQueueItemNotFound):
+		return models.ErrorCodeQueueItemNotFound
+	case errors.Is(err, services.ErrDuplicateTitle):
+		return models.ErrorCodeDuplicateTitle
+	case errors.Is(err, services.ErrDependencyCycle):
+		return models.ErrorCodeDependencyCycle
+	case errors.Is(err, services.ErrIncompleteDependencies):
+		return models.ErrorCodeIncompleteDependencies
+	case errors.Is(err, services.ErrConflictNotPending):
+		return models.ErrorCodeConflictNotPending
+	case errors.Is(err, services.ErrPreconditionFailed):
+		return models.ErrorCodePreconditionFailed
+	case errors.Is(err, services.ErrInvalidCursor), errors.Is(err, services.ErrInvalidConflictWinner),
+		errors.Is(err, services.ErrInvalidSort), errors.Is(err, models.ErrMetadataTooLarge):
+		return models.ErrorCodeValidationFailed
+	case errors.Is(err, services.ErrSyncInProgress):
+		return models.ErrorCodeSyncInProgress
+	case errors.Is(err, services.ErrQueueFull):
+		return models.ErrorCodeQueueFull
+	case errors.Is(err, database.ErrMaintenanceBusy):
+		return models.ErrorCodeMaintenanceBusy
+	case errors.Is(err, database.ErrBackupUnsupported):
+		return models.ErrorCodeBackupUnsupported
+	case errors.Is(err, syncclient.ErrPullUnsupported):
+		return models.ErrorCodePullUnsupported
+	case errors.Is(err, syncclient.ErrServerUnavailable):
+		return models.ErrorCodeUnavailable
+	default:
+		return models.ErrorCodeInternal
+	}
+}
+
+// respondServiceError writes err with status and the code errorCode gives it.
+func respondServiceError(c *gin.Context, status int, err error) {
+	middleware.RespondError(c, status, errorCode(err), err.Error())
+}
+
+// respondValidationError writes a 400 for a request that failed validation.
+func respondValidationError(c *gin.Context, message string) {
+	middleware.RespondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, message)
+}
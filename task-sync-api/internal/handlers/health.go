@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/middleware"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	db          *database.DB
+	syncService *services.SyncService
+}
+
+func NewHealthHandler(db *database.DB, syncService *services.SyncService) *HealthHandler {
+	return &HealthHandler{db: db, syncService: syncService}
+}
+
+// Health reports whether the database is reachable.
+func (h *HealthHandler) Health(c *gin.Context) {
+	if err := h.db.Ping(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Live reports that the process is up without touching any dependencies.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready reports whether the service can serve traffic, including the sync queue depth.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	if err := h.db.Ping(); err != nil {
+		h.respondUnavailable(c, err)
+		return
+	}
+
+	pending, err := h.syncService.GetPendingCount()
+	if err != nil {
+		h.respondUnavailable(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "pending_sync_count": pending})
+}
+
+// respondUnavailable reports a failed readiness check alongside the status.
+func (h *HealthHandler) respondUnavailable(c *gin.Context, err error) {
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"status": "unavailable",
+		"error":  middleware.WithRequestID(c, models.APIError{Code: models.ErrorCodeUnavailable, Message: err.Error()}),
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// parsePagination reads the limit and offset query parameters, applying defaults.
+func parsePagination(c *gin.Context) (int, int, error) {
+	limit, err := parseLimit(c)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	offset := 0
+	if value := c.Query("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
+
+// parseLimit reads the limit query parameter, applying the default page size.
+func parseLimit(c *gin.Context) (int, error) {
+	limit := defaultPageLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageLimit {
+			return 0, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		limit = parsed
+	}
+	return limit, nil
+}
+
+// pageLink is one relation in a Link header. Params override the request's
+// query parameters; an empty value removes the parameter.
+type pageLink struct {
+	rel    string
+	params map[string]string
+}
+
+// linkHeader builds a Link header value whose URLs are the request path with
+// each link's query parameters applied.
+func linkHeader(c *gin.Context, links ...pageLink) string {
+	parts := make([]string, 0, len(links))
+	for _, link := range links {
+		query := c.Request.URL.Query()
+		for key, value := range link.params {
+			if value == "" {
+				query.Del(key)
+			} else {
+				query.Set(key, value)
+			}
+		}
+
+		target := c.Request.URL.Path
+		if encoded := query.Encode(); encoded != "" {
+			target += "?" + encoded
+		}
+		parts = append(parts, fmt.Sprintf(`<%s>; rel="%s"`, target, link.rel))
+	}
+	return strings.Join(parts, ", ")
+}
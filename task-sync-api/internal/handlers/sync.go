@@ -1,35 +1,190 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/middleware"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/syncclient"
 
 	"github.com/gin-gonic/gin"
 )
 
 type SyncHandler struct {
 	syncService *services.SyncService
+	passTimeout time.Duration
 }
 
 func NewSyncHandler(syncService *services.SyncService) *SyncHandler {
 	return &SyncHandler{syncService: syncService}
 }
 
+// SetPassTimeout bounds a sync pass started by POST /api/sync/trigger or a
+// pull started by POST /api/sync/pull. A non-positive timeout leaves them
+// unbounded.
+func (h *SyncHandler) SetPassTimeout(timeout time.Duration) {
+	h.passTimeout = timeout
+}
+
+// passContext returns the context a sync pass or pull runs under. A client that
+// disconnects or a REQUEST_TIMEOUT mustn't abandon the work halfway, so it runs
+// detached from the request under its own time limit.
+func (h *SyncHandler) passContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	ctx := context.WithoutCancel(c.Request.Context())
+	if h.passTimeout > 0 {
+		return context.WithTimeout(ctx, h.passTimeout)
+	}
+	return ctx, func() {}
+}
+
+// syncs returns the sync service scoped to the caller and the request's
+// context, so listings only show the caller's items. Sync passes use the
+// unscoped service instead, so they push every user's changes and their
+// bookkeeping isn't cut short when the request ends.
+func (h *SyncHandler) syncs(c *gin.Context) *services.SyncService {
+	return h.syncService.ForUser(middleware.UserID(c)).WithContext(c.Request.Context())
+}
+
+// TriggerSync runs one sync pass. An optional JSON body of
+// {"batch_size": n, "max_retries": n} overrides the configured values for this run,
+// and "task_ids" limits it to those of the caller's tasks.
 func (h *SyncHandler) TriggerSync(c *gin.Context) {
-	err := h.syncService.ProcessSyncQueue()
+	// Fields left out of the body keep their configured defaults
+	opts := h.syncService.DefaultSyncOptions()
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(bindingErrorResponse(&opts, err))
+			return
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	paused, err := h.syncs(c).IsPaused()
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if paused {
+		middleware.RespondError(c, http.StatusConflict, models.ErrorCodeSyncPaused, "sync is paused")
+		return
+	}
+
+	ctx, cancel := h.passContext(c)
+	defer cancel()
+
+	// A run limited to task_ids may only name, and push, the caller's tasks
+	syncs := h.syncService
+	if len(opts.TaskIDs) > 0 {
+		syncs = syncs.ForUser(middleware.UserID(c))
+	}
+
+	err = syncs.ProcessSyncQueueWithOptions(ctx, opts)
+	if errors.Is(err, services.ErrSyncInProgress) {
+		respondServiceError(c, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, services.ErrTaskNotFound) {
+		respondServiceError(c, http.StatusNotFound, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "sync completed successfully"})
 }
 
+// PullSync fetches the server's tasks and merges them into the local store. An
+// optional ?since= RFC3339 timestamp limits the pull to tasks changed after it.
+func (h *SyncHandler) PullSync(c *gin.Context) {
+	var since time.Time
+	if value := c.Query("since"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			respondValidationError(c, "since must be an RFC3339 timestamp")
+			return
+		}
+		since = parsed
+	}
+
+	ctx, cancel := h.passContext(c)
+	defer cancel()
+
+	result, err := h.syncService.Pull(ctx, since)
+	switch {
+	case errors.Is(err, services.ErrSyncInProgress):
+		respondServiceError(c, http.StatusConflict, err)
+		return
+	case errors.Is(err, syncclient.ErrPullUnsupported):
+		respondServiceError(c, http.StatusNotImplemented, err)
+		return
+	case errors.Is(err, syncclient.ErrServerUnavailable):
+		respondServiceError(c, http.StatusServiceUnavailable, err)
+		return
+	case err != nil:
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "pull completed", "pull": result})
+}
+
+// PauseSync stops sync passes from pushing the queue until ResumeSync.
+func (h *SyncHandler) PauseSync(c *gin.Context) {
+	if err := h.syncs(c).Pause(); err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "sync paused"})
+}
+
+// ResumeSync lets sync passes push the queue again.
+func (h *SyncHandler) ResumeSync(c *gin.Context) {
+	if err := h.syncs(c).Resume(); err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "sync resumed"})
+}
+
+// ResetQueue empties the sync queue. With ?reset_errors=true, tasks whose sync
+// failed are also marked pending again.
+func (h *SyncHandler) ResetQueue(c *gin.Context) {
+	resetErrored := false
+	if value := c.Query("reset_errors"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondValidationError(c, "reset_errors must be true or false")
+			return
+		}
+		resetErrored = parsed
+	}
+
+	if err := h.syncs(c).ResetQueue(resetErrored); err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "sync queue reset"})
+}
+
 func (h *SyncHandler) GetSyncStatus(c *gin.Context) {
-	status, err := h.syncService.GetSyncStatus()
+	status, err := h.syncs(c).GetSyncStatus()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -39,22 +194,26 @@ func (h *SyncHandler) GetSyncStatus(c *gin.Context) {
 func (h *SyncHandler) BatchSync(c *gin.Context) {
 	// Process sync queue
 	err := h.syncService.ProcessSyncQueue()
+	if errors.Is(err, services.ErrSyncInProgress) {
+		respondServiceError(c, http.StatusConflict, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	// Resolve any conflicts
 	err = h.syncService.ResolveConflicts()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	// Get updated status
-	status, err := h.syncService.GetSyncStatus()
+	status, err := h.syncs(c).GetSyncStatus()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -64,12 +223,263 @@ func (h *SyncHandler) BatchSync(c *gin.Context) {
 	})
 }
 
+// GetSyncQueue lists queued items. With ?fields=summary each item is listed
+// without its task_data payload.
 func (h *SyncHandler) GetSyncQueue(c *gin.Context) {
-	items, err := h.syncService.GetSyncQueueContents()
+	filter, err := parseSyncQueueFilter(c)
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	fields := c.Query("fields")
+	if fields != "" && fields != "full" && fields != "summary" {
+		respondValidationError(c, "fields must be full or summary")
+		return
+	}
+
+	items, err := h.syncs(c).ListSyncQueue(filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if fields == "summary" {
+		summaries := make([]models.SyncQueueSummary, len(items))
+		for i, item := range items {
+			summaries[i] = item.Summary()
+		}
+		c.JSON(http.StatusOK, gin.H{"sync_queue": summaries})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"sync_queue": items})
 }
+
+// GetQueueItem returns one sync queue item with its payload decoded.
+func (h *SyncHandler) GetQueueItem(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		respondValidationError(c, "invalid queue item id")
+		return
+	}
+
+	item, err := h.syncs(c).GetQueueItem(id)
+	if err != nil {
+		if errors.Is(err, services.ErrQueueItemNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *SyncHandler) GetConflicts(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	conflicts, total, err := h.syncs(c).GetConflicts(limit, offset)
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conflicts": conflicts,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// ResolveConflict settles a conflict held for manual review. The body names the
+// copy to keep: {"winner": "local"} or {"winner": "remote"}.
+func (h *SyncHandler) ResolveConflict(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		respondValidationError(c, "invalid conflict id")
+		return
+	}
+
+	var req struct {
+		Winner models.ConflictWinner `json:"winner" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	conflict, err := h.syncs(c).ResolveReviewedConflict(id, req.Winner)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidConflictWinner):
+			middleware.RespondAPIError(c, http.StatusBadRequest, models.APIError{
+				Code:    models.ErrorCodeValidationFailed,
+				Message: err.Error(),
+				Field:   "winner",
+			})
+		case errors.Is(err, services.ErrConflictNotFound), errors.Is(err, services.ErrTaskNotFound):
+			respondServiceError(c, http.StatusNotFound, err)
+		case errors.Is(err, services.ErrConflictNotPending):
+			respondServiceError(c, http.StatusConflict, err)
+		case errors.Is(err, services.ErrQueueFull):
+			respondQueueFull(c)
+		default:
+			respondServiceError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conflict": conflict})
+}
+
+// GetDeadLetters lists sync items that exhausted their retries, optionally for
+// one task_id, with the total for paging.
+func (h *SyncHandler) GetDeadLetters(c *gin.Context) {
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	items, total, err := h.syncs(c).GetDeadLetters(c.Query("task_id"), limit, offset)
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": items,
+		"total":        total,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
+
+// GetSyncTasks lists the tasks in the sync status named by ?status=, with the
+// total number of tasks in that status.
+func (h *SyncHandler) GetSyncTasks(c *gin.Context) {
+	status := models.SyncStatus(c.Query("status"))
+	if !status.IsValid() {
+		respondValidationError(c, "status must be pending, synced or error")
+		return
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	tasks, total, err := h.syncs(c).GetTasksBySyncStatus(status, limit, offset)
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":  formatTasks(c, tasks),
+		"status": status,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *SyncHandler) GetSyncPlan(c *gin.Context) {
+	plan, err := h.syncs(c).DryRunSync()
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sync_plan": plan})
+}
+
+// parseSyncQueueFilter reads the operation_type, min_retries and limit query parameters.
+func parseSyncQueueFilter(c *gin.Context) (*models.SyncQueueFilter, error) {
+	filter := &models.SyncQueueFilter{
+		OperationType: models.OperationType(c.Query("operation_type")),
+	}
+
+	if value := c.Query("min_retries"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("min_retries must be a non-negative integer")
+		}
+		filter.MinRetries = parsed
+	}
+
+	// Without a limit the whole matching queue is returned
+	if c.Query("limit") != "" {
+		limit, err := parseLimit(c)
+		if err != nil {
+			return nil, err
+		}
+		filter.Limit = limit
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// GetSyncAttempts serves one page of the sync attempt log across all tasks,
+// newest first, and the cursor for the next page. ?result=success or
+// ?result=failure keeps only attempts with that outcome.
+func (h *SyncHandler) GetSyncAttempts(c *gin.Context) {
+	var success *bool
+	switch result := c.Query("result"); result {
+	case "":
+	case "success", "failure":
+		ok := result == "success"
+		success = &ok
+	default:
+		respondValidationError(c, "result must be success or failure")
+		return
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	attempts, next, err := h.syncs(c).GetSyncAttempts(success, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"attempts":    attempts,
+		"next_cursor": next,
+	})
+}
+
+func (h *SyncHandler) GetSyncRuns(c *gin.Context) {
+	limit, err := parseLimit(c)
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	runs, err := h.syncs(c).GetSyncRuns(limit)
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sync_runs": runs})
+}
@@ -1,8 +1,15 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/middleware"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
 
@@ -10,103 +17,956 @@ import (
 )
 
 type TaskHandler struct {
-	taskService *services.TaskService
+	taskService    *services.TaskService
+	hardDelete     bool
+	requireIfMatch bool
 }
 
 func NewTaskHandler(taskService *services.TaskService) *TaskHandler {
 	return &TaskHandler{taskService: taskService}
 }
 
+// SetHardDelete makes DELETE remove tasks permanently unless the request asks
+// for ?hard=false.
+func (h *TaskHandler) SetHardDelete(enabled bool) {
+	h.hardDelete = enabled
+}
+
+// SetRequireIfMatch makes PUT refuse with 428 any update without an If-Match
+// header, so clients can't overwrite changes they haven't seen.
+func (h *TaskHandler) SetRequireIfMatch(required bool) {
+	h.requireIfMatch = required
+}
+
+// descriptionTruncatedHeader is set on responses whose description was
+// shortened to fit MaxDescriptionLength.
+const descriptionTruncatedHeader = "X-Description-Truncated"
+
+// queueFullRetryAfter is the Retry-After, in seconds, sent when a change is
+// refused because the sync queue is full.
+const queueFullRetryAfter = 30
+
+// respondQueueFull tells the client to retry once the sync queue has drained.
+func respondQueueFull(c *gin.Context) {
+	c.Header("Retry-After", strconv.Itoa(queueFullRetryAfter))
+	respondServiceError(c, http.StatusServiceUnavailable, services.ErrQueueFull)
+}
+
+// formatTask writes the task's timestamps in the caller's requested format.
+func formatTask(c *gin.Context, task *models.Task) json.Marshaler {
+	return task.WithTimeFormat(middleware.TimeFormat(c))
+}
+
+// includeSync reports whether the request passed ?include_sync=true, asking for
+// the queued sync operation alongside the result. It responds with a validation
+// error and returns ok false when the value isn't a boolean.
+func includeSync(c *gin.Context) (include, ok bool) {
+	value := c.Query("include_sync")
+	if value == "" {
+		return false, true
+	}
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		respondValidationError(c, "include_sync must be true or false")
+		return false, false
+	}
+	return include, true
+}
+
+// taskWithSyncItem is the body for a changed task: the task alone, or
+// {task, sync_item} when the caller asked to include the queued operation.
+func taskWithSyncItem(c *gin.Context, task *models.Task, item *models.SyncQueueItem, include bool) interface{} {
+	if !include {
+		return formatTask(c, task)
+	}
+	return gin.H{"task": formatTask(c, task), "sync_item": item}
+}
+
+// formatTasks is formatTask for a list of tasks.
+func formatTasks(c *gin.Context, tasks []*models.Task) []json.Marshaler {
+	formatted := make([]json.Marshaler, len(tasks))
+	for i, task := range tasks {
+		formatted[i] = formatTask(c, task)
+	}
+	return formatted
+}
+
+// parseTaskFields reads the fields query parameter, writing a 400 response and
+// returning false when it names an unknown field.
+func parseTaskFields(c *gin.Context) ([]string, bool) {
+	fields, err := models.ParseTaskFields(c.Query("fields"))
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return nil, false
+	}
+	return fields, true
+}
+
+// projectTasks is formatTasks limited to the requested fields.
+func projectTasks(c *gin.Context, tasks []*models.Task, fields []string) []json.Marshaler {
+	projected := make([]json.Marshaler, len(tasks))
+	for i, task := range tasks {
+		projected[i] = task.Project(middleware.TimeFormat(c), fields)
+	}
+	return projected
+}
+
+// tasks returns the task service scoped to the calling user and the request context.
+func (h *TaskHandler) tasks(c *gin.Context) *services.TaskService {
+	return h.taskService.ForUser(middleware.UserID(c)).WithContext(c.Request.Context())
+}
+
 func (h *TaskHandler) GetTasks(c *gin.Context) {
-	tasks, err := h.taskService.GetAllTasks()
+	fields, ok := parseTaskFields(c)
+	if !ok {
+		return
+	}
+
+	// A cursor or limit switches to keyset pagination
+	_, hasCursor := c.GetQuery("cursor")
+	_, hasLimit := c.GetQuery("limit")
+	if hasCursor || hasLimit {
+		h.getTasksPage(c, fields)
+		return
+	}
+
+	filter, err := parseTaskFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	tasks, err := h.tasks(c).ListTasks(filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSort) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	// Return tasks array directly (not wrapped in object)
-	c.JSON(http.StatusOK, tasks)
+	c.Header("X-Total-Count", strconv.Itoa(len(tasks)))
+	c.JSON(http.StatusOK, projectTasks(c, tasks, fields))
+}
+
+// getTasksPage serves one page of tasks and the cursor for the next page,
+// which is empty on the last page. The X-Total-Count, X-Page-Limit and Link
+// headers carry the same paging state for clients that don't read the body.
+func (h *TaskHandler) getTasksPage(c *gin.Context, fields []string) {
+	for _, param := range []string{"updated_after", "updated_before", "tag", "include_archived", "sort"} {
+		if c.Query(param) != "" {
+			respondValidationError(c, "cursor pagination cannot be combined with "+param)
+			return
+		}
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	page, err := h.tasks(c).GetTaskPage(c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Both links spell out the limit: a first-page prev link has no cursor, and
+	// without a limit either it would fall back to the unpaginated listing
+	pageSize := strconv.Itoa(limit)
+	var links []pageLink
+	if page.NextCursor != "" {
+		links = append(links, pageLink{rel: "next", params: map[string]string{"cursor": page.NextCursor, "limit": pageSize}})
+	}
+	if page.HasPrev {
+		links = append(links, pageLink{rel: "prev", params: map[string]string{"cursor": page.PrevCursor, "limit": pageSize}})
+	}
+	if len(links) > 0 {
+		c.Header("Link", linkHeader(c, links...))
+	}
+	c.Header("X-Total-Count", strconv.Itoa(page.Total))
+	c.Header("X-Page-Limit", strconv.Itoa(limit))
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":       projectTasks(c, page.Tasks, fields),
+		"next_cursor": page.NextCursor,
+	})
 }
 
 func (h *TaskHandler) GetTask(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "task id is required"})
+		respondValidationError(c, "task id is required")
 		return
 	}
 
-	task, err := h.taskService.GetTaskByID(id)
+	fields, ok := parseTaskFields(c)
+	if !ok {
+		return
+	}
+
+	task, err := h.tasks(c).GetTaskByID(id)
 	if err != nil {
-		if err.Error() == "task not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	// Return single task (not in array)
-	c.JSON(http.StatusOK, task)
+	c.Header("ETag", task.ETag())
+	c.JSON(http.StatusOK, task.Project(middleware.TimeFormat(c), fields))
+}
+
+// GetTaskByServerID looks a task up by the ID the sync server assigned it.
+func (h *TaskHandler) GetTaskByServerID(c *gin.Context) {
+	serverID := c.Param("server_id")
+	if serverID == "" {
+		respondValidationError(c, "server id is required")
+		return
+	}
+
+	task, err := h.tasks(c).GetByServerID(serverID)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTask(c, task))
 }
 
 func (h *TaskHandler) CreateTask(c *gin.Context) {
 	var req models.CreateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+	include, ok := includeSync(c)
+	if !ok {
 		return
 	}
 
-	task, err := h.taskService.CreateTask(&req)
+	task, item, err := h.tasks(c).CreateTaskWithSyncItem(&req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, services.ErrDuplicateTitle) {
+			respondServiceError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, task)
+	if req.DescriptionTruncated {
+		c.Header(descriptionTruncatedHeader, "true")
+	}
+	c.Header("ETag", task.ETag())
+	c.JSON(http.StatusCreated, taskWithSyncItem(c, task, item, include))
 }
 
+// UpdateTask handles PUT. With REQUIRE_IF_MATCH set it refuses updates that
+// don't name the version they replace.
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
+	h.updateTask(c, h.requireIfMatch)
+}
+
+// PatchTask handles PATCH, a partial update for clients that only change a few
+// fields, such as toggling completed. It never requires If-Match, though one
+// that is sent is still checked.
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	h.updateTask(c, false)
+}
+
+func (h *TaskHandler) updateTask(c *gin.Context, requireIfMatch bool) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "task id is required"})
+		respondValidationError(c, "task id is required")
 		return
 	}
 
 	var req models.UpdateTaskRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondValidationError(c, err.Error())
 		return
 	}
+	if value := c.Query("force"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondValidationError(c, "force must be true or false")
+			return
+		}
+		req.Force = parsed
+	}
+	if value := c.Query("replace_metadata"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondValidationError(c, "replace_metadata must be true or false")
+			return
+		}
+		req.ReplaceMetadata = parsed
+	}
+	req.IfMatch = c.GetHeader("If-Match")
+	if req.IfMatch == "" && requireIfMatch {
+		middleware.RespondError(c, http.StatusPreconditionRequired, models.ErrorCodePreconditionRequired,
+			"If-Match header is required")
+		return
+	}
+	include, ok := includeSync(c)
+	if !ok {
+		return
+	}
+
+	task, item, err := h.tasks(c).UpdateTaskWithSyncItem(id, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		if errors.Is(err, services.ErrDuplicateTitle) || errors.Is(err, services.ErrIncompleteDependencies) {
+			respondServiceError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
+			return
+		}
+		if errors.Is(err, models.ErrMetadataTooLarge) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		if errors.Is(err, services.ErrPreconditionFailed) {
+			respondServiceError(c, http.StatusPreconditionFailed, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if req.DescriptionTruncated {
+		c.Header(descriptionTruncatedHeader, "true")
+	}
+	c.Header("ETag", task.ETag())
+	c.JSON(http.StatusOK, taskWithSyncItem(c, task, item, include))
+}
+
+// BulkComplete marks the listed tasks completed or not in one transaction. IDs
+// that don't match a task are skipped and reported under "not_found".
+func (h *TaskHandler) BulkComplete(c *gin.Context) {
+	var req models.BulkCompleteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	updated, notFound, err := h.tasks(c).BulkSetCompleted(req.IDs, *req.Completed)
+	if err != nil {
+		if errors.Is(err, services.ErrIncompleteDependencies) {
+			respondServiceError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"updated":   updated,
+		"not_found": notFound,
+	})
+}
+
+// BulkDelete soft-deletes the listed tasks in one transaction. IDs that don't
+// name a task are skipped and reported under "not_found"; the rest are deleted.
+func (h *TaskHandler) BulkDelete(c *gin.Context) {
+	var req models.BulkDeleteRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	deleted, notFound, err := h.tasks(c).BulkDelete(req.IDs)
+	if err != nil {
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted":   deleted,
+		"not_found": notFound,
+	})
+}
 
-	task, err := h.taskService.UpdateTask(id, &req)
+// BatchCreateTasks creates every task in the body in one transaction. The batch
+// is all or nothing unless the request passes ?partial=true; then the valid
+// tasks are created and "results" reports each entry's task or error by index.
+func (h *TaskHandler) BatchCreateTasks(c *gin.Context) {
+	var req models.BatchCreateTasksRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	partial := false
+	if value := c.Query("partial"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondValidationError(c, "partial must be true or false")
+			return
+		}
+		partial = parsed
+	}
+
+	results := make([]gin.H, len(req.Tasks))
+	failed := 0
+	var valid []*models.CreateTaskRequest
+	var indexes []int
+	for i := range req.Tasks {
+		if err := req.Tasks[i].Validate(); err != nil {
+			apiErr := models.APIError{
+				Code:    models.ErrorCodeValidationFailed,
+				Message: err.Error(),
+				Field:   fmt.Sprintf("tasks[%d]", i),
+			}
+			if !partial {
+				middleware.RespondAPIError(c, http.StatusBadRequest, apiErr)
+				return
+			}
+			results[i] = gin.H{"index": i, "error": apiErr}
+			failed++
+			continue
+		}
+		valid = append(valid, &req.Tasks[i])
+		indexes = append(indexes, i)
+	}
+
+	created, err := h.tasks(c).CreateTasks(valid, partial)
 	if err != nil {
-		if err.Error() == "task not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		var itemErr *services.BatchItemError
+		if !errors.As(err, &itemErr) {
+			respondServiceError(c, http.StatusInternalServerError, err)
+			return
+		}
+		// Report the entry's position in the request, not among the valid ones
+		itemErr.Index = indexes[itemErr.Index]
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrDuplicateTitle) {
+			status = http.StatusConflict
+		}
+		middleware.RespondAPIError(c, status, models.APIError{
+			Code:    errorCode(err),
+			Message: err.Error(),
+			Field:   fmt.Sprintf("tasks[%d]", itemErr.Index),
+		})
 		return
 	}
 
-	c.JSON(http.StatusOK, task)
+	for j, result := range created {
+		i := indexes[j]
+		if result.Err != nil {
+			results[i] = gin.H{"index": i, "error": models.APIError{
+				Code:    errorCode(result.Err),
+				Message: result.Err.Error(),
+				Field:   fmt.Sprintf("tasks[%d]", i),
+			}}
+			failed++
+			continue
+		}
+		if req.Tasks[i].DescriptionTruncated {
+			c.Header(descriptionTruncatedHeader, "true")
+		}
+		results[i] = gin.H{"index": i, "task": formatTask(c, result.Task)}
+	}
+
+	// Multi-Status tells a partial batch's caller to look at each result
+	status := http.StatusCreated
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, gin.H{
+		"results": results,
+		"created": len(results) - failed,
+		"failed":  failed,
+	})
 }
 
+// DeleteTask soft-deletes the task, or removes it permanently when hard delete
+// is enabled or the request passes ?hard=true.
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "task id is required"})
+		respondValidationError(c, "task id is required")
+		return
+	}
+
+	hard := h.hardDelete
+	if value := c.Query("hard"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondValidationError(c, "hard must be true or false")
+			return
+		}
+		hard = parsed
+	}
+	include, ok := includeSync(c)
+	if !ok {
+		return
+	}
+
+	var item *models.SyncQueueItem
+	var err error
+	if hard {
+		item, err = h.tasks(c).HardDeleteTaskWithSyncItem(id)
+	} else {
+		item, err = h.tasks(c).DeleteTaskWithSyncItem(id)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	body := gin.H{"message": "task deleted successfully"}
+	if include {
+		body["sync_item"] = item
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// exportFlushEvery is how many tasks are written between flushes of an export.
+const exportFlushEvery = 100
+
+// flushWriter flushes the response every exportFlushEvery writes so a long
+// export reaches the client as it is produced.
+type flushWriter struct {
+	w      gin.ResponseWriter
+	writes int
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	f.writes++
+	if f.writes%exportFlushEvery == 0 {
+		f.w.Flush()
+	}
+	return n, err
+}
+
+// ExportTasks streams every task, including deleted ones, as NDJSON.
+func (h *TaskHandler) ExportTasks(c *gin.Context) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	writer := &flushWriter{w: c.Writer}
+	if err := h.tasks(c).StreamTasks(writer); err != nil {
+		// Nothing has been sent yet, so a proper error response is still possible
+		if writer.writes == 0 {
+			c.Writer.Header().Del("Content-Type")
+			respondServiceError(c, http.StatusInternalServerError, err)
+			return
+		}
+		log.Printf("Task export aborted after %d tasks: %v", writer.writes, err)
+		return
+	}
+	c.Writer.Flush()
+}
+
+// ImportTasks upserts tasks from an NDJSON body. Lines that can't be applied are
+// listed under "errors" and don't fail the request.
+func (h *TaskHandler) ImportTasks(c *gin.Context) {
+	imported, updated, err := h.tasks(c).ImportTasks(c.Request.Body)
+
+	lineErrors := []services.ImportLineError{}
+	var importErr *services.ImportError
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &importErr) {
+		lineErrors = importErr.Lines
+	} else if errors.Is(err, services.ErrQueueFull) {
+		respondQueueFull(c)
+		return
+	} else if errors.As(err, &maxBytesErr) {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": middleware.WithRequestID(c, models.APIError{
+				Code:    models.ErrorCodeBodyTooLarge,
+				Message: "request body too large",
+			}),
+			"imported": imported,
+			"updated":  updated,
+		})
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error":    middleware.WithRequestID(c, models.APIError{Code: errorCode(err), Message: err.Error()}),
+			"imported": imported,
+			"updated":  updated,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imported": imported,
+		"updated":  updated,
+		"errors":   lineErrors,
+	})
+}
+
+// GetActivity serves one page of the caller's task changes, newest first, and the
+// cursor for the next page, which is empty on the last page.
+func (h *TaskHandler) GetActivity(c *gin.Context) {
+	limit, err := parseLimit(c)
+	if err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	entries, next, err := h.tasks(c).GetActivity(c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activity":    entries,
+		"next_cursor": next,
+	})
+}
+
+// GetChanges lists the tasks updated after the since timestamp, deleted ones
+// included, along with the server_time to send as since on the next call.
+func (h *TaskHandler) GetChanges(c *gin.Context) {
+	value := c.Query("since")
+	if value == "" {
+		respondValidationError(c, "since is required")
+		return
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		respondValidationError(c, "since must be an RFC3339 timestamp")
+		return
+	}
+
+	changes, err := h.tasks(c).GetChangedSince(since)
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// server_time is always RFC3339 so it can be passed back as since
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":       formatTasks(c, changes.Tasks),
+		"server_time": changes.ServerTime.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (h *TaskHandler) GetTaskStats(c *gin.Context) {
+	stats, err := h.tasks(c).GetStats()
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func (h *TaskHandler) ResyncTask(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondValidationError(c, "task id is required")
 		return
 	}
 
-	err := h.taskService.DeleteTask(id)
+	task, err := h.tasks(c).ForceResync(id)
 	if err != nil {
-		if err.Error() == "task not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondServiceError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "task deleted successfully"})
+	c.JSON(http.StatusOK, formatTask(c, task))
+}
+
+// RestoreTask brings back a soft-deleted task.
+func (h *TaskHandler) RestoreTask(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondValidationError(c, "task id is required")
+		return
+	}
+
+	task, err := h.tasks(c).RestoreTask(id)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "deleted task not found")
+			return
+		}
+		if errors.Is(err, services.ErrDuplicateTitle) {
+			respondServiceError(c, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTask(c, task))
+}
+
+func (h *TaskHandler) ArchiveTask(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+func (h *TaskHandler) UnarchiveTask(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *TaskHandler) setArchived(c *gin.Context, archived bool) {
+	id := c.Param("id")
+	if id == "" {
+		respondValidationError(c, "task id is required")
+		return
+	}
+
+	var task *models.Task
+	var err error
+	if archived {
+		task, err = h.tasks(c).ArchiveTask(id)
+	} else {
+		task, err = h.tasks(c).UnarchiveTask(id)
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			respondQueueFull(c)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, formatTask(c, task))
+}
+
+func (h *TaskHandler) GetSyncHistory(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		respondValidationError(c, "task id is required")
+		return
+	}
+
+	attempts, err := h.tasks(c).GetSyncHistory(id)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sync_history": attempts})
+}
+
+// GetDependencies lists the tasks the task depends on.
+func (h *TaskHandler) GetDependencies(c *gin.Context) {
+	tasks, err := h.tasks(c).GetDependencies(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dependencies": formatTasks(c, tasks)})
+}
+
+// AddDependency makes the task depend on the task named by depends_on_id.
+func (h *TaskHandler) AddDependency(c *gin.Context) {
+	var req models.AddDependencyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.tasks(c).AddDependency(c.Param("id"), req.DependsOnID); err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		if errors.Is(err, services.ErrDependencyCycle) {
+			respondServiceError(c, http.StatusBadRequest, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "dependency added"})
+}
+
+// RemoveDependency drops the task's dependency on :depends_on_id.
+func (h *TaskHandler) RemoveDependency(c *gin.Context) {
+	err := h.tasks(c).RemoveDependency(c.Param("id"), c.Param("depends_on_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) || errors.Is(err, services.ErrDependencyNotFound) {
+			respondServiceError(c, http.StatusNotFound, err)
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "dependency removed"})
+}
+
+// GetComments lists the comments on the task, oldest first.
+func (h *TaskHandler) GetComments(c *gin.Context) {
+	comments, err := h.tasks(c).GetComments(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": comments})
+}
+
+// AddComment leaves a comment on the task.
+func (h *TaskHandler) AddComment(c *gin.Context) {
+	var req models.CreateCommentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		respondValidationError(c, err.Error())
+		return
+	}
+
+	comment, err := h.tasks(c).AddComment(c.Param("id"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrTaskNotFound) {
+			middleware.RespondError(c, http.StatusNotFound, models.ErrorCodeTaskNotFound, "task not found")
+			return
+		}
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (h *TaskHandler) PurgeDeletedTasks(c *gin.Context) {
+	// Require an explicit age so a bare request can't wipe every deleted task
+	olderThanDays := c.Query("older_than_days")
+	if olderThanDays == "" {
+		respondValidationError(c, "older_than_days is required")
+		return
+	}
+
+	days, err := strconv.Atoi(olderThanDays)
+	if err != nil || days < 1 {
+		respondValidationError(c, "older_than_days must be a positive integer")
+		return
+	}
+
+	purged, err := h.taskService.WithContext(c.Request.Context()).PurgeDeleted(time.Duration(days) * 24 * time.Hour)
+	if err != nil {
+		respondServiceError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+func parseTaskFilter(c *gin.Context) (*models.TaskFilter, error) {
+	filter := &models.TaskFilter{}
+
+	if value := c.Query("updated_after"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("updated_after must be an RFC3339 timestamp")
+		}
+		filter.UpdatedAfter = &parsed
+	}
+
+	if value := c.Query("updated_before"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("updated_before must be an RFC3339 timestamp")
+		}
+		filter.UpdatedBefore = &parsed
+	}
+
+	filter.Tag = c.Query("tag")
+	filter.Sort = c.Query("sort")
+
+	if value := c.Query("include_archived"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("include_archived must be true or false")
+		}
+		filter.IncludeArchived = parsed
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
 }
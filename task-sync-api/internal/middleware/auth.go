@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyHeader   = "X-API-Key"
+	adminKeyHeader = "X-Admin-Key"
+)
+
+// APIKeyAuth requires the X-API-Key header to match one of apiKeys. Empty keys
+// are ignored, and with none left the check is disabled.
+func APIKeyAuth(apiKeys ...string) gin.HandlerFunc {
+	var keys []string
+	for _, key := range apiKeys {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return func(c *gin.Context) {
+		if len(keys) == 0 {
+			c.Next()
+			return
+		}
+
+		provided := []byte(c.GetHeader(apiKeyHeader))
+		matched := false
+		for _, key := range keys {
+			// Compare against every key so the timing doesn't reveal which matched
+			if subtle.ConstantTimeCompare(provided, []byte(key)) == 1 {
+				matched = true
+			}
+		}
+		if !matched {
+			RespondError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "invalid or missing API key")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AdminAuth guards operator actions that reach across every user, such as
+// emptying the sync queue. The X-Admin-Key header must match adminKey. With no
+// adminKey configured the actions are refused outright.
+func AdminAuth(adminKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			RespondError(c, http.StatusForbidden, models.ErrorCodeForbidden, "admin actions are disabled; set ADMIN_API_KEY")
+			return
+		}
+
+		provided := c.GetHeader(adminKeyHeader)
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(adminKey)) != 1 {
+			RespondError(c, http.StatusForbidden, models.ErrorCodeForbidden, "invalid or missing admin key")
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit rejects POST, PUT and PATCH bodies larger than maxBytes with 413. A
+// declared Content-Length over the limit is refused up front; otherwise the body
+// is capped so reading past the limit fails with *http.MaxBytesError. A
+// non-positive maxBytes disables the check.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 {
+			c.Next()
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > maxBytes {
+			RespondError(c, http.StatusRequestEntityTooLarge, models.ErrorCodeBodyTooLarge, "request body too large")
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
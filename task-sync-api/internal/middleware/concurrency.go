@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimiter caps how many requests are handled at once, so a burst of
+// traffic queues in front of the database instead of piling onto its single
+// writer. Reads and writes have separate limits, letting GETs through while
+// writes are throttled.
+type ConcurrencyLimiter struct {
+	reads        chan struct{}
+	writes       chan struct{}
+	queueTimeout time.Duration
+}
+
+// NewConcurrencyLimiter allows maxWrites writes and maxReads reads at once; a
+// non-positive limit leaves that kind of request unlimited. A request over the
+// limit waits up to queueTimeout for a slot, and is rejected at once when
+// queueTimeout is not positive.
+func NewConcurrencyLimiter(maxWrites, maxReads int, queueTimeout time.Duration) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{queueTimeout: queueTimeout}
+	if maxWrites > 0 {
+		l.writes = make(chan struct{}, maxWrites)
+	}
+	if maxReads > 0 {
+		l.reads = make(chan struct{}, maxReads)
+	}
+	return l
+}
+
+// isRead reports whether method only reads, and so counts against the read limit.
+func isRead(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquire takes a slot from slots, waiting up to the queue timeout or until the
+// request is cancelled.
+func (l *ConcurrencyLimiter) acquire(c *gin.Context, slots chan struct{}) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
+
+// Middleware rejects requests that find no free slot in time with 503 and a
+// Retry-After header.
+func (l *ConcurrencyLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		slots := l.writes
+		if isRead(c.Request.Method) {
+			slots = l.reads
+		}
+		if slots == nil {
+			c.Next()
+			return
+		}
+
+		if !l.acquire(c, slots) {
+			c.Header("Retry-After", "1")
+			RespondError(c, http.StatusServiceUnavailable, models.ErrorCodeServerBusy, "too many concurrent requests")
+			return
+		}
+		defer func() { <-slots }()
+
+		c.Next()
+	}
+}
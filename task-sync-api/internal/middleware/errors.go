@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RespondError aborts the request with status and the body
+// {"error": {"code": ..., "message": ..., "request_id": ...}}.
+func RespondError(c *gin.Context, status int, code models.ErrorCode, message string) {
+	RespondAPIError(c, status, models.APIError{Code: code, Message: message})
+}
+
+// RespondAPIError is RespondError for an error that carries more than a code
+// and message, such as the field it belongs to.
+func RespondAPIError(c *gin.Context, status int, apiErr models.APIError) {
+	c.AbortWithStatusJSON(status, gin.H{"error": WithRequestID(c, apiErr)})
+}
+
+// WithRequestID stamps apiErr with the request's ID, for responses that send
+// other keys alongside "error".
+func WithRequestID(c *gin.Context, apiErr models.APIError) models.APIError {
+	apiErr.RequestID = GetRequestID(c)
+	return apiErr
+}
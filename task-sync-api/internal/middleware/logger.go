@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLoggedBody caps how much of a request body is written at debug level.
+const maxLoggedBody = 4 << 10
+
+// LogLevel controls which requests RequestLogger writes.
+type LogLevel int
+
+const (
+	// LogLevelDebug logs every request along with its body.
+	LogLevelDebug LogLevel = iota
+	// LogLevelInfo logs every request.
+	LogLevelInfo
+	// LogLevelWarn logs requests that did not end in a 2xx status.
+	LogLevelWarn
+	// LogLevelError logs requests that ended in a 5xx status.
+	LogLevelError
+)
+
+// ParseLogLevel reads debug, info, warn or error, ignoring case.
+func ParseLogLevel(value string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return LogLevelDebug, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "warn":
+		return LogLevelWarn, nil
+	case "error":
+		return LogLevelError, nil
+	}
+	return 0, fmt.Errorf("log level must be debug, info, warn or error")
+}
+
+// RequestLogger writes one line per request to logger once the request has
+// been handled, skipping those below level.
+func RequestLogger(level LogLevel, logger *log.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		var body []byte
+		if level == LogLevelDebug && c.Request.Body != nil {
+			// Read a bounded prefix for the log and put it back in front of the rest
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBody))
+			c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		switch {
+		case level >= LogLevelError && status < 500:
+			return
+		case level >= LogLevelWarn && status >= 200 && status < 300:
+			return
+		}
+
+		line := fmt.Sprintf("%s %s %d %s", c.Request.Method, c.Request.URL.RequestURI(), status, time.Since(start))
+		if requestID := GetRequestID(c); requestID != "" {
+			line += " request_id=" + requestID
+		}
+		if len(body) > 0 {
+			line += " body=" + string(body)
+		}
+		logger.Print(line)
+	}
+}
+
+// readCloser reads from one reader and closes another, so a wrapped request
+// body still closes the original.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
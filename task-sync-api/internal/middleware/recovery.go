@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic in a later handler into a 500 JSON response carrying
+// the request ID, and writes the panic and its stack to logger. If the handler
+// had already started its response, the response is left as it is.
+func Recovery(logger *log.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// The handler asked for the connection to be dropped
+				panic(recovered)
+			}
+
+			requestID := GetRequestID(c)
+			logger.Printf("panic recovered: %s %s request_id=%s: %v\n%s",
+				c.Request.Method, c.Request.URL.RequestURI(), requestID, recovered, debug.Stack())
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			RespondError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "internal server error")
+		}()
+
+		c.Next()
+	}
+}
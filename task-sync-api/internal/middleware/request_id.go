@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeader     = "X-Request-ID"
+	requestIDContextKey = "request_id"
+	maxRequestIDLength  = 128
+)
+
+// RequestID tags each request with an ID, reusing the caller's X-Request-ID
+// when it sends a usable one, and echoes it in the X-Request-ID response header.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.New().String()
+		}
+
+		c.Set(requestIDContextKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID stored by RequestID, or "" if none was set.
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
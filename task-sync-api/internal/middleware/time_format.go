@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+const (
+	timeFormatHeader     = "X-Time-Format"
+	timeFormatQuery      = "time_format"
+	timeFormatContextKey = "time_format"
+)
+
+// ResponseTimeFormat stores the timestamp format the caller asked for, taken
+// from the time_format query parameter or else the X-Time-Format header. An
+// unknown format is rejected with 400.
+func ResponseTimeFormat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.Query(timeFormatQuery)
+		if value == "" {
+			value = c.GetHeader(timeFormatHeader)
+		}
+
+		format, err := models.ParseTimeFormat(value)
+		if err != nil {
+			RespondError(c, http.StatusBadRequest, models.ErrorCodeValidationFailed, err.Error())
+			return
+		}
+
+		c.Set(timeFormatContextKey, format)
+		c.Next()
+	}
+}
+
+// TimeFormat returns the format stored by ResponseTimeFormat, or RFC3339 if
+// none was set.
+func TimeFormat(c *gin.Context) models.TimeFormat {
+	if format, ok := c.Get(timeFormatContextKey); ok {
+		return format.(models.TimeFormat)
+	}
+	return models.TimeFormatRFC3339
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout gives each request a context that expires after timeout, so database
+// work running under the request context is abandoned once it is due. A request
+// still unanswered at its deadline gets 503; anything the handler writes after
+// that is discarded. A non-positive timeout disables the limit.
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := c.Writer
+		c.Writer = &timeoutWriter{ResponseWriter: writer, ctx: ctx}
+		c.Next()
+		c.Writer = writer
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !writer.Written() {
+			RespondError(c, http.StatusServiceUnavailable, models.ErrorCodeRequestTimeout, "request timed out")
+		}
+	}
+}
+
+// timeoutWriter drops the response once the request's deadline has passed, so
+// the timeout response can be written in its place.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if w.ctx.Err() == nil {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	if w.ctx.Err() == nil {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.ctx.Err() != nil {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.ctx.Err() != nil {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tracing records a server span for each request, named after its method and
+// route. A traceparent header on the request makes the span part of the
+// caller's trace. Responses of 500 and above mark the span as failed.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Request.Method
+		if route := c.FullPath(); route != "" {
+			name += " " + route
+		}
+
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Start(ctx, name, tracing.SpanKindServer)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", c.FullPath())
+		span.SetAttribute("request_id", GetRequestID(c))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", strconv.Itoa(status))
+		if status >= http.StatusInternalServerError {
+			span.RecordError(errorStatus(status))
+		}
+	}
+}
+
+// errorStatus is an error describing a failed response's status.
+type errorStatus int
+
+func (s errorStatus) Error() string {
+	return http.StatusText(int(s))
+}
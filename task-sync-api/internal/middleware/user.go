@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	userIDHeader     = "X-User-ID"
+	userIDContextKey = "user_id"
+)
+
+// UserContext stores the caller's user ID in the request context. userKeys maps
+// each user ID to its API key, and a request acts as the user whose key it sent
+// in X-API-Key. Other requests act as the empty user ID. X-User-ID is refused,
+// since nothing vouches for the identity it claims.
+func UserContext(userKeys map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(userIDHeader) != "" {
+			RespondError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized,
+				"X-User-ID is not accepted; send the user's API key in X-API-Key")
+			return
+		}
+
+		c.Set(userIDContextKey, userForKey(userKeys, c.GetHeader(apiKeyHeader)))
+		c.Next()
+	}
+}
+
+// userForKey returns the user whose key is provided, or "" if none matches.
+func userForKey(userKeys map[string]string, provided string) string {
+	userID := ""
+	for user, key := range userKeys {
+		// Compare against every key so the timing doesn't reveal which matched
+		if key != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+			userID = user
+		}
+	}
+	return userID
+}
+
+// UserID returns the user ID stored by UserContext, or "" if none was set.
+func UserID(c *gin.Context) string {
+	return c.GetString(userIDContextKey)
+}
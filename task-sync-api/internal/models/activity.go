@@ -0,0 +1,16 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ActivityEntry is one change in the activity feed, with the task as it was
+// right after the change.
+type ActivityEntry struct {
+	ID         int             `json:"id" db:"id"`
+	TaskID     string          `json:"task_id" db:"task_id"`
+	Event      TaskEventType   `json:"event" db:"event_type"`
+	Task       json.RawMessage `json:"task" db:"task_data"`
+	OccurredAt time.Time       `json:"occurred_at" db:"occurred_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+// ErrorCode identifies the kind of failure in an error response, so clients can
+// branch on it instead of matching the message text.
+type ErrorCode string
+
+const (
+	ErrorCodeValidationFailed       ErrorCode = "VALIDATION_FAILED"
+	ErrorCodeBodyTooLarge           ErrorCode = "BODY_TOO_LARGE"
+	ErrorCodeUnauthorized           ErrorCode = "UNAUTHORIZED"
+	ErrorCodeForbidden              ErrorCode = "FORBIDDEN"
+	ErrorCodeRateLimited            ErrorCode = "RATE_LIMITED"
+	ErrorCodeServerBusy             ErrorCode = "SERVER_BUSY"
+	ErrorCodeTaskNotFound           ErrorCode = "TASK_NOT_FOUND"
+	ErrorCodeDependencyNotFound     ErrorCode = "DEPENDENCY_NOT_FOUND"
+	ErrorCodeConflictNotFound       ErrorCode = "CONFLICT_NOT_FOUND"
+	ErrorCodeQueueItemNotFound      ErrorCode = "QUEUE_ITEM_NOT_FOUND"
+	ErrorCodeDuplicateTitle         ErrorCode = "DUPLICATE_TITLE"
+	ErrorCodeDependencyCycle        ErrorCode = "DEPENDENCY_CYCLE"
+	ErrorCodeIncompleteDependencies ErrorCode = "INCOMPLETE_DEPENDENCIES"
+	ErrorCodeConflictNotPending     ErrorCode = "CONFLICT_NOT_PENDING"
+	ErrorCodePreconditionFailed     ErrorCode = "PRECONDITION_FAILED"
+	ErrorCodePreconditionRequired   ErrorCode = "PRECONDITION_REQUIRED"
+	ErrorCodeSyncPaused             ErrorCode = "SYNC_PAUSED"
+	ErrorCodeSyncInProgress         ErrorCode = "SYNC_IN_PROGRESS"
+	ErrorCodeQueueFull              ErrorCode = "QUEUE_FULL"
+	ErrorCodeMaintenanceBusy        ErrorCode = "MAINTENANCE_IN_PROGRESS"
+	ErrorCodeBackupUnsupported      ErrorCode = "BACKUP_UNSUPPORTED"
+	ErrorCodePullUnsupported        ErrorCode = "PULL_UNSUPPORTED"
+	ErrorCodeRequestTimeout         ErrorCode = "REQUEST_TIMEOUT"
+	ErrorCodeUnavailable            ErrorCode = "UNAVAILABLE"
+	ErrorCodeInternal               ErrorCode = "INTERNAL_ERROR"
+)
+
+// APIError is the object sent under "error" in every error response. Field
+// names the offending JSON key when the failure belongs to one field.
+type APIError struct {
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"`
+	Field     string    `json:"field,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
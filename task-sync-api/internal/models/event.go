@@ -0,0 +1,15 @@
+package models
+
+type TaskEventType string
+
+const (
+	TaskEventCreated TaskEventType = "task.created"
+	TaskEventUpdated TaskEventType = "task.updated"
+	TaskEventDeleted TaskEventType = "task.deleted"
+)
+
+// TaskEvent is the payload sent to webhook subscribers when a task changes.
+type TaskEvent struct {
+	Event TaskEventType `json:"event"`
+	Task  *Task         `json:"task"`
+}
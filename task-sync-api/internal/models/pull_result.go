@@ -0,0 +1,14 @@
+package models
+
+// PullResult counts what a pull from the sync server did to the local tasks.
+type PullResult struct {
+	// Inserted tasks existed only on the server
+	Inserted int `json:"inserted"`
+	// Updated tasks had no local changes and took the server's newer copy
+	Updated int `json:"updated"`
+	// Conflicted tasks had unsynced local changes and a newer server copy, so the
+	// conflict strategy picked between them
+	Conflicted int `json:"conflicted"`
+	// Unchanged tasks were already as new as the server's copy
+	Unchanged int `json:"unchanged"`
+}
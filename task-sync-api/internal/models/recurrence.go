@@ -0,0 +1,60 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Recurrence rules a task can repeat on.
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+// ValidateRecurrenceRule checks rule is one of the supported intervals.
+func ValidateRecurrenceRule(rule string) error {
+	switch rule {
+	case RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly:
+		return nil
+	}
+	return fmt.Errorf("recurrence_rule must be one of %s, %s, %s", RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly)
+}
+
+// NextOccurrence returns the time one interval of rule after from.
+func NextOccurrence(rule string, from time.Time) (time.Time, error) {
+	switch rule {
+	case RecurrenceDaily:
+		return from.AddDate(0, 0, 1), nil
+	case RecurrenceWeekly:
+		return from.AddDate(0, 0, 7), nil
+	case RecurrenceMonthly:
+		return from.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, ValidateRecurrenceRule(rule)
+}
+
+// NextOccurrence builds the task that follows t on its recurrence schedule. The
+// due date moves forward one interval from t's due date, or from now when t has none.
+func (t *Task) NextOccurrence(now time.Time) (*Task, error) {
+	if t.RecurrenceRule == nil {
+		return nil, fmt.Errorf("task does not recur")
+	}
+
+	from := now
+	if t.DueDate != nil {
+		from = *t.DueDate
+	}
+	due, err := NextOccurrence(*t.RecurrenceRule, from)
+	if err != nil {
+		return nil, err
+	}
+
+	next := NewTask(t.Title, t.Description)
+	next.UserID = t.UserID
+	next.Tags = append([]string(nil), t.Tags...)
+	next.RecurrenceRule = t.RecurrenceRule
+	next.Metadata = MergeMetadata(nil, t.Metadata)
+	next.DueDate = &due
+	return next, nil
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SyncAttempt is one entry in a task's sync history.
+type SyncAttempt struct {
+	ID            int           `json:"id" db:"id"`
+	TaskID        string        `json:"task_id" db:"task_id"`
+	OperationType OperationType `json:"operation_type" db:"operation_type"`
+	Success       bool          `json:"success" db:"success"`
+	ErrorMessage  *string       `json:"error_message" db:"error_message"`
+	AttemptedAt   time.Time     `json:"attempted_at" db:"attempted_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ConflictWinner string
+
+const (
+	ConflictWinnerLocal  ConflictWinner = "local"
+	ConflictWinnerRemote ConflictWinner = "remote"
+)
+
+type SyncConflict struct {
+	ID         int             `json:"id" db:"id"`
+	TaskID     string          `json:"task_id" db:"task_id"`
+	LocalData  json.RawMessage `json:"local_data" db:"local_data"`
+	RemoteData json.RawMessage `json:"remote_data" db:"remote_data"`
+	Winner     ConflictWinner  `json:"winner" db:"winner"`
+	ResolvedAt time.Time       `json:"resolved_at" db:"resolved_at"`
+	// NeedsReview is set on conflicts held back from automatic resolution. Until
+	// someone resolves them, Winner is the copy the strategy would have kept.
+	NeedsReview bool `json:"needs_review" db:"needs_review"`
+}
+
+// IsValid reports whether w names one of the two copies of a task.
+func (w ConflictWinner) IsValid() bool {
+	return w == ConflictWinnerLocal || w == ConflictWinnerRemote
+}
@@ -1,7 +1,15 @@
 package models
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
 	"time"
 )
 
@@ -11,17 +19,63 @@ const (
 	OperationTypeCreate OperationType = "create"
 	OperationTypeUpdate OperationType = "update"
 	OperationTypeDelete OperationType = "delete"
+	// OperationTypeRestore brings back a soft-deleted task.
+	OperationTypeRestore OperationType = "restore"
+	// OperationTypeArchive hides a task from default listings.
+	OperationTypeArchive OperationType = "archive"
 )
 
+// OperationTypes lists the known operation types.
+var OperationTypes = []OperationType{
+	OperationTypeCreate,
+	OperationTypeUpdate,
+	OperationTypeDelete,
+	OperationTypeRestore,
+	OperationTypeArchive,
+}
+
+// IsValid reports whether t is one of the known operation types.
+func (t OperationType) IsValid() bool {
+	for _, known := range OperationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskDataCompressThreshold is the payload size, in bytes, above which queued
+// task data is gzipped. Zero turns compression off.
+var TaskDataCompressThreshold = 0
+
 type SyncQueueItem struct {
 	ID            int           `json:"id" db:"id"`
 	TaskID        string        `json:"task_id" db:"task_id"`
+	UserID        string        `json:"user_id" db:"user_id"`
 	OperationType OperationType `json:"operation_type" db:"operation_type"`
-	TaskData      string        `json:"task_data" db:"task_data"`
-	RetryCount    int           `json:"retry_count" db:"retry_count"`
-	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
-	LastAttempt   *time.Time    `json:"last_attempt" db:"last_attempt"`
-	ErrorMessage  *string       `json:"error_message" db:"error_message"`
+	// TaskData is the task as JSON, or when Compressed is set, that JSON gzipped
+	// and base64 encoded. Read it through GetTaskData.
+	TaskData      string     `json:"task_data" db:"task_data"`
+	Compressed    bool       `json:"compressed" db:"compressed"`
+	RetryCount    int        `json:"retry_count" db:"retry_count"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	LastAttempt   *time.Time `json:"last_attempt" db:"last_attempt"`
+	ErrorMessage  *string    `json:"error_message" db:"error_message"`
+	NextAttemptAt *time.Time `json:"next_attempt_at" db:"next_attempt_at"`
+	// ServerRetryAfter is the wait the sync server asked for on the last failure,
+	// if it sent a Retry-After header.
+	ServerRetryAfter *time.Duration `json:"server_retry_after_ns" db:"server_retry_after"`
+	// SyncPriority orders the queue across tasks: a task with a higher-priority
+	// item is pushed first, and otherwise items go in the order they were
+	// queued. A task's own items are never reordered.
+	SyncPriority int `json:"sync_priority" db:"sync_priority"`
+	// ClaimedAt and ClaimedBy are set while a sync pass is working the item, so
+	// no other pass picks it up.
+	ClaimedAt *time.Time `json:"claimed_at" db:"claimed_at"`
+	ClaimedBy *string    `json:"claimed_by" db:"claimed_by"`
+	// ContentHash identifies the operation and payload so an identical pending
+	// operation is not queued twice. It is only set on items about to be inserted.
+	ContentHash string `json:"-" db:"content_hash"`
 }
 
 func NewSyncQueueItem(taskID string, opType OperationType, task *Task) (*SyncQueueItem, error) {
@@ -30,19 +84,80 @@ func NewSyncQueueItem(taskID string, opType OperationType, task *Task) (*SyncQue
 		return nil, err
 	}
 
-	return &SyncQueueItem{
+	item := &SyncQueueItem{
 		TaskID:        taskID,
+		UserID:        task.UserID,
 		OperationType: opType,
 		TaskData:      string(taskData),
 		RetryCount:    0,
 		CreatedAt:     time.Now(),
-	}, nil
+		// Hashed before compression so duplicates are found either way
+		ContentHash: contentHash(opType, taskData),
+	}
+
+	if TaskDataCompressThreshold > 0 && len(taskData) > TaskDataCompressThreshold {
+		compressed, err := compressTaskData(taskData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress task data: %w", err)
+		}
+		item.TaskData = compressed
+		item.Compressed = true
+	}
+	return item, nil
+}
+
+// compressTaskData gzips data and base64 encodes it to fit the text column.
+func compressTaskData(data []byte) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// decompressTaskData reverses compressTaskData.
+func decompressTaskData(data string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// contentHash is the hex SHA-256 of the operation type and payload.
+func contentHash(opType OperationType, taskData []byte) string {
+	sum := sha256.Sum256(append([]byte(opType+":"), taskData...))
+	return hex.EncodeToString(sum[:])
 }
 
+// GetTaskData decodes the queued task, decompressing it if needed, and checks
+// it is valid.
 func (sq *SyncQueueItem) GetTaskData() (*Task, error) {
 	var task Task
-	err := json.Unmarshal([]byte(sq.TaskData), &task)
-	return &task, err
+	data := []byte(sq.TaskData)
+	if sq.Compressed {
+		decompressed, err := decompressTaskData(sq.TaskData)
+		if err != nil {
+			return &task, fmt.Errorf("failed to decompress task data: %w", err)
+		}
+		data = decompressed
+	}
+	if err := json.Unmarshal(data, &task); err != nil {
+		return &task, err
+	}
+	if err := task.Validate(); err != nil {
+		return &task, fmt.Errorf("invalid task data: %w", err)
+	}
+	return &task, nil
 }
 
 func (sq *SyncQueueItem) IncrementRetry(errorMsg string) {
@@ -51,3 +166,60 @@ func (sq *SyncQueueItem) IncrementRetry(errorMsg string) {
 	sq.LastAttempt = &now
 	sq.ErrorMessage = &errorMsg
 }
+
+// SyncQueueSummary is a queue item without its payload, for callers that only
+// need to see what is queued.
+type SyncQueueSummary struct {
+	ID            int           `json:"id"`
+	TaskID        string        `json:"task_id"`
+	OperationType OperationType `json:"operation_type"`
+	RetryCount    int           `json:"retry_count"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// Summary returns the item without its payload.
+func (sq *SyncQueueItem) Summary() SyncQueueSummary {
+	return SyncQueueSummary{
+		ID:            sq.ID,
+		TaskID:        sq.TaskID,
+		OperationType: sq.OperationType,
+		RetryCount:    sq.RetryCount,
+		CreatedAt:     sq.CreatedAt,
+	}
+}
+
+// SyncQueueItemDetail is a queue item with its payload decoded, for inspecting
+// an item that won't sync. When the payload can't be decoded, TaskData is nil
+// and DecodeError says why.
+type SyncQueueItemDetail struct {
+	*SyncQueueItem
+	TaskData    *Task  `json:"task_data"`
+	DecodeError string `json:"decode_error,omitempty"`
+	// DeadLettered is set once the item has used up its retries.
+	DeadLettered bool `json:"dead_lettered"`
+}
+
+// SyncQueueFilter narrows the sync queue listing. Zero values match everything;
+// a Limit of 0 returns every matching item.
+type SyncQueueFilter struct {
+	OperationType OperationType
+	MinRetries    int
+	Limit         int
+}
+
+func (f *SyncQueueFilter) Validate() error {
+	if f.OperationType != "" && !f.OperationType.IsValid() {
+		names := make([]string, len(OperationTypes))
+		for i, known := range OperationTypes {
+			names[i] = string(known)
+		}
+		return fmt.Errorf("operation_type must be one of %s", strings.Join(names, ", "))
+	}
+	if f.MinRetries < 0 {
+		return fmt.Errorf("min_retries must be a non-negative integer")
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("limit must be a positive integer")
+	}
+	return nil
+}
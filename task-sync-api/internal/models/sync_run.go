@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// SyncRun summarises one pass over the sync queue.
+type SyncRun struct {
+	ID         int       `json:"id" db:"id"`
+	StartedAt  time.Time `json:"started_at" db:"started_at"`
+	FinishedAt time.Time `json:"finished_at" db:"finished_at"`
+	Processed  int       `json:"processed" db:"processed"`
+	Succeeded  int       `json:"succeeded" db:"succeeded"`
+	Failed     int       `json:"failed" db:"failed"`
+}
+
+// SyncRunCompleted is the event named in sync run webhook payloads.
+const SyncRunCompleted = "sync.completed"
+
+// SyncRunSummary is the payload sent to the sync webhook after each sync pass.
+// DeadLettered counts the failed items that have now exhausted their retries.
+type SyncRunSummary struct {
+	Event        string    `json:"event"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	Processed    int       `json:"processed"`
+	Succeeded    int       `json:"succeeded"`
+	Failed       int       `json:"failed"`
+	DeadLettered int       `json:"dead_lettered"`
+}
@@ -1,8 +1,13 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -15,62 +20,405 @@ const (
 	SyncStatusError   SyncStatus = "error"
 )
 
+// SystemActor is recorded as the creator or last editor of a task when the
+// change was made without a caller identity.
+const SystemActor = "system"
+
 type Task struct {
-	ID           string     `json:"id" db:"id"`
-	Title        string     `json:"title" db:"title"`
-	Description  *string    `json:"description" db:"description"`
-	Completed    bool       `json:"completed" db:"completed"`
-	IsDeleted    bool       `json:"is_deleted" db:"is_deleted"`
-	SyncStatus   SyncStatus `json:"sync_status" db:"sync_status"`
-	ServerID     *string    `json:"server_id" db:"server_id"`
-	LastSyncedAt *time.Time `json:"last_synced_at" db:"last_synced_at"`
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	Title          string     `json:"title" db:"title"`
+	Description    *string    `json:"description" db:"description"`
+	Completed      bool       `json:"completed" db:"completed"`
+	Priority       int        `json:"priority" db:"priority"`
+	IsDeleted      bool       `json:"is_deleted" db:"is_deleted"`
+	DeletedAt      *time.Time `json:"deleted_at" db:"deleted_at"`
+	Archived       bool       `json:"archived" db:"archived"`
+	SyncStatus     SyncStatus `json:"sync_status" db:"sync_status"`
+	ServerID       *string    `json:"server_id" db:"server_id"`
+	LastSyncedAt   *time.Time `json:"last_synced_at" db:"last_synced_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	Tags           []string   `json:"tags"`
+	DueDate        *time.Time `json:"due_date" db:"due_date"`
+	RecurrenceRule *string    `json:"recurrence_rule" db:"recurrence_rule"`
+	CreatedBy      string     `json:"created_by" db:"created_by"`
+	UpdatedBy      string     `json:"updated_by" db:"updated_by"`
+	// Metadata holds arbitrary key/values attached by integrations.
+	Metadata map[string]interface{} `json:"metadata" db:"metadata"`
 }
 
+// MarshalJSON writes the task with RFC3339 timestamps in ResponseTimeZone, so
+// queued payloads and exports always decode back into a Task.
 func (t *Task) MarshalJSON() ([]byte, error) {
+	return t.marshalJSON(TimeFormatRFC3339)
+}
+
+// WithTimeFormat returns a JSON view of the task that writes its timestamps in
+// format f.
+func (t *Task) WithTimeFormat(f TimeFormat) json.Marshaler {
+	return formattedTask{task: t, format: f}
+}
+
+type formattedTask struct {
+	task   *Task
+	format TimeFormat
+}
+
+func (ft formattedTask) MarshalJSON() ([]byte, error) {
+	return ft.task.marshalJSON(ft.format)
+}
+
+func (t *Task) marshalJSON(f TimeFormat) ([]byte, error) {
 	return json.Marshal(struct {
-		ID           string     `json:"id"`
-		Title        string     `json:"title"`
-		Description  *string    `json:"description"`
-		Completed    bool       `json:"completed"`
-		IsDeleted    bool       `json:"is_deleted"`
-		SyncStatus   SyncStatus `json:"sync_status"`
-		ServerID     *string    `json:"server_id"`
-		LastSyncedAt *string    `json:"last_synced_at"`
-		CreatedAt    string     `json:"created_at"`
-		UpdatedAt    string     `json:"updated_at"`
+		ID             string                 `json:"id"`
+		UserID         string                 `json:"user_id"`
+		Title          string                 `json:"title"`
+		Description    *string                `json:"description"`
+		Completed      bool                   `json:"completed"`
+		Priority       int                    `json:"priority"`
+		IsDeleted      bool                   `json:"is_deleted"`
+		DeletedAt      interface{}            `json:"deleted_at"`
+		Archived       bool                   `json:"archived"`
+		SyncStatus     SyncStatus             `json:"sync_status"`
+		ServerID       *string                `json:"server_id"`
+		LastSyncedAt   interface{}            `json:"last_synced_at"`
+		CreatedAt      interface{}            `json:"created_at"`
+		UpdatedAt      interface{}            `json:"updated_at"`
+		Tags           []string               `json:"tags"`
+		DueDate        interface{}            `json:"due_date"`
+		RecurrenceRule *string                `json:"recurrence_rule"`
+		CreatedBy      string                 `json:"created_by"`
+		UpdatedBy      string                 `json:"updated_by"`
+		Metadata       map[string]interface{} `json:"metadata"`
 	}{
-		ID:           t.ID,
-		Title:        t.Title,
-		Description:  t.Description,
-		Completed:    t.Completed,
-		IsDeleted:    t.IsDeleted,
-		SyncStatus:   t.SyncStatus,
-		ServerID:     t.ServerID,
-		LastSyncedAt: formatTimePtr(t.LastSyncedAt),
-		CreatedAt:    t.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:    t.UpdatedAt.Format(time.RFC3339),
+		ID:             t.ID,
+		UserID:         t.UserID,
+		Title:          t.Title,
+		Description:    t.Description,
+		Completed:      t.Completed,
+		Priority:       t.Priority,
+		IsDeleted:      t.IsDeleted,
+		DeletedAt:      f.formatTimePtr(t.DeletedAt),
+		Archived:       t.Archived,
+		SyncStatus:     t.SyncStatus,
+		ServerID:       t.ServerID,
+		LastSyncedAt:   f.formatTimePtr(t.LastSyncedAt),
+		CreatedAt:      f.formatTime(t.CreatedAt),
+		UpdatedAt:      f.formatTime(t.UpdatedAt),
+		Tags:           tagsOrEmpty(t.Tags),
+		DueDate:        f.formatTimePtr(t.DueDate),
+		RecurrenceRule: t.RecurrenceRule,
+		CreatedBy:      t.CreatedBy,
+		UpdatedBy:      t.UpdatedBy,
+		Metadata:       metadataOrEmpty(t.Metadata),
 	})
 }
 
-func formatTimePtr(t *time.Time) *string {
-	if t == nil {
-		return nil
+// IsValid reports whether s is one of the known sync statuses.
+func (s SyncStatus) IsValid() bool {
+	switch s {
+	case SyncStatusPending, SyncStatusSynced, SyncStatusError:
+		return true
 	}
-	formatted := t.Format(time.RFC3339)
-	return &formatted
+	return false
 }
 
+// Validate checks a task decoded from outside the database, such as a queued
+// payload or a server copy, has what the tasks table needs before it is stored.
+func (t *Task) Validate() error {
+	if strings.TrimSpace(t.ID) == "" {
+		return fmt.Errorf("id is required")
+	}
+	if _, err := validateTitle(t.Title); err != nil {
+		return err
+	}
+	if !t.SyncStatus.IsValid() {
+		return fmt.Errorf("sync_status must be one of pending, synced, error")
+	}
+	if t.CreatedAt.IsZero() {
+		return fmt.Errorf("created_at is required")
+	}
+	if t.UpdatedAt.IsZero() {
+		return fmt.Errorf("updated_at is required")
+	}
+	if t.RecurrenceRule != nil {
+		if err := ValidateRecurrenceRule(*t.RecurrenceRule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// MaxTitleLength is the maximum number of characters allowed in a task title.
+var MaxTitleLength = 500
+
+// MaxDescriptionLength is the maximum number of characters allowed in a task
+// description. Zero means no limit.
+var MaxDescriptionLength = 0
+
+// DescriptionOverflowPolicy says what happens to a description longer than
+// MaxDescriptionLength.
+type DescriptionOverflowPolicy string
+
+const (
+	// DescriptionOverflowReject fails validation.
+	DescriptionOverflowReject DescriptionOverflowPolicy = "reject"
+	// DescriptionOverflowTruncate cuts the description to the limit, ending it
+	// with an ellipsis.
+	DescriptionOverflowTruncate DescriptionOverflowPolicy = "truncate"
+)
+
+// IsValid reports whether p is one of the known policies.
+func (p DescriptionOverflowPolicy) IsValid() bool {
+	return p == DescriptionOverflowReject || p == DescriptionOverflowTruncate
+}
+
+// DescriptionOverflow is the policy applied to descriptions over the limit.
+var DescriptionOverflow = DescriptionOverflowReject
+
 type CreateTaskRequest struct {
-	Title       string  `json:"title" binding:"required"`
-	Description *string `json:"description"`
+	Title          string                 `json:"title" binding:"required"`
+	Description    *string                `json:"description"`
+	Tags           []string               `json:"tags"`
+	Priority       int                    `json:"priority"`
+	DueDate        *time.Time             `json:"due_date"`
+	RecurrenceRule *string                `json:"recurrence_rule"`
+	Metadata       map[string]interface{} `json:"metadata"`
+	// DescriptionTruncated is set by Validate when it shortened the description.
+	DescriptionTruncated bool `json:"-"`
 }
 
+// UpdateTaskRequest holds the fields to change. Absent fields are left alone and a
+// non-nil Tags replaces the whole set. Sending "description": null clears the
+// description, which is recorded in ClearDescription.
 type UpdateTaskRequest struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	Completed   *bool   `json:"completed"`
+	Title          *string    `json:"title,omitempty"`
+	Description    *string    `json:"description,omitempty"`
+	Completed      *bool      `json:"completed,omitempty"`
+	Tags           *[]string  `json:"tags,omitempty"`
+	Priority       *int       `json:"priority,omitempty"`
+	DueDate        *time.Time `json:"due_date,omitempty"`
+	RecurrenceRule *string    `json:"recurrence_rule,omitempty"`
+	// Metadata is merged into the task's metadata, a null value removing its
+	// key, unless ReplaceMetadata is set
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	ReplaceMetadata  bool                   `json:"-"`
+	ClearDescription bool                   `json:"-"`
+	// DescriptionTruncated is set by Validate when it shortened the description.
+	DescriptionTruncated bool `json:"-"`
+	// Force completes the task even while its dependencies are incomplete.
+	Force bool `json:"-"`
+	// IfMatch, when set, is an If-Match header value the task's current ETag
+	// must match for the update to apply.
+	IfMatch string `json:"-"`
+}
+
+// AddDependencyRequest makes a task depend on another.
+type AddDependencyRequest struct {
+	DependsOnID string `json:"depends_on_id" binding:"required"`
+}
+
+// BulkCompleteRequest sets the completed flag on several tasks at once.
+type BulkCompleteRequest struct {
+	IDs       []string `json:"ids" binding:"required,min=1"`
+	Completed *bool    `json:"completed" binding:"required"`
+}
+
+// BulkDeleteRequest soft-deletes several tasks at once.
+type BulkDeleteRequest struct {
+	IDs []string `json:"ids" binding:"required,min=1"`
+}
+
+// BatchCreateTasksRequest creates several tasks at once. Each entry is checked
+// like a single create, by the handler rather than by binding, so a partial
+// batch can report invalid entries one by one.
+type BatchCreateTasksRequest struct {
+	Tasks []CreateTaskRequest `json:"tasks" binding:"required,min=1,max=100"`
+}
+
+func (r *UpdateTaskRequest) UnmarshalJSON(data []byte) error {
+	type plain UpdateTaskRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	// A plain struct can't tell an absent key from null, so look at the raw fields
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if raw, ok := fields["description"]; ok && string(bytes.TrimSpace(raw)) == "null" {
+		r.ClearDescription = true
+	}
+
+	return nil
+}
+
+// Validate trims the title and checks it is neither blank nor too long. A
+// description over MaxDescriptionLength is rejected or truncated according to
+// DescriptionOverflow.
+func (r *CreateTaskRequest) Validate() error {
+	title, err := validateTitle(r.Title)
+	if err != nil {
+		return err
+	}
+	r.Title = title
+
+	if r.Description != nil {
+		description, truncated, err := validateDescription(*r.Description)
+		if err != nil {
+			return err
+		}
+		r.Description = &description
+		r.DescriptionTruncated = truncated
+	}
+
+	tags, err := normalizeTags(r.Tags)
+	if err != nil {
+		return err
+	}
+	r.Tags = tags
+
+	if err := validatePriority(r.Priority); err != nil {
+		return err
+	}
+	if r.RecurrenceRule != nil {
+		if err := ValidateRecurrenceRule(*r.RecurrenceRule); err != nil {
+			return err
+		}
+	}
+	if r.Metadata != nil {
+		if err := ValidateMetadata(r.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate trims the title, if one is given, and checks it is neither blank nor
+// too long. A description is held to the same limit as on create.
+func (r *UpdateTaskRequest) Validate() error {
+	if r.Title != nil {
+		title, err := validateTitle(*r.Title)
+		if err != nil {
+			return err
+		}
+		r.Title = &title
+	}
+	if r.Description != nil {
+		description, truncated, err := validateDescription(*r.Description)
+		if err != nil {
+			return err
+		}
+		r.Description = &description
+		r.DescriptionTruncated = truncated
+	}
+	if r.Tags != nil {
+		tags, err := normalizeTags(*r.Tags)
+		if err != nil {
+			return err
+		}
+		r.Tags = &tags
+	}
+	if r.Priority != nil {
+		if err := validatePriority(*r.Priority); err != nil {
+			return err
+		}
+	}
+	if r.RecurrenceRule != nil {
+		if err := ValidateRecurrenceRule(*r.RecurrenceRule); err != nil {
+			return err
+		}
+	}
+	if r.Metadata != nil {
+		if err := ValidateMetadata(r.Metadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePriority(priority int) error {
+	if priority < 0 {
+		return fmt.Errorf("priority must not be negative")
+	}
+	return nil
+}
+
+func validateTitle(title string) (string, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return "", fmt.Errorf("title must not be blank")
+	}
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return "", fmt.Errorf("title must be at most %d characters", MaxTitleLength)
+	}
+	return title, nil
+}
+
+// descriptionEllipsis ends a truncated description.
+const descriptionEllipsis = "…"
+
+// validateDescription applies MaxDescriptionLength, reporting whether the
+// description was truncated to fit.
+func validateDescription(description string) (string, bool, error) {
+	length := utf8.RuneCountInString(description)
+	if MaxDescriptionLength <= 0 || length <= MaxDescriptionLength {
+		return description, false, nil
+	}
+	if DescriptionOverflow != DescriptionOverflowTruncate {
+		return "", false, fmt.Errorf("description must be at most %d characters", MaxDescriptionLength)
+	}
+
+	// The ellipsis counts towards the limit
+	runes := []rune(description)[:MaxDescriptionLength-1]
+	log.Printf("Truncated description from %d to %d characters", length, MaxDescriptionLength)
+	return string(runes) + descriptionEllipsis, true, nil
+}
+
+// normalizeTags trims each tag and drops duplicates, keeping the first occurrence.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			return nil, fmt.Errorf("tags must not be blank")
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
+}
+
+// TaskFilter narrows the set of tasks returned by a listing.
+// UpdatedAfter is inclusive and UpdatedBefore is exclusive. Archived tasks are
+// left out unless IncludeArchived is set.
+type TaskFilter struct {
+	UpdatedAfter    *time.Time
+	UpdatedBefore   *time.Time
+	Tag             string
+	IncludeArchived bool
+	// Sort is "field" or "field:asc" or "field:desc"; empty keeps the default order
+	Sort string
+}
+
+func (f *TaskFilter) Validate() error {
+	if f.UpdatedAfter != nil && f.UpdatedBefore != nil && !f.UpdatedAfter.Before(*f.UpdatedBefore) {
+		return fmt.Errorf("updated_after must be before updated_before")
+	}
+	return nil
 }
 
 func NewTask(title string, description *string) *Task {
@@ -93,16 +441,86 @@ func (t *Task) Update(req *UpdateTaskRequest) {
 	}
 	if req.Description != nil {
 		t.Description = req.Description
+	} else if req.ClearDescription {
+		t.Description = nil
 	}
 	if req.Completed != nil {
 		t.Completed = *req.Completed
 	}
+	if req.Tags != nil {
+		t.Tags = *req.Tags
+	}
+	if req.Priority != nil {
+		t.Priority = *req.Priority
+	}
+	if req.DueDate != nil {
+		t.DueDate = req.DueDate
+	}
+	if req.RecurrenceRule != nil {
+		t.RecurrenceRule = req.RecurrenceRule
+	}
+	if req.Metadata != nil {
+		base := t.Metadata
+		if req.ReplaceMetadata {
+			base = nil
+		}
+		t.Metadata = MergeMetadata(base, req.Metadata)
+	}
+	t.UpdatedAt = time.Now()
+	t.SyncStatus = SyncStatusPending
+}
+
+// SetArchived archives or unarchives the task. Like any edit it must be synced again.
+func (t *Task) SetArchived(archived bool) {
+	t.Archived = archived
 	t.UpdatedAt = time.Now()
 	t.SyncStatus = SyncStatusPending
 }
 
+// SoftDelete marks the task deleted, recording when in DeletedAt.
 func (t *Task) SoftDelete() {
+	now := time.Now()
 	t.IsDeleted = true
+	t.DeletedAt = &now
+	t.UpdatedAt = now
+	t.SyncStatus = SyncStatusPending
+}
+
+// Restore undoes SoftDelete and clears DeletedAt.
+func (t *Task) Restore() {
+	t.IsDeleted = false
+	t.DeletedAt = nil
 	t.UpdatedAt = time.Now()
 	t.SyncStatus = SyncStatusPending
 }
+
+// Clone returns a copy of the task that shares nothing with it, so changes to
+// the copy's description, tags or metadata leave t untouched. Nested metadata
+// values are still shared.
+func (t *Task) Clone() *Task {
+	clone := *t
+	clone.Description = copyPtr(t.Description)
+	clone.RecurrenceRule = copyPtr(t.RecurrenceRule)
+	clone.ServerID = copyPtr(t.ServerID)
+	clone.DeletedAt = copyPtr(t.DeletedAt)
+	clone.LastSyncedAt = copyPtr(t.LastSyncedAt)
+	clone.DueDate = copyPtr(t.DueDate)
+	if t.Tags != nil {
+		clone.Tags = append([]string(nil), t.Tags...)
+	}
+	if t.Metadata != nil {
+		clone.Metadata = make(map[string]interface{}, len(t.Metadata))
+		for key, value := range t.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+	return &clone
+}
+
+func copyPtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// TaskComment is a note left on a task. Comments are local and are not synced.
+type TaskComment struct {
+	ID        string    `json:"id" db:"id"`
+	TaskID    string    `json:"task_id" db:"task_id"`
+	Body      string    `json:"body" db:"body"`
+	Author    string    `json:"author" db:"author"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateCommentRequest adds a comment to a task. Author defaults to the
+// X-User-ID of the caller.
+type CreateCommentRequest struct {
+	Body   string `json:"body" binding:"required"`
+	Author string `json:"author"`
+}
+
+// Validate trims the comment and checks its body isn't blank.
+func (r *CreateCommentRequest) Validate() error {
+	r.Body = strings.TrimSpace(r.Body)
+	if r.Body == "" {
+		return errors.New("body must not be blank")
+	}
+	r.Author = strings.TrimSpace(r.Author)
+	return nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// ETag identifies this version of the task for HTTP conditional requests. Every
+// change moves updated_at, so a new version always gets a new tag.
+func (t *Task) ETag() string {
+	sum := sha256.Sum256([]byte(t.ID + "\x00" + t.UpdatedAt.UTC().Format(time.RFC3339Nano)))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// MatchesETag reports whether an If-Match header value names this version of
+// the task. The value may list several tags, or be "*" to match any version.
+// Weak tags never match, as If-Match requires strong comparison.
+func (t *Task) MatchesETag(ifMatch string) bool {
+	etag := t.ETag()
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// taskFields are the JSON keys a task response can be narrowed to.
+var taskFields = map[string]bool{
+	"id":              true,
+	"user_id":         true,
+	"title":           true,
+	"description":     true,
+	"completed":       true,
+	"priority":        true,
+	"is_deleted":      true,
+	"deleted_at":      true,
+	"archived":        true,
+	"sync_status":     true,
+	"server_id":       true,
+	"last_synced_at":  true,
+	"created_at":      true,
+	"updated_at":      true,
+	"tags":            true,
+	"due_date":        true,
+	"recurrence_rule": true,
+	"created_by":      true,
+	"updated_by":      true,
+	"metadata":        true,
+}
+
+// ParseTaskFields reads a comma-separated list of task JSON keys. The empty
+// string selects every field and returns nil.
+func ParseTaskFields(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		if !taskFields[field] {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// Project returns a JSON view of the task holding only the given fields, with
+// timestamps in format f. No fields means every field.
+func (t *Task) Project(f TimeFormat, fields []string) json.Marshaler {
+	if len(fields) == 0 {
+		return t.WithTimeFormat(f)
+	}
+	return projectedTask{task: t, format: f, fields: fields}
+}
+
+type projectedTask struct {
+	task   *Task
+	format TimeFormat
+	fields []string
+}
+
+func (pt projectedTask) MarshalJSON() ([]byte, error) {
+	full, err := pt.task.marshalJSON(pt.format)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(full, &all); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]json.RawMessage, len(pt.fields))
+	for _, field := range pt.fields {
+		projected[field] = all[field]
+	}
+	return json.Marshal(projected)
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MaxMetadataBytes caps the size of a task's metadata once serialized as JSON.
+var MaxMetadataBytes = 4096
+
+// ErrMetadataTooLarge is returned when a task's metadata serializes to more
+// than MaxMetadataBytes.
+var ErrMetadataTooLarge = errors.New("metadata is too large")
+
+// ValidateMetadata checks metadata fits within MaxMetadataBytes.
+func ValidateMetadata(metadata map[string]interface{}) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("metadata must be a JSON object: %w", err)
+	}
+	if len(data) > MaxMetadataBytes {
+		return fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrMetadataTooLarge, len(data), MaxMetadataBytes)
+	}
+	return nil
+}
+
+// MergeMetadata returns base with the keys of patch applied on top. A key whose
+// patch value is null is removed. Values are replaced whole, not merged deeper.
+func MergeMetadata(base, patch map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(patch))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+func metadataOrEmpty(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return map[string]interface{}{}
+	}
+	return metadata
+}
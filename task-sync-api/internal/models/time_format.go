@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResponseTimeZone is the zone task timestamps are written in. Nil keeps the
+// zone each time already carries.
+var ResponseTimeZone *time.Location
+
+// TimeFormat selects how task timestamps are written to JSON.
+type TimeFormat string
+
+const (
+	// TimeFormatRFC3339 writes timestamps as RFC3339 strings in ResponseTimeZone.
+	TimeFormatRFC3339 TimeFormat = "rfc3339"
+	// TimeFormatEpochMillis writes timestamps as Unix epoch milliseconds.
+	TimeFormatEpochMillis TimeFormat = "epoch_ms"
+)
+
+// ParseTimeFormat reads a time format name. The empty string means RFC3339.
+func ParseTimeFormat(value string) (TimeFormat, error) {
+	switch TimeFormat(value) {
+	case "", TimeFormatRFC3339:
+		return TimeFormatRFC3339, nil
+	case TimeFormatEpochMillis:
+		return TimeFormatEpochMillis, nil
+	}
+	return "", fmt.Errorf("time format must be %s or %s", TimeFormatRFC3339, TimeFormatEpochMillis)
+}
+
+// formatTime returns t as it should appear in JSON under format f.
+func (f TimeFormat) formatTime(t time.Time) interface{} {
+	if f == TimeFormatEpochMillis {
+		return t.UnixMilli()
+	}
+	if ResponseTimeZone != nil {
+		t = t.In(ResponseTimeZone)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatTimePtr is formatTime for optional timestamps, keeping nil as null.
+func (f TimeFormat) formatTimePtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return f.formatTime(*t)
+}
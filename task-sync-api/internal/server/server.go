@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ListenAndServe listens on addr and serves handler until ctx is done. See Serve.
+func ListenAndServe(ctx context.Context, addr string, handler http.Handler, certFile, keyFile string, shutdownTimeout time.Duration) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(ctx, ln, handler, certFile, keyFile, shutdownTimeout)
+}
+
+// Serve serves handler on ln. When certFile and keyFile are set the connection
+// is HTTPS, which also enables HTTP/2; otherwise it is plain HTTP.
+//
+// Once ctx is done the server stops accepting connections and waits up to
+// shutdownTimeout for in-flight requests to finish. A clean shutdown returns nil.
+func Serve(ctx context.Context, ln net.Listener, handler http.Handler, certFile, keyFile string, shutdownTimeout time.Duration) error {
+	srv := &http.Server{Handler: handler}
+
+	served := make(chan error, 1)
+	go func() {
+		if certFile != "" && keyFile != "" {
+			served <- srv.ServeTLS(ln, certFile, keyFile)
+			return
+		}
+		served <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
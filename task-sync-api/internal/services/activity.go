@@ -0,0 +1,108 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+// recordEventTx appends a change to the activity feed inside the mutation's
+// transaction, so the entry commits or rolls back with the change itself.
+func recordEventTx(tx *sql.Tx, event models.TaskEventType, task *models.Task) error {
+	snapshot, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to encode task: %w", err)
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO task_events (task_id, user_id, event_type, task_data, occurred_at)
+        VALUES (?, ?, ?, ?, ?)
+    `, task.ID, task.UserID, event, string(snapshot), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record task event: %w", err)
+	}
+	return nil
+}
+
+// Activity cursors are the last entry's ID, base64 encoded so clients treat them
+// as opaque tokens.
+func encodeActivityCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeActivityCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(string(data))
+	if err != nil || id < 1 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// GetActivity returns up to limit of the user's task changes following cursor,
+// newest first. An empty cursor starts at the most recent change. The returned
+// cursor is empty once there are no more entries.
+func (s *TaskService) GetActivity(cursor string, limit int) ([]*models.ActivityEntry, string, error) {
+	s, span := s.startSpan("GetActivity")
+	defer span.End()
+
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{s.userID}
+
+	// Entry IDs increase with every change, so they order the feed on their own
+	if cursor != "" {
+		before, err := decodeActivityCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		conditions = append(conditions, "id < ?")
+		args = append(args, before)
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	query := `
+        SELECT id, task_id, event_type, task_data, occurred_at
+        FROM task_events
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY id DESC
+        LIMIT ?
+    `
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(s.context(), query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query activity: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.ActivityEntry{}
+	for rows.Next() {
+		entry := &models.ActivityEntry{}
+		var snapshot string
+		if err := rows.Scan(&entry.ID, &entry.TaskID, &entry.Event, &snapshot, &entry.OccurredAt); err != nil {
+			return nil, "", fmt.Errorf("failed to scan activity: %w", err)
+		}
+		entry.Task = json.RawMessage(snapshot)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read activity: %w", err)
+	}
+
+	var next string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		next = encodeActivityCursor(entries[limit-1].ID)
+	}
+
+	return entries, next, nil
+}
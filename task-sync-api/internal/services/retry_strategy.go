@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/config"
+)
+
+// RetryStrategy schedules retries of failed queue items. NextAttempt is when an
+// item that has failed retryCount times, most recently at lastAttempt, may be
+// tried again. ShouldGiveUp reports whether an item that has failed retryCount
+// times should be dead-lettered even though it is under MAX_RETRIES.
+type RetryStrategy interface {
+	NextAttempt(retryCount int, lastAttempt time.Time) time.Time
+	ShouldGiveUp(retryCount int) bool
+}
+
+// Names accepted by RETRY_STRATEGY.
+const (
+	RetryStrategyFixed       = "fixed"
+	RetryStrategyExponential = "exponential"
+	RetryStrategyLinear      = "linear"
+)
+
+// FixedRetry waits the same Delay after every failure. A zero MaxAttempts
+// leaves giving up to MAX_RETRIES.
+type FixedRetry struct {
+	Delay       time.Duration
+	MaxAttempts int
+}
+
+func (r FixedRetry) NextAttempt(retryCount int, lastAttempt time.Time) time.Time {
+	if retryCount < 1 || r.Delay <= 0 {
+		return lastAttempt
+	}
+	return lastAttempt.Add(r.Delay)
+}
+
+func (r FixedRetry) ShouldGiveUp(retryCount int) bool {
+	return giveUpAfter(r.MaxAttempts, retryCount)
+}
+
+// ExponentialRetry waits BaseDelay after the first failure and doubles the wait
+// with each one after, up to MaxDelay. A zero MaxDelay doesn't cap the wait.
+type ExponentialRetry struct {
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxAttempts int
+}
+
+func (r ExponentialRetry) NextAttempt(retryCount int, lastAttempt time.Time) time.Time {
+	if retryCount < 1 || r.BaseDelay <= 0 {
+		return lastAttempt
+	}
+
+	delay := r.BaseDelay
+	for i := 1; i < retryCount && (r.MaxDelay <= 0 || delay < r.MaxDelay); i++ {
+		delay *= 2
+	}
+	return lastAttempt.Add(capDelay(delay, r.MaxDelay))
+}
+
+func (r ExponentialRetry) ShouldGiveUp(retryCount int) bool {
+	return giveUpAfter(r.MaxAttempts, retryCount)
+}
+
+// LinearRetry waits one more Step after each failure, up to MaxDelay. A zero
+// MaxDelay doesn't cap the wait.
+type LinearRetry struct {
+	Step        time.Duration
+	MaxDelay    time.Duration
+	MaxAttempts int
+}
+
+func (r LinearRetry) NextAttempt(retryCount int, lastAttempt time.Time) time.Time {
+	if retryCount < 1 || r.Step <= 0 {
+		return lastAttempt
+	}
+
+	delay := r.MaxDelay
+	if r.MaxDelay <= 0 || retryCount <= int(r.MaxDelay/r.Step) {
+		delay = r.Step * time.Duration(retryCount)
+	}
+	return lastAttempt.Add(capDelay(delay, r.MaxDelay))
+}
+
+func (r LinearRetry) ShouldGiveUp(retryCount int) bool {
+	return giveUpAfter(r.MaxAttempts, retryCount)
+}
+
+func capDelay(delay, max time.Duration) time.Duration {
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
+
+func giveUpAfter(maxAttempts, retryCount int) bool {
+	return maxAttempts > 0 && retryCount >= maxAttempts
+}
+
+// ValidateRetryStrategy checks a RETRY_STRATEGY name. An empty name is valid and
+// means exponential.
+func ValidateRetryStrategy(name string) error {
+	switch name {
+	case "", RetryStrategyFixed, RetryStrategyExponential, RetryStrategyLinear:
+		return nil
+	}
+	return fmt.Errorf("retry strategy must be one of fixed, exponential, linear, got %q", name)
+}
+
+// NewRetryStrategy builds the strategy named by cfg.RetryStrategy from the
+// RETRY_BASE_DELAY and RETRY_MAX_DELAY settings. Unknown names fall back to
+// exponential, as Config.Validate rejects them at startup.
+func NewRetryStrategy(cfg *config.Config) RetryStrategy {
+	switch cfg.RetryStrategy {
+	case RetryStrategyFixed:
+		return FixedRetry{Delay: cfg.RetryBaseDelay}
+	case RetryStrategyLinear:
+		return LinearRetry{Step: cfg.RetryBaseDelay, MaxDelay: cfg.RetryMaxDelay}
+	default:
+		return ExponentialRetry{BaseDelay: cfg.RetryBaseDelay, MaxDelay: cfg.RetryMaxDelay}
+	}
+}
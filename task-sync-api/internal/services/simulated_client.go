@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+// simulatedClient stands in for the sync server when none is configured. Each
+// call takes a short delay and about one in ten fails, so the retry path gets
+// exercised in demos. Tests that need exact outcomes inject their own client
+// with SetClient.
+type simulatedClient struct{}
+
+func (simulatedClient) CreateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return nil, simulateSync(ctx, models.OperationTypeCreate, task)
+}
+
+func (simulatedClient) UpdateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return nil, simulateSync(ctx, models.OperationTypeUpdate, task)
+}
+
+func (simulatedClient) DeleteTask(ctx context.Context, task *models.Task) error {
+	return simulateSync(ctx, models.OperationTypeDelete, task)
+}
+
+func simulateSync(ctx context.Context, opType models.OperationType, task *models.Task) error {
+	// Simulate network delay
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// Simulate occasional failures (10% chance)
+	if time.Now().UnixNano()%10 == 0 {
+		return fmt.Errorf("simulated network error")
+	}
+
+	log.Printf("Successfully synced task %s with operation %s", task.ID, opType)
+	return nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+// attemptCursor is the position of the last attempt on a page of the global
+// attempt log, handed to clients as opaque base64.
+type attemptCursor struct {
+	AttemptedAt time.Time `json:"a"`
+	ID          int       `json:"id"`
+}
+
+func encodeAttemptCursor(attempt *models.SyncAttempt) string {
+	data, _ := json.Marshal(attemptCursor{AttemptedAt: attempt.AttemptedAt, ID: attempt.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeAttemptCursor(cursor string) (*attemptCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c attemptCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID < 1 || c.AttemptedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// GetSyncAttempts returns up to limit sync attempts across all tasks following
+// cursor, newest first, ordered by (attempted_at, id). A non-nil success keeps
+// only attempts with that outcome. An empty cursor starts at the most recent
+// attempt; the returned cursor is empty once there are no more.
+func (s *SyncService) GetSyncAttempts(success *bool, cursor string, limit int) ([]*models.SyncAttempt, string, error) {
+	owned, args := s.ownerCondition(taskOwnedBy)
+	conditions := []string{owned}
+
+	if success != nil {
+		conditions = append(conditions, "success = ?")
+		args = append(args, *success)
+	}
+	if cursor != "" {
+		after, err := decodeAttemptCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		conditions = append(conditions, "(attempted_at < ? OR (attempted_at = ? AND id < ?))")
+		args = append(args, after.AttemptedAt, after.AttemptedAt, after.ID)
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	query := `
+        SELECT id, task_id, operation_type, success, error_message, attempted_at
+        FROM sync_attempts
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY attempted_at DESC, id DESC
+        LIMIT ?
+    `
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(s.context(), query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query sync attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []*models.SyncAttempt{}
+	for rows.Next() {
+		attempt := &models.SyncAttempt{}
+		var errorMessage sql.NullString
+		err := rows.Scan(&attempt.ID, &attempt.TaskID, &attempt.OperationType,
+			&attempt.Success, &errorMessage, &attempt.AttemptedAt)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan sync attempt: %w", err)
+		}
+		if errorMessage.Valid {
+			attempt.ErrorMessage = &errorMessage.String
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read sync attempts: %w", err)
+	}
+
+	var next string
+	if len(attempts) > limit {
+		attempts = attempts[:limit]
+		next = encodeAttemptCursor(attempts[limit-1])
+	}
+
+	return attempts, next, nil
+}
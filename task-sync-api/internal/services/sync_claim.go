@@ -0,0 +1,83 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// defaultSyncClaimTimeout is used when SyncClaimTimeout isn't set.
+const defaultSyncClaimTimeout = 10 * time.Minute
+
+// claimTimeout is how long a claim holds before another pass may take the item.
+func (s *SyncService) claimTimeout() time.Duration {
+	if s.config.SyncClaimTimeout <= 0 {
+		return defaultSyncClaimTimeout
+	}
+	return s.config.SyncClaimTimeout
+}
+
+// ClaimBatch marks the next batch of queue items as claimed and returns them
+// with the claim ID that names them. Items already claimed are skipped unless
+// their claim is older than SyncClaimTimeout, so two callers never get the same
+// item. The claim lasts until ReleaseClaim or until it goes stale.
+func (s *SyncService) ClaimBatch(opts SyncOptions) (string, []*models.SyncQueueItem, error) {
+	candidates, err := s.nextBatch(opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	claimID := uuid.NewString()
+	if len(candidates) == 0 {
+		return claimID, nil, nil
+	}
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The claim condition is checked again row by row, so a caller that selected
+	// the same items first leaves nothing for this one
+	now := time.Now()
+	staleBefore := now.Add(-s.claimTimeout())
+	items := make([]*models.SyncQueueItem, 0, len(candidates))
+	for _, item := range candidates {
+		result, err := tx.Exec(`
+            UPDATE sync_queue SET claimed_at = ?, claimed_by = ?
+            WHERE id = ? AND (claimed_at IS NULL OR claimed_at <= ?)
+        `, now, claimID, item.ID, staleBefore)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to claim sync queue item: %w", err)
+		}
+		if claimed, _ := result.RowsAffected(); claimed == 0 {
+			continue
+		}
+
+		claimedAt, claimedBy := now, claimID
+		item.ClaimedAt = &claimedAt
+		item.ClaimedBy = &claimedBy
+		items = append(items, item)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return claimID, items, nil
+}
+
+// ReleaseClaim clears the claim on every item claimID still holds, leaving them
+// free for the next pass. Items that synced have already left the queue.
+func (s *SyncService) ReleaseClaim(claimID string) error {
+	_, err := s.db.ExecContext(s.context(), `
+        UPDATE sync_queue SET claimed_at = NULL, claimed_by = NULL WHERE claimed_by = ?
+    `, claimID)
+	if err != nil {
+		return fmt.Errorf("failed to release sync queue claim: %w", err)
+	}
+	return nil
+}
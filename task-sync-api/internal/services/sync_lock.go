@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrSyncInProgress is returned when a sync pass is skipped because another one,
+// in this process or another instance sharing the database, holds the sync lock.
+var ErrSyncInProgress = errors.New("sync already in progress")
+
+// syncLockName is the sync_locks row guarding sync passes.
+const syncLockName = "sync_queue"
+
+// defaultSyncLockTTL is used when SyncLockTTL isn't set.
+const defaultSyncLockTTL = 10 * time.Minute
+
+// acquireSyncLock takes the sync lock for this service, returning false when it
+// is held by someone else. A lock whose holder crashed lapses once it expires,
+// so SyncLockTTL must be longer than the slowest sync pass.
+func (s *SyncService) acquireSyncLock() (bool, error) {
+	ttl := s.config.SyncLockTTL
+	if ttl <= 0 {
+		ttl = defaultSyncLockTTL
+	}
+
+	now := time.Now()
+	result, err := s.db.ExecContext(s.context(), `
+        INSERT INTO sync_locks (name, holder, acquired_at, expires_at) VALUES (?, ?, ?, ?)
+        ON CONFLICT (name) DO UPDATE
+        SET holder = excluded.holder, acquired_at = excluded.acquired_at, expires_at = excluded.expires_at
+        WHERE sync_locks.expires_at <= ?
+    `, syncLockName, s.lockHolder, now, now.Add(ttl), now)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire sync lock: %w", err)
+	}
+
+	acquired, _ := result.RowsAffected()
+	return acquired == 1, nil
+}
+
+// releaseSyncLock gives up the sync lock if this service still holds it.
+func (s *SyncService) releaseSyncLock() {
+	_, err := s.db.ExecContext(s.context(), `DELETE FROM sync_locks WHERE name = ? AND holder = ?`,
+		syncLockName, s.lockHolder)
+	if err != nil {
+		log.Printf("Failed to release sync lock: %v", err)
+	}
+}
+
+// syncLocked reports whether any sync pass currently holds the sync lock.
+func (s *SyncService) syncLocked() (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(s.context(), `SELECT COUNT(*) FROM sync_locks WHERE name = ? AND expires_at > ?`,
+		syncLockName, time.Now()).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check sync lock: %w", err)
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/syncclient"
+)
+
+// PullSyncClient is implemented by clients that can list the server's tasks.
+// Pull reports syncclient.ErrPullUnsupported for other clients.
+type PullSyncClient interface {
+	ListTasks(ctx context.Context, since time.Time) ([]*models.Task, error)
+}
+
+// Pull fetches the server's tasks changed after since, or all of them when since
+// is zero, and merges each into the local store. A task missing locally is
+// inserted, one whose local copy has no unsynced changes takes a newer server
+// copy, and one with unsynced local changes goes through ResolveConflict. Pull
+// holds the sync lock so it never interleaves with a push pass.
+func (s *SyncService) Pull(ctx context.Context, since time.Time) (*models.PullResult, error) {
+	client, ok := s.client.(PullSyncClient)
+	if !ok {
+		return nil, syncclient.ErrPullUnsupported
+	}
+
+	acquired, err := s.acquireSyncLock()
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrSyncInProgress
+	}
+	defer s.releaseSyncLock()
+
+	remotes, err := client.ListTasks(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.PullResult{}
+	for _, remote := range remotes {
+		if err := s.pullTask(remote, result); err != nil {
+			return result, fmt.Errorf("failed to merge task %s: %w", remote.ID, err)
+		}
+	}
+
+	log.Printf("Pulled %d tasks: %d inserted, %d updated, %d conflicted, %d unchanged",
+		len(remotes), result.Inserted, result.Updated, result.Conflicted, result.Unchanged)
+	return result, nil
+}
+
+// pullTask merges one server task and counts the outcome in result.
+func (s *SyncService) pullTask(remote *models.Task, result *models.PullResult) error {
+	local, err := s.findPulledTask(remote)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case local == nil && remote.IsDeleted:
+		// Nothing to merge a deletion into
+		result.Unchanged++
+		return nil
+	case local == nil:
+		if err := s.insertRemoteTask(remote); err != nil {
+			return err
+		}
+		result.Inserted++
+		return nil
+	case !remote.UpdatedAt.After(local.UpdatedAt):
+		result.Unchanged++
+		return nil
+	case local.SyncStatus != models.SyncStatusSynced:
+		if _, err := s.ResolveConflict(local, remote); err != nil {
+			return err
+		}
+		result.Conflicted++
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.applyRemoteTx(tx, local.ID, remote); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	result.Updated++
+	return nil
+}
+
+// findPulledTask returns the local copy of a server task, matched by ID and then
+// by server_id, or nil when there is none. Deleted local tasks count as copies.
+func (s *SyncService) findPulledTask(remote *models.Task) (*models.Task, error) {
+	local, err := scanTask(s.db.QueryRowContext(s.context(), `SELECT `+taskColumns+` FROM tasks WHERE id = ?`, remote.ID))
+	if err == sql.ErrNoRows && remote.ServerID != nil {
+		local, err = scanTask(s.db.QueryRowContext(s.context(),
+			`SELECT `+taskColumns+` FROM tasks WHERE server_id = ?`, *remote.ServerID))
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return local, nil
+}
+
+// insertRemoteTask stores a task that so far only the server has. It is already
+// in sync, so nothing is queued.
+func (s *SyncService) insertRemoteTask(remote *models.Task) error {
+	task := *remote
+	task.SyncStatus = models.SyncStatusSynced
+	now := time.Now()
+	task.LastSyncedAt = &now
+	if task.ServerID == nil {
+		task.ServerID = &task.ID
+	}
+	if task.CreatedBy == "" {
+		task.CreatedBy = models.SystemActor
+	}
+	if task.UpdatedBy == "" {
+		task.UpdatedBy = models.SystemActor
+	}
+	metadata, err := metadataJSON(task.Metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        INSERT INTO tasks (id, user_id, title, description, completed, priority, created_at, updated_at,
+                          is_deleted, deleted_at, archived, sync_status, server_id, last_synced_at, due_date,
+                          recurrence_rule, created_by, updated_by, metadata)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, task.ID, task.UserID, task.Title, task.Description, task.Completed, task.Priority, task.CreatedAt, task.UpdatedAt,
+		task.IsDeleted, task.DeletedAt, task.Archived, task.SyncStatus, task.ServerID, task.LastSyncedAt,
+		task.DueDate, task.RecurrenceRule, task.CreatedBy, task.UpdatedBy, metadata)
+	if err != nil {
+		return fmt.Errorf("failed to insert task: %w", err)
+	}
+
+	if err := setTaskTagsTx(tx, task.ID, task.Tags); err != nil {
+		return err
+	}
+	if err := recordEventTx(tx, models.TaskEventCreated, &task); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -1,19 +1,120 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/config"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/syncclient"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/tracing"
+
+	"github.com/google/uuid"
+)
+
+// ErrQueueFull is returned when the sync queue has reached MaxQueueSize. The
+// change is rejected rather than queued so a stuck server can't grow the queue
+// without bound.
+var ErrQueueFull = errors.New("sync queue is full")
+
+// ErrConflictNotFound is returned when no conflict log entry has the given ID.
+var ErrConflictNotFound = errors.New("conflict not found")
+
+// ErrQueueItemNotFound is returned when no sync queue item has the given ID.
+var ErrQueueItemNotFound = errors.New("sync queue item not found")
+
+// ErrConflictNotPending is returned when resolving a conflict that isn't held for
+// manual review, either because it was resolved automatically or already reviewed.
+var ErrConflictNotPending = errors.New("conflict is not awaiting review")
+
+// ErrInvalidConflictWinner is returned when a manual resolution names neither
+// the local nor the remote copy.
+var ErrInvalidConflictWinner = errors.New("winner must be local or remote")
+
+// ErrQueueItemExpired is the dead-letter reason for an item that failed after
+// outliving QueueItemTTL.
+var ErrQueueItemExpired = errors.New("expired")
+
+type ConflictStrategy string
+
+const (
+	ConflictStrategyLastWriteWins ConflictStrategy = "last_write_wins"
+	ConflictStrategyServerWins    ConflictStrategy = "server_wins"
+	ConflictStrategyClientWins    ConflictStrategy = "client_wins"
 )
 
+// LifecycleSyncClient is implemented by clients whose server has dedicated
+// restore and archive operations. Other clients push these as plain updates,
+// since the queued task already carries its is_deleted and archived state.
+type LifecycleSyncClient interface {
+	RestoreTask(ctx context.Context, task *models.Task) (*models.Task, error)
+	ArchiveTask(ctx context.Context, task *models.Task) (*models.Task, error)
+}
+
+// BatchSyncClient is implemented by clients that can push many operations in one
+// request. Clients without it are synced one item at a time.
+type BatchSyncClient interface {
+	BatchSync(ctx context.Context, items []syncclient.BatchItem) ([]syncclient.BatchResult, error)
+}
+
+// SyncRunNotifier is told about each sync pass once it finishes. It must not
+// block the sync pass for long.
+type SyncRunNotifier interface {
+	NotifySyncRun(summary models.SyncRunSummary)
+}
+
+// SyncTransform rewrites a task just before it is pushed to the server, for
+// example to enrich it or redact fields the server shouldn't see. It gets a
+// copy of the task, which it may change and return or replace. An error fails
+// the item like a failed push, so it is retried later.
+type SyncTransform func(*models.Task) (*models.Task, error)
+
 type SyncService struct {
-	db     *database.DB
-	config *config.Config
+	db               *database.DB
+	config           *config.Config
+	conflictStrategy ConflictStrategy
+	ctx              context.Context
+	// userID, when set, limits listings and lookups to that user's items
+	userID *string
+
+	// Views made by WithContext share this state with the service they came from
+	*syncState
+}
+
+type syncState struct {
+	client           syncclient.SyncTransport
+	batchUnsupported bool
+	runNotifier      SyncRunNotifier
+	transform        SyncTransform
+	retryStrategy    RetryStrategy
+
+	// lockHolder names this service in sync_locks
+	lockHolder string
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	// resultMu serialises database work when items sync concurrently, so only
+	// the server calls overlap
+	resultMu sync.Mutex
+
+	// queueSize caches the sync queue length for the MaxQueueSize check. It is
+	// recounted every QueueSizeRefresh and bumped by each insert in between.
+	queueSizeMu sync.Mutex
+	queueSize   int
+	queueSizeAt time.Time
 }
 
 type SyncStatus struct {
@@ -21,71 +122,387 @@ type SyncStatus struct {
 	ErrorCount   int       `json:"error_count"`
 	LastSync     time.Time `json:"last_sync"`
 	InProgress   bool      `json:"in_progress"`
+	// OldestPendingAge is how long the oldest retryable queue item has been
+	// waiting; zero when the queue is empty.
+	OldestPendingAge time.Duration `json:"oldest_pending_age_ns"`
+	// DeadLetterCount is the number of queue items that exhausted their retries.
+	DeadLetterCount int `json:"dead_letter_count"`
+	// Paused is set while syncing is paused; changes keep queueing meanwhile.
+	Paused bool `json:"paused"`
 }
 
+// NewSyncService builds a sync service that pushes over HTTP to SYNC_SERVER_URL,
+// or to the built-in simulation when it is unset. See NewSyncServiceWithTransport.
 func NewSyncService(db *database.DB, config *config.Config) *SyncService {
-	return &SyncService{
-		db:     db,
-		config: config,
+	var transport syncclient.SyncTransport
+	if config.SyncServerURL != "" {
+		transport = syncclient.NewClient(config.SyncServerURL, &http.Client{Timeout: 10 * time.Second})
+	}
+	return NewSyncServiceWithTransport(db, config, transport)
+}
+
+// NewSyncServiceWithTransport builds a sync service that pushes through
+// transport. A nil transport falls back to the built-in simulation.
+func NewSyncServiceWithTransport(db *database.DB, config *config.Config, transport syncclient.SyncTransport) *SyncService {
+	strategy := ConflictStrategy(config.ConflictStrategy)
+	switch strategy {
+	case ConflictStrategyLastWriteWins, ConflictStrategyServerWins, ConflictStrategyClientWins:
+	default:
+		strategy = ConflictStrategyLastWriteWins
+	}
+
+	service := &SyncService{
+		db:               db,
+		config:           config,
+		conflictStrategy: strategy,
+		syncState: &syncState{
+			rng:           rand.New(rand.NewSource(time.Now().UnixNano())),
+			lockHolder:    uuid.NewString(),
+			retryStrategy: NewRetryStrategy(config),
+		},
+	}
+	service.SetClient(transport)
+	return service
+}
+
+// WithContext returns a view of the service whose database calls run under ctx,
+// so they are abandoned once ctx is cancelled or its deadline passes. The view
+// shares its client and queue bookkeeping with s.
+func (s *SyncService) WithContext(ctx context.Context) *SyncService {
+	scoped := *s
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// ForUser returns a view of the service whose listings and lookups of queue
+// items, conflicts, sync attempts and tasks only see userID's. The unscoped
+// service, which sync passes use, sees every user's.
+func (s *SyncService) ForUser(userID string) *SyncService {
+	scoped := *s
+	scoped.userID = &userID
+	return &scoped
+}
+
+// ownerCondition returns condition with the scoped user as its argument, or a
+// condition matching everything when the service isn't scoped.
+func (s *SyncService) ownerCondition(condition string) (string, []interface{}) {
+	if s.userID == nil {
+		return "1 = 1", nil
+	}
+	return condition, []interface{}{*s.userID}
+}
+
+// taskOwnedBy limits rows of tables without a user_id to the owner's tasks.
+const taskOwnedBy = "task_id IN (SELECT id FROM tasks WHERE user_id = ?)"
+
+// context is the context database calls run under.
+func (s *SyncService) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// SetClient replaces the transport used to reach the sync server. A nil client
+// falls back to the built-in simulation.
+func (s *SyncService) SetClient(client syncclient.SyncTransport) {
+	if client == nil {
+		client = simulatedClient{}
+	}
+	s.client = client
+	s.batchUnsupported = false
+}
+
+// SetRunNotifier registers a notifier told about each finished sync pass.
+func (s *SyncService) SetRunNotifier(notifier SyncRunNotifier) {
+	s.runNotifier = notifier
+}
+
+// SetTransform registers a hook applied to each task before it is pushed. A nil
+// transform pushes tasks unchanged.
+func (s *SyncService) SetTransform(transform SyncTransform) {
+	s.transform = transform
+}
+
+// outgoingTask is the copy of task to push, after the SyncTransform if one is set.
+func (s *SyncService) outgoingTask(task *models.Task) (*models.Task, error) {
+	if s.transform == nil {
+		return task, nil
+	}
+	outgoing, err := s.transform(task.Clone())
+	if err != nil {
+		return nil, fmt.Errorf("sync transform failed: %w", err)
+	}
+	if outgoing == nil {
+		return nil, errors.New("sync transform returned no task")
+	}
+	return outgoing, nil
+}
+
+// SeedJitter reseeds the random source used for retry jitter so tests can
+// predict the delays.
+func (s *SyncService) SeedJitter(seed int64) {
+	s.rngMu.Lock()
+	defer s.rngMu.Unlock()
+	s.rng = rand.New(rand.NewSource(seed))
+}
+
+// SetRetryStrategy replaces the strategy that schedules retries and decides
+// when to give up. A nil strategy restores the one named by RETRY_STRATEGY.
+func (s *SyncService) SetRetryStrategy(strategy RetryStrategy) {
+	if strategy == nil {
+		strategy = NewRetryStrategy(s.config)
+	}
+	s.retryStrategy = strategy
+}
+
+// RetryDelay is how long a queue item waits after its retryCount-th failure, as
+// scheduled by the retry strategy.
+func (s *SyncService) RetryDelay(retryCount int) time.Duration {
+	now := time.Now()
+	return s.nextAttempt(retryCount, now).Sub(now)
+}
+
+// nextAttempt is when an item that failed for the retryCount-th time at
+// lastAttempt is next eligible. The strategy's delay is spread by up to
+// ±RetryJitterPercent so items that failed together don't retry together.
+func (s *SyncService) nextAttempt(retryCount int, lastAttempt time.Time) time.Time {
+	next := s.retryStrategy.NextAttempt(retryCount, lastAttempt)
+	delay := next.Sub(lastAttempt)
+	if delay <= 0 {
+		return lastAttempt
 	}
+
+	if s.config.RetryJitterPercent > 0 {
+		s.rngMu.Lock()
+		factor := (s.rng.Float64()*2 - 1) * float64(s.config.RetryJitterPercent) / 100
+		s.rngMu.Unlock()
+		delay += time.Duration(float64(delay) * factor)
+	}
+
+	return lastAttempt.Add(delay)
 }
 
 func (s *SyncService) AddToQueue(taskID string, opType models.OperationType, task *models.Task) error {
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(s.context(), nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	if err := s.AddToQueueTx(tx, taskID, opType, task); err != nil {
+	if _, err := s.AddToQueueTx(tx, taskID, opType, task); err != nil {
 		return err
 	}
 
 	return tx.Commit()
 }
 
-func (s *SyncService) AddToQueueTx(tx *sql.Tx, taskID string, opType models.OperationType, task *models.Task) error {
+// AddToQueueTx queues the operation inside tx and returns the queued item with
+// its ID.
+func (s *SyncService) AddToQueueTx(tx *sql.Tx, taskID string, opType models.OperationType, task *models.Task) (*models.SyncQueueItem, error) {
+	if err := s.reserveQueueSlot(tx); err != nil {
+		return nil, err
+	}
+
 	queueItem, err := models.NewSyncQueueItem(taskID, opType, task)
 	if err != nil {
-		return fmt.Errorf("failed to create queue item: %w", err)
+		return nil, fmt.Errorf("failed to create queue item: %w", err)
 	}
+	queueItem.SyncPriority = s.syncPriority(opType)
 
+	// An identical operation already queued, such as one re-added after a crash,
+	// is kept as the single copy. If that copy was dead-lettered it is given a
+	// fresh set of retries instead.
+	retryLimit, limitArgs := s.retryLimitSQL(s.config.MaxRetries)
 	query := `
-        INSERT INTO sync_queue (task_id, operation_type, task_data, retry_count, created_at)
-        VALUES (?, ?, ?, ?, ?)
+        INSERT INTO sync_queue (task_id, user_id, operation_type, task_data, compressed, retry_count, created_at,
+                                content_hash, sync_priority)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT (task_id, operation_type, content_hash) DO UPDATE
+        SET retry_count = 0, next_attempt_at = NULL, error_message = NULL, server_retry_after = NULL,
+            sync_priority = excluded.sync_priority
+        WHERE retry_count >= ` + retryLimit + `
     `
 
-	_, err = tx.Exec(query, queueItem.TaskID, queueItem.OperationType,
-		queueItem.TaskData, queueItem.RetryCount, queueItem.CreatedAt)
+	args := append([]interface{}{queueItem.TaskID, queueItem.UserID, queueItem.OperationType,
+		queueItem.TaskData, queueItem.Compressed, queueItem.RetryCount, queueItem.CreatedAt, queueItem.ContentHash,
+		queueItem.SyncPriority}, limitArgs...)
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert into sync queue: %w", err)
+	}
+
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		s.releaseQueueSlot()
+	}
+
+	// Read the row back rather than trusting LastInsertId, which isn't set when
+	// the operation was already queued.
+	row := tx.QueryRow(`SELECT `+queueColumns+` FROM sync_queue
+        WHERE task_id = ? AND operation_type = ? AND content_hash = ?`,
+		queueItem.TaskID, queueItem.OperationType, queueItem.ContentHash)
+	stored, err := scanQueueItem(row)
 	if err != nil {
-		return fmt.Errorf("failed to insert into sync queue: %w", err)
+		return nil, fmt.Errorf("failed to read queued item: %w", err)
+	}
+	return stored, nil
+}
+
+// maxRetriesFor is the retry limit for opType: its MaxRetriesByOperation
+// entry, or fallback when it has none.
+func (s *SyncService) maxRetriesFor(opType models.OperationType, fallback int) int {
+	if limit, ok := s.config.MaxRetriesByOperation[string(opType)]; ok {
+		return limit
+	}
+	return fallback
+}
+
+// retryLimitSQL returns an SQL expression for each sync_queue row's retry
+// limit, following maxRetriesFor, along with its arguments.
+func (s *SyncService) retryLimitSQL(fallback int) (string, []interface{}) {
+	if len(s.config.MaxRetriesByOperation) == 0 {
+		return "?", []interface{}{fallback}
+	}
+
+	ops := make([]string, 0, len(s.config.MaxRetriesByOperation))
+	for op := range s.config.MaxRetriesByOperation {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	expr := "CASE operation_type"
+	var args []interface{}
+	for _, op := range ops {
+		expr += " WHEN ? THEN ?"
+		args = append(args, op, s.config.MaxRetriesByOperation[op])
+	}
+	return expr + " ELSE ? END", append(args, fallback)
+}
+
+// syncPriority is the queue priority configured for opType. Operations without
+// an entry in SyncPriorities get 0.
+func (s *SyncService) syncPriority(opType models.OperationType) int {
+	return s.config.SyncPriorities[string(opType)]
+}
+
+// reserveQueueSlot returns ErrQueueFull when the queue is at MaxQueueSize and
+// otherwise counts the item about to be inserted. The count is only refreshed
+// from the table every QueueSizeRefresh, so the limit is a soft one.
+func (s *SyncService) reserveQueueSlot(tx *sql.Tx) error {
+	if s.config.MaxQueueSize <= 0 {
+		return nil
+	}
+
+	s.queueSizeMu.Lock()
+	defer s.queueSizeMu.Unlock()
+
+	if s.queueSizeAt.IsZero() || time.Since(s.queueSizeAt) >= s.config.QueueSizeRefresh {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM sync_queue`).Scan(&count); err != nil {
+			return fmt.Errorf("failed to count sync queue: %w", err)
+		}
+		s.queueSize = count
+		s.queueSizeAt = time.Now()
 	}
 
+	if s.queueSize >= s.config.MaxQueueSize {
+		return ErrQueueFull
+	}
+	s.queueSize++
 	return nil
 }
 
-func (s *SyncService) ProcessSyncQueue() error {
+// releaseQueueSlot gives back a slot reserved for an item that wasn't inserted.
+func (s *SyncService) releaseQueueSlot() {
+	if s.config.MaxQueueSize <= 0 {
+		return
+	}
+
+	s.queueSizeMu.Lock()
+	defer s.queueSizeMu.Unlock()
+	if s.queueSize > 0 {
+		s.queueSize--
+	}
+}
+
+// invalidateQueueSize forces the next queue size check to recount the table.
+func (s *SyncService) invalidateQueueSize() {
+	s.queueSizeMu.Lock()
+	s.queueSizeAt = time.Time{}
+	s.queueSizeMu.Unlock()
+}
+
+const queueColumns = `
+        id, task_id, user_id, operation_type, task_data, compressed, retry_count, created_at,
+        last_attempt, error_message, next_attempt_at, server_retry_after, sync_priority,
+        claimed_at, claimed_by
+`
+
+func scanQueueItem(row rowScanner) (*models.SyncQueueItem, error) {
+	item := &models.SyncQueueItem{}
+	err := row.Scan(&item.ID, &item.TaskID, &item.UserID, &item.OperationType,
+		&item.TaskData, &item.Compressed, &item.RetryCount, &item.CreatedAt,
+		&item.LastAttempt, &item.ErrorMessage, &item.NextAttemptAt, &item.ServerRetryAfter, &item.SyncPriority,
+		&item.ClaimedAt, &item.ClaimedBy)
+	return item, err
+}
+
+// SyncPlanItem describes a queue item the next sync run would push.
+type SyncPlanItem struct {
+	QueueID       int                  `json:"queue_id"`
+	TaskID        string               `json:"task_id"`
+	OperationType models.OperationType `json:"operation_type"`
+	RetryCount    int                  `json:"retry_count"`
+	SyncPriority  int                  `json:"sync_priority"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+// nextBatch loads the queue items the next sync run should process.
+func (s *SyncService) nextBatch(opts SyncOptions) ([]*models.SyncQueueItem, error) {
 	// Get pending items in batches
+	// Items still backing off after a failure are skipped until the next attempt
+	// their retry strategy scheduled; items it gave up on are at the retry limit
+	// Items claimed by a pass still working them are skipped until the claim goes stale
+	// Tasks with a higher-priority item drain first, then the oldest items; a
+	// task's own items always keep the order they were queued in
+	now := time.Now()
+	retryLimit, args := s.retryLimitSQL(opts.MaxRetries)
+	args = append(args, now, now.Add(-s.claimTimeout()))
+	// A scoped view only plans the caller's items
+	owned, ownerArgs := s.ownerCondition(taskOwnedBy)
+	args = append(args, ownerArgs...)
+	taskFilter := ""
+	if len(opts.TaskIDs) > 0 {
+		taskFilter = "AND task_id IN (?" + strings.Repeat(", ?", len(opts.TaskIDs)-1) + ")"
+		for _, id := range opts.TaskIDs {
+			args = append(args, id)
+		}
+	}
+	args = append(args, opts.BatchSize)
+
 	query := `
-        SELECT id, task_id, operation_type, task_data, retry_count, created_at, last_attempt, error_message
-        FROM sync_queue
-        WHERE retry_count < ?
-        ORDER BY created_at ASC
+        SELECT ` + queueColumns + `
+        FROM (
+            SELECT *, MAX(sync_priority) OVER (PARTITION BY task_id) AS task_priority
+            FROM sync_queue
+            WHERE retry_count < ` + retryLimit + ` AND (next_attempt_at IS NULL OR next_attempt_at <= ?)
+              AND (claimed_at IS NULL OR claimed_at <= ?)
+              AND ` + owned + `
+              ` + taskFilter + `
+        )
+        ORDER BY task_priority DESC, created_at ASC, id ASC
         LIMIT ?
     `
 
-	rows, err := s.db.Query(query, s.config.MaxRetries, s.config.SyncBatchSize)
+	rows, err := s.db.QueryContext(s.context(), query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to query sync queue: %w", err)
+		return nil, fmt.Errorf("failed to query sync queue: %w", err)
 	}
 	defer rows.Close()
 
 	var items []*models.SyncQueueItem
 	for rows.Next() {
-		item := &models.SyncQueueItem{}
-		err := rows.Scan(&item.ID, &item.TaskID, &item.OperationType,
-			&item.TaskData, &item.RetryCount, &item.CreatedAt,
-			&item.LastAttempt, &item.ErrorMessage)
+		item, err := scanQueueItem(rows)
 		if err != nil {
 			log.Printf("Failed to scan sync queue item: %v", err)
 			continue
@@ -93,170 +510,1476 @@ func (s *SyncService) ProcessSyncQueue() error {
 		items = append(items, item)
 	}
 
-	// Process each item
-	for _, item := range items {
-		if err := s.processSyncItem(item); err != nil {
-			log.Printf("Failed to process sync item %d: %v", item.ID, err)
-		}
-	}
-
-	return nil
+	return items, rows.Err()
 }
 
-func (s *SyncService) processSyncItem(item *models.SyncQueueItem) error {
-	task, err := item.GetTaskData()
+// DryRunSync reports what the next ProcessSyncQueue run would push without
+// contacting the server or changing the database. A view made by ForUser only
+// reports the user's items.
+func (s *SyncService) DryRunSync() ([]SyncPlanItem, error) {
+	items, err := s.nextBatch(s.DefaultSyncOptions())
 	if err != nil {
-		return fmt.Errorf("failed to parse task data: %w", err)
+		return nil, err
 	}
 
-	// Simulate server sync operation
-	success, err := s.syncToServer(item.OperationType, task)
-	if err != nil || !success {
-		return s.handleSyncError(item, err)
+	plan := make([]SyncPlanItem, 0, len(items))
+	for _, item := range items {
+		plan = append(plan, SyncPlanItem{
+			QueueID:       item.ID,
+			TaskID:        item.TaskID,
+			OperationType: item.OperationType,
+			RetryCount:    item.RetryCount,
+			SyncPriority:  item.SyncPriority,
+			CreatedAt:     item.CreatedAt,
+		})
 	}
 
-	// Mark as synced and remove from queue
-	return s.markAsSynced(item, task)
+	return plan, nil
+}
+
+// SyncOptions tunes a single sync run.
+type SyncOptions struct {
+	BatchSize  int `json:"batch_size"`
+	MaxRetries int `json:"max_retries"`
+	// TaskIDs limits the run to queue items for these tasks. Empty means every
+	// task.
+	TaskIDs []string `json:"task_ids"`
+
+	progress *progressCounter
 }
 
-func (s *SyncService) syncToServer(opType models.OperationType, task *models.Task) (bool, error) {
-	// Simulate server communication
-	// In a real implementation, this would make HTTP requests to the server
+// ProgressFunc is told how many items of the current batch have finished
+// syncing, whether or not they succeeded, and how many the batch holds.
+type ProgressFunc func(processed, total int)
 
-	// Simulate network delay
-	time.Sleep(10 * time.Millisecond)
+// progressCounter counts finished items and reports each one to fn. Reports
+// are serialized, so fn sees processed increase by one each call even when
+// items sync concurrently.
+type progressCounter struct {
+	mu        sync.Mutex
+	fn        ProgressFunc
+	processed int
+	total     int
+}
 
-	// Simulate occasional failures (10% chance)
-	if time.Now().UnixNano()%10 == 0 {
-		return false, fmt.Errorf("simulated network error")
+func (p *progressCounter) start(total int) {
+	if p == nil {
+		return
 	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processed, p.total = 0, total
+}
 
-	log.Printf("Successfully synced task %s with operation %s", task.ID, opType)
-	return true, nil
+func (p *progressCounter) itemDone() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processed++
+	p.fn(p.processed, p.total)
 }
 
-func (s *SyncService) handleSyncError(item *models.SyncQueueItem, syncErr error) error {
-	errorMsg := "unknown error"
-	if syncErr != nil {
-		errorMsg = syncErr.Error()
+const (
+	maxSyncBatchSize  = 1000
+	maxSyncMaxRetries = 20
+)
+
+// Validate checks the options are positive and within sane caps.
+func (o SyncOptions) Validate() error {
+	if o.BatchSize < 1 || o.BatchSize > maxSyncBatchSize {
+		return fmt.Errorf("batch_size must be between 1 and %d", maxSyncBatchSize)
+	}
+	if o.MaxRetries < 1 || o.MaxRetries > maxSyncMaxRetries {
+		return fmt.Errorf("max_retries must be between 1 and %d", maxSyncMaxRetries)
+	}
+	if len(o.TaskIDs) > maxSyncBatchSize {
+		return fmt.Errorf("task_ids must list at most %d tasks", maxSyncBatchSize)
+	}
+	for _, id := range o.TaskIDs {
+		if strings.TrimSpace(id) == "" {
+			return errors.New("task_ids must not contain blank IDs")
+		}
 	}
+	return nil
+}
 
-	item.IncrementRetry(errorMsg)
+// DefaultSyncOptions returns the options from the service configuration.
+func (s *SyncService) DefaultSyncOptions() SyncOptions {
+	return SyncOptions{
+		BatchSize:  s.config.SyncBatchSize,
+		MaxRetries: s.config.MaxRetries,
+	}
+}
 
-	query := `
-        UPDATE sync_queue 
-        SET retry_count = ?, last_attempt = ?, error_message = ?
-        WHERE id = ?
-    `
+// ProcessSyncQueue runs one sync pass using the configured batch size and retry limit.
+func (s *SyncService) ProcessSyncQueue() error {
+	return s.ProcessSyncQueueWithOptions(context.Background(), s.DefaultSyncOptions())
+}
 
-	_, err := s.db.Exec(query, item.RetryCount, item.LastAttempt, item.ErrorMessage, item.ID)
+// ProcessSyncQueueForTasks runs one sync pass with the default options over
+// only the queue items for the given tasks, leaving the rest of the queue for
+// later passes. It returns ErrTaskNotFound if any ID is unknown.
+func (s *SyncService) ProcessSyncQueueForTasks(ids []string) error {
+	opts := s.DefaultSyncOptions()
+	opts.TaskIDs = ids
+	return s.ProcessSyncQueueWithOptions(context.Background(), opts)
+}
+
+// checkTasksKnown returns ErrTaskNotFound naming every ID that is neither a
+// task nor has queued operations. Hard-deleted tasks count as known while
+// their delete is still queued. A view made by ForUser only knows the user's
+// tasks, so other users' IDs are reported missing.
+func (s *SyncService) checkTasksKnown(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	owned, ownerArgs := s.ownerCondition("user_id = ?")
+	in := "(?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	var args []interface{}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, ownerArgs...)
+	args = append(args, args...)
+
+	rows, err := s.db.QueryContext(s.context(), `
+        SELECT id FROM tasks WHERE id IN `+in+` AND `+owned+`
+        UNION
+        SELECT task_id FROM sync_queue WHERE task_id IN `+in+` AND `+owned, args...)
 	if err != nil {
-		return fmt.Errorf("failed to update sync queue item: %w", err)
+		return fmt.Errorf("failed to look up tasks: %w", err)
 	}
+	defer rows.Close()
 
-	// If max retries reached, mark task as error
-	if item.RetryCount >= s.config.MaxRetries {
-		if err := s.markTaskAsError(item.TaskID); err != nil {
-			log.Printf("Failed to mark task as error: %v", err)
+	known := make(map[string]bool, len(ids))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan task id: %w", err)
 		}
+		known[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to look up tasks: %w", err)
 	}
 
+	var missing []string
+	for _, id := range ids {
+		if !known[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, strings.Join(missing, ", "))
+	}
 	return nil
 }
 
-func (s *SyncService) markAsSynced(item *models.SyncQueueItem, task *models.Task) error {
-	tx, err := s.db.Begin()
+// ProcessSyncQueueWithProgress runs one sync pass with the default options and
+// calls progress after each item in the batch finishes.
+func (s *SyncService) ProcessSyncQueueWithProgress(ctx context.Context, progress ProgressFunc) error {
+	opts := s.DefaultSyncOptions()
+	if progress != nil {
+		opts.progress = &progressCounter{fn: progress}
+	}
+	return s.ProcessSyncQueueWithOptions(ctx, opts)
+}
+
+// ProcessSyncQueueWithOptions runs one sync pass with the given batch size and
+// retry limit, which apply to this invocation only. Every run is recorded in sync_runs.
+// Each server call is bounded by the configured SyncItemTimeout as well as ctx.
+// While sync is paused it does nothing and records no run. Passes hold a lock in
+// the database, so when another pass is running, here or in another instance,
+// it returns ErrSyncInProgress without pushing anything. When opts.TaskIDs is
+// set, every ID must name a known task or it returns ErrTaskNotFound.
+func (s *SyncService) ProcessSyncQueueWithOptions(ctx context.Context, opts SyncOptions) error {
+	if err := s.checkTasksKnown(opts.TaskIDs); err != nil {
+		return err
+	}
+
+	paused, err := s.IsPaused()
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
+	if paused {
+		log.Printf("Sync is paused, skipping sync pass")
+		return nil
+	}
 
-	// Update task sync status
-	now := time.Now()
-	query := `
-        UPDATE tasks 
-        SET sync_status = 'synced', last_synced_at = ?, server_id = ?
-        WHERE id = ?
-    `
+	acquired, err := s.acquireSyncLock()
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		log.Printf("Another sync pass holds the sync lock, skipping sync pass")
+		return ErrSyncInProgress
+	}
+	defer s.releaseSyncLock()
 
-	serverID := task.ID // In real implementation, this would be from server response
-	_, err = tx.Exec(query, now, serverID, task.ID)
+	startedAt := time.Now()
+
+	claimID, items, err := s.ClaimBatch(opts)
 	if err != nil {
-		return fmt.Errorf("failed to update task sync status: %w", err)
+		return err
 	}
+	defer func() {
+		if err := s.ReleaseClaim(claimID); err != nil {
+			log.Printf("Failed to release sync queue claim: %v", err)
+		}
+	}()
+	opts.progress.start(len(items))
 
-	// Remove from sync queue
-	deleteQuery := `DELETE FROM sync_queue WHERE id = ?`
-	_, err = tx.Exec(deleteQuery, item.ID)
+	if err := s.processBatch(ctx, items, opts); err != nil {
+		return err
+	}
+
+	summary, err := s.recordRun(startedAt, items, opts)
 	if err != nil {
-		return fmt.Errorf("failed to remove from sync queue: %w", err)
+		log.Printf("Failed to record sync run: %v", err)
+	} else if s.runNotifier != nil {
+		s.runNotifier.NotifySyncRun(summary)
 	}
 
-	return tx.Commit()
-}
+	// Synced items have left the queue, so let writers see the room straight away
+	s.invalidateQueueSize()
 
-func (s *SyncService) markTaskAsError(taskID string) error {
-	query := `UPDATE tasks SET sync_status = 'error' WHERE id = ?`
-	_, err := s.db.Exec(query, taskID)
-	return err
+	return nil
 }
 
-func (s *SyncService) GetSyncStatus() (*SyncStatus, error) {
-	var pendingCount, errorCount int
-	var lastSyncStr sql.NullString
+func (s *SyncService) processBatch(ctx context.Context, items []*models.SyncQueueItem, opts SyncOptions) error {
+	if len(items) == 0 {
+		return nil
+	}
 
-	// Get pending count
-	err := s.db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE retry_count < ?", s.config.MaxRetries).Scan(&pendingCount)
-	if err != nil {
-		return nil, err
+	// Push the whole batch in one request when the server supports it
+	if _, ok := s.client.(BatchSyncClient); ok && !s.batchUnsupported {
+		err := s.BatchSyncToServer(ctx, items, opts)
+		if !errors.Is(err, syncclient.ErrBatchUnsupported) {
+			return err
+		}
+		log.Printf("Sync server does not support batch sync, falling back to per-item sync")
+		s.batchUnsupported = true
 	}
 
-	// Get error count
-	err = s.db.QueryRow("SELECT COUNT(*) FROM tasks WHERE sync_status = 'error'").Scan(&errorCount)
-	if err != nil {
-		return nil, err
+	if s.config.SyncConcurrency > 1 {
+		s.processConcurrently(ctx, items, opts)
+		return nil
 	}
 
-	// Get last sync time as nullable string
-	err = s.db.QueryRow("SELECT MAX(last_synced_at) FROM tasks WHERE last_synced_at IS NOT NULL").Scan(&lastSyncStr)
-	if err != nil {
-		return nil, err
+	// Process each item
+	for _, item := range items {
+		if err := s.processSyncItem(ctx, item, opts); err != nil {
+			log.Printf("Failed to process sync item %d: %v", item.ID, err)
+		}
+		opts.progress.itemDone()
 	}
 
-	// Parse the time string or use epoch time
-	var lastSync time.Time
-	if lastSyncStr.Valid && lastSyncStr.String != "" {
-		if parsed, err := time.Parse("2006-01-02 15:04:05", lastSyncStr.String); err == nil {
-			lastSync = parsed
-		} else {
-			lastSync = time.Unix(0, 0)
+	return nil
+}
+
+// processConcurrently syncs items on up to SyncConcurrency workers. Items are
+// grouped by task and each group runs on one worker in queue order, so two
+// operations for the same task never overlap or reorder.
+func (s *SyncService) processConcurrently(ctx context.Context, items []*models.SyncQueueItem, opts SyncOptions) {
+	var order []string
+	groups := make(map[string][]*models.SyncQueueItem)
+	for _, item := range items {
+		if _, ok := groups[item.TaskID]; !ok {
+			order = append(order, item.TaskID)
 		}
-	} else {
-		lastSync = time.Unix(0, 0)
+		groups[item.TaskID] = append(groups[item.TaskID], item)
 	}
 
-	return &SyncStatus{
-		PendingCount: pendingCount,
-		ErrorCount:   errorCount,
-		LastSync:     lastSync,
-		InProgress:   false,
-	}, nil
+	work := make(chan []*models.SyncQueueItem)
+	var wg sync.WaitGroup
+	workers := s.config.SyncConcurrency
+	if workers > len(order) {
+		workers = len(order)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range work {
+				for _, item := range group {
+					if err := s.processSyncItem(ctx, item, opts); err != nil {
+						log.Printf("Failed to process sync item %d: %v", item.ID, err)
+					}
+					opts.progress.itemDone()
+				}
+			}
+		}()
+	}
+
+	for _, taskID := range order {
+		work <- groups[taskID]
+	}
+	close(work)
+	wg.Wait()
 }
 
-func (s *SyncService) ResolveConflicts() error {
-	// Implementation of last-write-wins conflict resolution
-	log.Println("Conflict resolution completed using last-write-wins strategy")
-	return nil
+// recordRun stores the summary of a finished run. An item counts as succeeded
+// once it has left the queue; anything still queued failed on this pass.
+func (s *SyncService) recordRun(startedAt time.Time, items []*models.SyncQueueItem, opts SyncOptions) (models.SyncRunSummary, error) {
+	summary := models.SyncRunSummary{
+		Event:      models.SyncRunCompleted,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Processed:  len(items),
+	}
+
+	// Items still queued failed; those out of retries were dead-lettered. The
+	// batch is looked up in one query rather than one per item.
+	if len(items) > 0 {
+		ops := make(map[int]models.OperationType, len(items))
+		args := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			ops[item.ID] = item.OperationType
+			args = append(args, item.ID)
+		}
+
+		rows, err := s.db.QueryContext(s.context(),
+			"SELECT id, retry_count FROM sync_queue WHERE id IN (?"+strings.Repeat(", ?", len(args)-1)+")", args...)
+		if err != nil {
+			return summary, fmt.Errorf("failed to check sync queue items: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id, retryCount int
+			if err := rows.Scan(&id, &retryCount); err != nil {
+				return summary, fmt.Errorf("failed to scan sync queue item: %w", err)
+			}
+			summary.Failed++
+			if retryCount >= s.maxRetriesFor(ops[id], opts.MaxRetries) {
+				summary.DeadLettered++
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return summary, fmt.Errorf("failed to check sync queue items: %w", err)
+		}
+		rows.Close()
+	}
+	summary.Succeeded = summary.Processed - summary.Failed
+
+	_, err := s.db.ExecContext(s.context(), `
+        INSERT INTO sync_runs (started_at, finished_at, processed, succeeded, failed)
+        VALUES (?, ?, ?, ?, ?)
+    `, summary.StartedAt, summary.FinishedAt, summary.Processed, summary.Succeeded, summary.Failed)
+	if err != nil {
+		return summary, fmt.Errorf("failed to insert sync run: %w", err)
+	}
+	return summary, nil
 }
 
-func (s *SyncService) GetSyncQueueContents() ([]*models.SyncQueueItem, error) {
+// GetSyncRuns returns the most recent sync runs, newest first.
+func (s *SyncService) GetSyncRuns(limit int) ([]*models.SyncRun, error) {
 	query := `
-        SELECT id, task_id, operation_type, task_data, retry_count, created_at, last_attempt, error_message
-        FROM sync_queue
-        ORDER BY created_at ASC
+        SELECT id, started_at, finished_at, processed, succeeded, failed
+        FROM sync_runs
+        ORDER BY started_at DESC, id DESC
+        LIMIT ?
     `
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(s.context(), query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []*models.SyncRun{}
+	for rows.Next() {
+		run := &models.SyncRun{}
+		err := rows.Scan(&run.ID, &run.StartedAt, &run.FinishedAt, &run.Processed, &run.Succeeded, &run.Failed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sync run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// BatchSyncToServer pushes the items in a single request and applies each
+// per-item result. It returns syncclient.ErrBatchUnsupported untouched so the
+// caller can fall back to per-item sync.
+func (s *SyncService) BatchSyncToServer(ctx context.Context, items []*models.SyncQueueItem, opts SyncOptions) error {
+	client, ok := s.client.(BatchSyncClient)
+	if !ok {
+		return syncclient.ErrBatchUnsupported
+	}
+
+	// The server's reply is compared with what was pushed, which the
+	// SyncTransform may have changed from the local copy
+	tasks := make(map[string]*models.Task, len(items))
+	pushed := make(map[string]*models.Task, len(items))
+	var batch []syncclient.BatchItem
+	for _, item := range items {
+		task, err := item.GetTaskData()
+		if err != nil {
+			if err := s.deadLetter(item, err, opts); err != nil {
+				log.Printf("Failed to dead-letter sync item %d: %v", item.ID, err)
+			}
+			continue
+		}
+		opType, err := s.effectiveOperation(item, task)
+		if err != nil {
+			log.Printf("Failed to prepare sync item %d: %v", item.ID, err)
+			continue
+		}
+		outgoing, err := s.outgoingTask(task)
+		if err != nil {
+			if err := s.handleSyncError(item, err, opts); err != nil {
+				log.Printf("Failed to record sync error for item %d: %v", item.ID, err)
+			}
+			continue
+		}
+		id := strconv.Itoa(item.ID)
+		tasks[id] = task
+		pushed[id] = outgoing
+		batch = append(batch, syncclient.BatchItem{
+			ID:         id,
+			TaskID:     item.TaskID,
+			Operation:  opType,
+			Data:       outgoing,
+			CreatedAt:  item.CreatedAt,
+			RetryCount: item.RetryCount,
+		})
+	}
+
+	// The batch is a single request, so it gets a single item's timeout
+	batchCtx, cancel := s.withItemTimeout(ctx)
+	results, err := client.BatchSync(batchCtx, batch)
+	cancel()
+	if errors.Is(err, syncclient.ErrBatchUnsupported) {
+		return err
+	}
+	if err != nil {
+		// The whole request failed, so every item in it counts as a failed
+		// attempt. Items left out of it were already dealt with above.
+		for _, item := range items {
+			if _, ok := tasks[strconv.Itoa(item.ID)]; ok {
+				if err := s.handleSyncError(item, err, opts); err != nil {
+					log.Printf("Failed to record sync error for item %d: %v", item.ID, err)
+				}
+			}
+			opts.progress.itemDone()
+		}
+		return nil
+	}
+
+	byID := make(map[string]syncclient.BatchResult, len(results))
+	for _, result := range results {
+		byID[result.ID] = result
+	}
+
+	for _, item := range items {
+		// Items left out of the request were already dealt with above
+		id := strconv.Itoa(item.ID)
+		if task, ok := tasks[id]; ok {
+			if err := s.applyBatchResult(item, task, pushed[id], byID[id], opts); err != nil {
+				log.Printf("Failed to process sync item %d: %v", item.ID, err)
+			}
+		}
+		opts.progress.itemDone()
+	}
+
+	return nil
+}
+
+func (s *SyncService) applyBatchResult(item *models.SyncQueueItem, task, pushed *models.Task, result syncclient.BatchResult, opts SyncOptions) error {
+	remote := result.ResolvedData
+	if remote != nil {
+		if err := remote.Validate(); err != nil {
+			return s.deadLetter(item, fmt.Errorf("%w: %v", syncclient.ErrInvalidPayload, err), opts)
+		}
+		if result.ServerID != "" {
+			remote.ServerID = &result.ServerID
+		}
+	}
+
+	switch result.Status {
+	case syncclient.BatchStatusSuccess:
+		if remote == nil && result.ServerID != "" {
+			remote = &models.Task{ServerID: &result.ServerID}
+		}
+		if remoteIsNewer(pushed, remote) {
+			return s.handleConflict(item, task, remote, opts)
+		}
+		return s.markAsSynced(item, task, remote)
+	case syncclient.BatchStatusConflict:
+		if remote != nil {
+			return s.handleConflict(item, task, remote, opts)
+		}
+		return s.handleSyncError(item, syncclient.ErrConflict, opts)
+	case syncclient.BatchStatusError:
+		message := result.Error
+		if message == "" {
+			message = "sync server reported an error"
+		}
+		return s.handleSyncError(item, errors.New(message), opts)
+	default:
+		return s.handleSyncError(item, fmt.Errorf("no result returned for sync item"), opts)
+	}
+}
+
+// withItemTimeout bounds one server call by the configured SyncItemTimeout.
+func (s *SyncService) withItemTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.config.SyncItemTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.config.SyncItemTimeout)
+}
+
+func (s *SyncService) processSyncItem(ctx context.Context, item *models.SyncQueueItem, opts SyncOptions) error {
+	ctx, span := tracing.Start(ctx, "SyncService.processSyncItem", tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("sync.queue_id", strconv.Itoa(item.ID))
+	span.SetAttribute("sync.operation", string(item.OperationType))
+	span.SetAttribute("task.id", item.TaskID)
+
+	task, err := item.GetTaskData()
+	if err != nil {
+		span.RecordError(err)
+		s.resultMu.Lock()
+		defer s.resultMu.Unlock()
+		return s.deadLetter(item, err, opts)
+	}
+
+	s.resultMu.Lock()
+	opType, err := s.effectiveOperation(item, task)
+	if err != nil {
+		err = s.handleSyncError(item, err, opts)
+		s.resultMu.Unlock()
+		return err
+	}
+	s.resultMu.Unlock()
+
+	outgoing, err := s.outgoingTask(task)
+	if err != nil {
+		span.RecordError(err)
+		s.resultMu.Lock()
+		defer s.resultMu.Unlock()
+		return s.handleSyncError(item, err, opts)
+	}
+
+	// A slow server is abandoned after the timeout and the item retried later
+	itemCtx, cancel := s.withItemTimeout(ctx)
+	result, err := s.syncToServer(itemCtx, opType, outgoing)
+	cancel()
+
+	span.RecordError(err)
+
+	s.resultMu.Lock()
+	defer s.resultMu.Unlock()
+	if errors.Is(err, syncclient.ErrInvalidPayload) {
+		return s.deadLetter(item, err, opts)
+	}
+	if err != nil {
+		return s.handleSyncError(item, err, opts)
+	}
+	if result.Conflict {
+		return s.handleConflict(item, task, result.RemoteTask, opts)
+	}
+
+	// Mark as synced and remove from queue
+	return s.markAsSynced(item, task, result.RemoteTask)
+}
+
+// effectiveOperation turns a queued create into an update when the task already
+// has a server_id, so replaying a create whose acknowledgement was lost (for
+// example after a crash) cannot duplicate the task on the server.
+func (s *SyncService) effectiveOperation(item *models.SyncQueueItem, task *models.Task) (models.OperationType, error) {
+	if item.OperationType != models.OperationTypeCreate {
+		return item.OperationType, nil
+	}
+
+	var serverID sql.NullString
+	err := s.db.QueryRowContext(s.context(), `SELECT server_id FROM tasks WHERE id = ?`, item.TaskID).Scan(&serverID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return item.OperationType, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up server id: %w", err)
+	}
+	if !serverID.Valid || serverID.String == "" {
+		return item.OperationType, nil
+	}
+
+	task.ServerID = &serverID.String
+	return models.OperationTypeUpdate, nil
+}
+
+// syncResult is the outcome of pushing one operation to the server.
+type syncResult struct {
+	// RemoteTask is the server's copy of the task, when it sent one back
+	RemoteTask *models.Task
+	// Conflict is set when the server reported a conflict or sent back a newer
+	// copy that differs from the one pushed
+	Conflict bool
+}
+
+// syncToServer pushes one operation and reports what the server sent back.
+func (s *SyncService) syncToServer(ctx context.Context, opType models.OperationType, task *models.Task) (syncResult, error) {
+	var remote *models.Task
+	var err error
+	switch opType {
+	case models.OperationTypeCreate:
+		remote, err = s.client.CreateTask(ctx, task)
+	case models.OperationTypeUpdate:
+		remote, err = s.client.UpdateTask(ctx, task)
+	case models.OperationTypeDelete:
+		err = s.client.DeleteTask(ctx, task)
+	case models.OperationTypeRestore:
+		if client, ok := s.client.(LifecycleSyncClient); ok {
+			remote, err = client.RestoreTask(ctx, task)
+		} else {
+			remote, err = s.client.UpdateTask(ctx, task)
+		}
+	case models.OperationTypeArchive:
+		if client, ok := s.client.(LifecycleSyncClient); ok {
+			remote, err = client.ArchiveTask(ctx, task)
+		} else {
+			remote, err = s.client.UpdateTask(ctx, task)
+		}
+	default:
+		err = fmt.Errorf("unknown operation type %s", opType)
+	}
+
+	if errors.Is(err, syncclient.ErrConflict) && remote != nil {
+		return syncResult{RemoteTask: remote, Conflict: true}, nil
+	}
+	if err != nil {
+		return syncResult{}, err
+	}
+	return syncResult{RemoteTask: remote, Conflict: remoteIsNewer(task, remote)}, nil
+}
+
+// remoteIsNewer reports whether the server's copy was changed after the local
+// one and holds different content. A newer copy with the same content is just
+// the server stamping its own write, not a conflict.
+func remoteIsNewer(local, remote *models.Task) bool {
+	if remote == nil || !remote.UpdatedAt.After(local.UpdatedAt) {
+		return false
+	}
+	return remote.Title != local.Title ||
+		!equalStringPtr(remote.Description, local.Description) ||
+		remote.Completed != local.Completed ||
+		remote.Priority != local.Priority ||
+		remote.IsDeleted != local.IsDeleted ||
+		remote.Archived != local.Archived
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// handleConflict resolves a server-reported conflict and drops the queue item,
+// since resolution either applies the server copy or queues a fresh push.
+func (s *SyncService) handleConflict(item *models.SyncQueueItem, local, remote *models.Task, opts SyncOptions) error {
+	conflictMsg := syncclient.ErrConflict.Error()
+	if err := recordAttempt(s.db, item, false, &conflictMsg); err != nil {
+		log.Printf("Failed to record sync attempt: %v", err)
+	}
+
+	// The item leaves the queue in the same transaction that may re-queue the
+	// local copy, which has the same content hash and would otherwise be merged
+	// into the item and deleted with it
+	if _, err := s.resolveItemConflict(item, local, remote); err != nil {
+		return s.handleSyncError(item, err, opts)
+	}
+	return nil
+}
+
+// handleSyncError records a failed attempt and schedules the retry. An item
+// older than QueueItemTTL is dead-lettered as expired instead.
+func (s *SyncService) handleSyncError(item *models.SyncQueueItem, syncErr error, opts SyncOptions) error {
+	// An item this old is most likely stale, so it isn't worth retrying
+	if s.config.QueueItemTTL > 0 && time.Since(item.CreatedAt) > s.config.QueueItemTTL {
+		return s.deadLetter(item, ErrQueueItemExpired, opts)
+	}
+
+	errorMsg := "unknown error"
+	if syncErr != nil {
+		errorMsg = syncErr.Error()
+	}
+
+	item.IncrementRetry(errorMsg)
+
+	// The strategy may give up before MAX_RETRIES does
+	if s.retryStrategy.ShouldGiveUp(item.RetryCount) {
+		return s.deadLetter(item, fmt.Errorf("retry strategy gave up after %d attempts: %s", item.RetryCount, errorMsg), opts)
+	}
+
+	// A Retry-After from the server is a floor on our own backoff
+	nextAttempt := s.nextAttempt(item.RetryCount, *item.LastAttempt)
+	item.ServerRetryAfter = nil
+	if hint, ok := syncclient.RetryAfter(syncErr); ok {
+		item.ServerRetryAfter = &hint
+		if floor := item.LastAttempt.Add(hint); floor.After(nextAttempt) {
+			nextAttempt = floor
+		}
+	}
+	item.NextAttemptAt = &nextAttempt
+
+	query := `
+        UPDATE sync_queue 
+        SET retry_count = ?, last_attempt = ?, error_message = ?, next_attempt_at = ?,
+            server_retry_after = ?
+        WHERE id = ?
+    `
+
+	_, err := s.db.ExecContext(s.context(), query, item.RetryCount, item.LastAttempt, item.ErrorMessage, item.NextAttemptAt,
+		item.ServerRetryAfter, item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update sync queue item: %w", err)
+	}
+
+	if err := recordAttempt(s.db, item, false, &errorMsg); err != nil {
+		log.Printf("Failed to record sync attempt: %v", err)
+	}
+
+	// If max retries reached, mark task as error
+	if item.RetryCount >= s.maxRetriesFor(item.OperationType, opts.MaxRetries) {
+		if err := s.markTaskAsError(item.TaskID); err != nil {
+			log.Printf("Failed to mark task as error: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// deadLetter gives up on an item whose payload, or the server's reply, is
+// malformed, or that the retry strategy won't retry again. It goes straight to
+// the dead-letter set with the reason kept in error_message, and nothing from it
+// is stored.
+func (s *SyncService) deadLetter(item *models.SyncQueueItem, reason error, opts SyncOptions) error {
+	errorMsg := reason.Error()
+	log.Printf("Dead-lettering sync item %d: %s", item.ID, errorMsg)
+
+	// Count it as exhausted against both this run's limit and the configured one
+	retries := s.maxRetriesFor(item.OperationType, opts.MaxRetries)
+	if configured := s.maxRetriesFor(item.OperationType, s.config.MaxRetries); configured > retries {
+		retries = configured
+	}
+	if item.RetryCount > retries {
+		retries = item.RetryCount
+	}
+
+	now := time.Now()
+	item.RetryCount = retries
+	item.LastAttempt = &now
+	item.ErrorMessage = &errorMsg
+	item.NextAttemptAt = nil
+
+	query := `
+        UPDATE sync_queue
+        SET retry_count = ?, last_attempt = ?, error_message = ?, next_attempt_at = NULL
+        WHERE id = ?
+    `
+
+	if _, err := s.db.ExecContext(s.context(), query, item.RetryCount, item.LastAttempt, item.ErrorMessage, item.ID); err != nil {
+		return fmt.Errorf("failed to update sync queue item: %w", err)
+	}
+
+	if err := recordAttempt(s.db, item, false, &errorMsg); err != nil {
+		log.Printf("Failed to record sync attempt: %v", err)
+	}
+
+	if err := s.markTaskAsError(item.TaskID); err != nil {
+		log.Printf("Failed to mark task as error: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SyncService) markAsSynced(item *models.SyncQueueItem, task, remote *models.Task) error {
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Update task sync status, keeping any known server_id when the server
+	// doesn't send one back
+	now := time.Now()
+	query := `
+        UPDATE tasks 
+        SET sync_status = 'synced', last_synced_at = ?, server_id = COALESCE(?, server_id, id)
+        WHERE id = ?
+    `
+
+	var serverID *string
+	if remote != nil && remote.ServerID != nil {
+		serverID = remote.ServerID
+	}
+	_, err = tx.Exec(query, now, serverID, task.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update task sync status: %w", err)
+	}
+
+	// Remove from sync queue
+	deleteQuery := `DELETE FROM sync_queue WHERE id = ?`
+	_, err = tx.Exec(deleteQuery, item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to remove from sync queue: %w", err)
+	}
+
+	if err := recordAttempt(tx, item, true, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// recordAttempt appends an entry to the task's sync history. Nothing is recorded
+// for a task that has been hard-deleted, since its history went with it.
+func recordAttempt(db execer, item *models.SyncQueueItem, success bool, errorMsg *string) error {
+	query := `
+        INSERT INTO sync_attempts (task_id, operation_type, success, error_message, attempted_at)
+        SELECT ?, ?, ?, ?, ?
+        WHERE EXISTS (SELECT 1 FROM tasks WHERE id = ?)
+    `
+
+	_, err := db.Exec(query, item.TaskID, item.OperationType, success, errorMsg, time.Now(), item.TaskID)
+	if err != nil {
+		return fmt.Errorf("failed to record sync attempt: %w", err)
+	}
+	return nil
+}
+
+func (s *SyncService) markTaskAsError(taskID string) error {
+	query := `UPDATE tasks SET sync_status = 'error' WHERE id = ?`
+	_, err := s.db.ExecContext(s.context(), query, taskID)
+	return err
+}
+
+// GetPendingCount returns the number of queue items that will still be retried.
+func (s *SyncService) GetPendingCount() (int, error) {
+	var count int
+	retryLimit, args := s.retryLimitSQL(s.config.MaxRetries)
+	owned, ownerArgs := s.ownerCondition("user_id = ?")
+	err := s.db.QueryRowContext(s.context(), "SELECT COUNT(*) FROM sync_queue WHERE retry_count < "+retryLimit+" AND "+owned,
+		append(args, ownerArgs...)...).Scan(&count)
+	return count, err
+}
+
+// GetSyncStatus summarises the queue and the tasks' sync state. A view made by
+// ForUser only counts the user's items and tasks; pausing and the sync lock are
+// shared by everyone.
+func (s *SyncService) GetSyncStatus() (*SyncStatus, error) {
+	var errorCount int
+	var lastSyncStr sql.NullString
+	owned, ownerArgs := s.ownerCondition("user_id = ?")
+
+	// Get pending count
+	pendingCount, err := s.GetPendingCount()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get error count
+	err = s.db.QueryRowContext(s.context(), "SELECT COUNT(*) FROM tasks WHERE sync_status = 'error' AND "+owned, ownerArgs...).Scan(&errorCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get last sync time as nullable string
+	err = s.db.QueryRowContext(s.context(), "SELECT MAX(last_synced_at) FROM tasks WHERE last_synced_at IS NOT NULL AND "+owned, ownerArgs...).Scan(&lastSyncStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the time string or use epoch time
+	lastSync := time.Unix(0, 0)
+	if parsed, ok := parseSQLiteTime(lastSyncStr); ok {
+		lastSync = parsed
+	}
+
+	// Age of the oldest item still eligible for retry
+	var oldestStr sql.NullString
+	retryLimit, limitArgs := s.retryLimitSQL(s.config.MaxRetries)
+	queueArgs := append(append([]interface{}{}, limitArgs...), ownerArgs...)
+	err = s.db.QueryRowContext(s.context(), "SELECT MIN(created_at) FROM sync_queue WHERE retry_count < "+retryLimit+" AND "+owned, queueArgs...).Scan(&oldestStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldestAge time.Duration
+	if oldest, ok := parseSQLiteTime(oldestStr); ok {
+		if age := time.Since(oldest); age > 0 {
+			oldestAge = age
+		}
+	}
+
+	// Items that have exhausted their retries
+	var deadLetterCount int
+	err = s.db.QueryRowContext(s.context(), "SELECT COUNT(*) FROM sync_queue WHERE retry_count >= "+retryLimit+" AND "+owned, queueArgs...).Scan(&deadLetterCount)
+	if err != nil {
+		return nil, err
+	}
+
+	paused, err := s.IsPaused()
+	if err != nil {
+		return nil, err
+	}
+
+	inProgress, err := s.syncLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyncStatus{
+		PendingCount:     pendingCount,
+		ErrorCount:       errorCount,
+		LastSync:         lastSync,
+		InProgress:       inProgress,
+		OldestPendingAge: oldestAge,
+		DeadLetterCount:  deadLetterCount,
+		Paused:           paused,
+	}, nil
+}
+
+// syncPausedSetting is the sync_settings key that records whether sync is paused.
+const syncPausedSetting = "paused"
+
+// Pause stops sync passes from pushing anything until Resume is called. The
+// queue is left as it is and keeps accepting changes. The flag is stored in the
+// database, so sync stays paused across restarts.
+func (s *SyncService) Pause() error {
+	return s.setPaused(true)
+}
+
+// Resume lets sync passes push the queue again after Pause.
+func (s *SyncService) Resume() error {
+	return s.setPaused(false)
+}
+
+func (s *SyncService) setPaused(paused bool) error {
+	_, err := s.db.ExecContext(s.context(), `
+        INSERT INTO sync_settings (key, value, updated_at) VALUES (?, ?, ?)
+        ON CONFLICT (key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+    `, syncPausedSetting, strconv.FormatBool(paused), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to update sync settings: %w", err)
+	}
+
+	if paused {
+		log.Printf("Sync paused")
+	} else {
+		log.Printf("Sync resumed")
+	}
+	return nil
+}
+
+// IsPaused reports whether sync is paused.
+func (s *SyncService) IsPaused() (bool, error) {
+	var value string
+	err := s.db.QueryRowContext(s.context(), `SELECT value FROM sync_settings WHERE key = ?`, syncPausedSetting).Scan(&value)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read sync settings: %w", err)
+	}
+	return value == "true", nil
+}
+
+// parseSQLiteTime parses a timestamp returned by an aggregate such as MIN or MAX,
+// which loses the column's declared type and comes back as plain text.
+func parseSQLiteTime(value sql.NullString) (time.Time, bool) {
+	if !value.Valid || value.String == "" {
+		return time.Time{}, false
+	}
+	parsed, err := database.ParseTimestamp(value.String)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+func (s *SyncService) ResolveConflicts() error {
+	log.Printf("Conflict resolution completed using %s strategy", s.conflictStrategy)
+	return nil
+}
+
+// ResolveConflict settles a disagreement between the local and remote copies of a
+// task using the configured strategy and records the decision in the conflict log.
+// A remote winner overwrites the local row; a local winner is queued to be pushed again.
+// A conflict the config holds for manual review changes neither copy: it is logged
+// as needing review and the task is marked as a sync error until it is resolved.
+func (s *SyncService) ResolveConflict(local, remote *models.Task) (*models.Task, error) {
+	return s.resolveItemConflict(nil, local, remote)
+}
+
+// resolveItemConflict is ResolveConflict for a conflict met while pushing item,
+// which it removes from the queue before the local copy is re-queued. A nil
+// item removes nothing.
+func (s *SyncService) resolveItemConflict(item *models.SyncQueueItem, local, remote *models.Task) (*models.Task, error) {
+	winner := s.resolveConflict(local, remote)
+	needsReview := s.needsReview(local, remote)
+
+	localData, err := json.Marshal(local)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode local task: %w", err)
+	}
+	remoteData, err := json.Marshal(remote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode remote task: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if item != nil {
+		if _, err := tx.Exec(`DELETE FROM sync_queue WHERE id = ?`, item.ID); err != nil {
+			return nil, fmt.Errorf("failed to remove from sync queue: %w", err)
+		}
+	}
+
+	resolved := local
+	switch {
+	case needsReview:
+		if _, err := tx.Exec(`UPDATE tasks SET sync_status = 'error' WHERE id = ?`, local.ID); err != nil {
+			return nil, fmt.Errorf("failed to update sync status: %w", err)
+		}
+	case winner == models.ConflictWinnerRemote:
+		resolved = remote
+		if err := s.applyRemoteTx(tx, local.ID, remote); err != nil {
+			return nil, err
+		}
+	default:
+		if _, err := s.AddToQueueTx(tx, local.ID, models.OperationTypeUpdate, local); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO sync_conflicts (task_id, local_data, remote_data, winner, resolved_at, needs_review)
+        VALUES (?, ?, ?, ?, ?, ?)
+    `, local.ID, string(localData), string(remoteData), winner, time.Now(), needsReview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record conflict: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if needsReview {
+		log.Printf("Conflict for task %s changes completed status, holding it for manual review", local.ID)
+		return local, nil
+	}
+	log.Printf("Resolved conflict for task %s in favour of %s copy", local.ID, winner)
+	return resolved, nil
+}
+
+// needsReview reports whether a conflict should wait for someone to pick the
+// winner. Only the fixed server_wins and client_wins strategies escalate, and
+// only when the copies disagree on whether the task is completed.
+func (s *SyncService) needsReview(local, remote *models.Task) bool {
+	if !s.config.ManualReviewOnStatusConflict {
+		return false
+	}
+	switch s.conflictStrategy {
+	case ConflictStrategyServerWins, ConflictStrategyClientWins:
+		return local.Completed != remote.Completed
+	default:
+		return false
+	}
+}
+
+// ResolveReviewedConflict settles a conflict that was held for manual review in
+// favour of winner. A remote winner overwrites the task with the logged server
+// copy; a local winner queues the task as it stands now to be pushed again. The
+// conflict log entry is updated with the decision.
+func (s *SyncService) ResolveReviewedConflict(id int, winner models.ConflictWinner) (*models.SyncConflict, error) {
+	if !winner.IsValid() {
+		return nil, ErrInvalidConflictWinner
+	}
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	owned, ownerArgs := s.ownerCondition(taskOwnedBy)
+	conflict := &models.SyncConflict{}
+	var localData, remoteData string
+	err = tx.QueryRow(`
+        SELECT id, task_id, local_data, remote_data, winner, resolved_at, needs_review
+        FROM sync_conflicts
+        WHERE id = ? AND `+owned, append([]interface{}{id}, ownerArgs...)...).Scan(&conflict.ID, &conflict.TaskID, &localData, &remoteData,
+		&conflict.Winner, &conflict.ResolvedAt, &conflict.NeedsReview)
+	if err == sql.ErrNoRows {
+		return nil, ErrConflictNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conflict: %w", err)
+	}
+	if !conflict.NeedsReview {
+		return nil, ErrConflictNotPending
+	}
+	conflict.LocalData = json.RawMessage(localData)
+	conflict.RemoteData = json.RawMessage(remoteData)
+
+	// The task may have been edited, but not deleted, while the conflict waited
+	local, err := scanTask(tx.QueryRow(`SELECT `+taskColumns+` FROM tasks WHERE id = ?`, conflict.TaskID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if winner == models.ConflictWinnerRemote {
+		var remote models.Task
+		if err := json.Unmarshal(conflict.RemoteData, &remote); err != nil {
+			return nil, fmt.Errorf("failed to decode remote task: %w", err)
+		}
+		if err := s.applyRemoteTx(tx, local.ID, &remote); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := tx.Exec(`UPDATE tasks SET sync_status = 'pending' WHERE id = ?`, local.ID); err != nil {
+			return nil, fmt.Errorf("failed to update sync status: %w", err)
+		}
+		if _, err := s.AddToQueueTx(tx, local.ID, models.OperationTypeUpdate, local); err != nil {
+			return nil, err
+		}
+	}
+
+	conflict.Winner = winner
+	conflict.ResolvedAt = time.Now()
+	conflict.NeedsReview = false
+	_, err = tx.Exec(`
+        UPDATE sync_conflicts SET winner = ?, resolved_at = ?, needs_review = 0 WHERE id = ?
+    `, conflict.Winner, conflict.ResolvedAt, conflict.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record conflict: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("Resolved reviewed conflict %d for task %s in favour of %s copy", conflict.ID, conflict.TaskID, winner)
+	return conflict, nil
+}
+
+// resolveConflict picks which copy of the task survives.
+func (s *SyncService) resolveConflict(local, remote *models.Task) models.ConflictWinner {
+	switch s.conflictStrategy {
+	case ConflictStrategyServerWins:
+		return models.ConflictWinnerRemote
+	case ConflictStrategyClientWins:
+		return models.ConflictWinnerLocal
+	default:
+		if remote.UpdatedAt.After(local.UpdatedAt) {
+			return models.ConflictWinnerRemote
+		}
+		return models.ConflictWinnerLocal
+	}
+}
+
+// applyRemoteTx overwrites the local task with the server's copy and marks it synced.
+func (s *SyncService) applyRemoteTx(tx *sql.Tx, taskID string, remote *models.Task) error {
+	// A server that doesn't send metadata leaves the local copy alone
+	var metadata interface{}
+	if remote.Metadata != nil {
+		encoded, err := metadataJSON(remote.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = encoded
+	}
+
+	query := `
+        UPDATE tasks
+        SET title = ?, description = ?, completed = ?, updated_at = ?, is_deleted = ?,
+            deleted_at = CASE WHEN ? THEN COALESCE(?, deleted_at, ?) ELSE NULL END,
+            archived = ?, sync_status = 'synced', server_id = ?, last_synced_at = ?,
+            updated_by = COALESCE(NULLIF(?, ''), updated_by), metadata = COALESCE(?, metadata)
+        WHERE id = ?
+    `
+
+	// A server that doesn't send deleted_at leaves a known one alone
+	_, err := tx.Exec(query, remote.Title, remote.Description, remote.Completed,
+		remote.UpdatedAt, remote.IsDeleted, remote.IsDeleted, remote.DeletedAt, remote.UpdatedAt,
+		remote.Archived, remote.ServerID, time.Now(), remote.UpdatedBy, metadata, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to apply remote task: %w", err)
+	}
+
+	return setTaskTagsTx(tx, taskID, remote.Tags)
+}
+
+// GetConflicts returns a page of the conflict log, newest first, and the total count.
+func (s *SyncService) GetConflicts(limit, offset int) ([]*models.SyncConflict, int, error) {
+	owned, args := s.ownerCondition(taskOwnedBy)
+
+	var total int
+	if err := s.db.QueryRowContext(s.context(), "SELECT COUNT(*) FROM sync_conflicts WHERE "+owned, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count conflicts: %w", err)
+	}
+
+	query := `
+        SELECT id, task_id, local_data, remote_data, winner, resolved_at, needs_review
+        FROM sync_conflicts
+        WHERE ` + owned + `
+        ORDER BY resolved_at DESC, id DESC
+        LIMIT ? OFFSET ?
+    `
+
+	rows, err := s.db.QueryContext(s.context(), query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	conflicts := []*models.SyncConflict{}
+	for rows.Next() {
+		conflict := &models.SyncConflict{}
+		var localData, remoteData string
+		err := rows.Scan(&conflict.ID, &conflict.TaskID, &localData, &remoteData,
+			&conflict.Winner, &conflict.ResolvedAt, &conflict.NeedsReview)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan conflict: %w", err)
+		}
+		conflict.LocalData = json.RawMessage(localData)
+		conflict.RemoteData = json.RawMessage(remoteData)
+		conflicts = append(conflicts, conflict)
+	}
+
+	return conflicts, total, rows.Err()
+}
+
+// GetTasksBySyncStatus returns a page of tasks in the given sync status, most
+// recently updated first, and how many tasks are in that status. Deleted tasks
+// are included, since their deletion still has to sync.
+func (s *SyncService) GetTasksBySyncStatus(status models.SyncStatus, limit, offset int) ([]*models.Task, int, error) {
+	owned, ownerArgs := s.ownerCondition("user_id = ?")
+	args := append([]interface{}{status}, ownerArgs...)
+
+	var total int
+	if err := s.db.QueryRowContext(s.context(), "SELECT COUNT(*) FROM tasks WHERE sync_status = ? AND "+owned, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	query := `
+        SELECT ` + taskColumns + `
+        FROM tasks
+        WHERE sync_status = ? AND ` + owned + `
+        ORDER BY updated_at DESC, id DESC
+        LIMIT ? OFFSET ?
+    `
+
+	rows, err := s.db.QueryContext(s.context(), query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []*models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, total, rows.Err()
+}
+
+// GetDeadLetters returns a page of queue items that exhausted their retries,
+// most recently attempted first, and how many match in total. Each item carries
+// its last error and retry count. A non-empty taskID limits the listing to that task.
+func (s *SyncService) GetDeadLetters(taskID string, limit, offset int) ([]*models.SyncQueueItem, int, error) {
+	retryLimit, args := s.retryLimitSQL(s.config.MaxRetries)
+	owned, ownerArgs := s.ownerCondition("user_id = ?")
+	conditions := []string{"retry_count >= " + retryLimit, owned}
+	args = append(args, ownerArgs...)
+	if taskID != "" {
+		conditions = append(conditions, "task_id = ?")
+		args = append(args, taskID)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := s.db.QueryRowContext(s.context(), "SELECT COUNT(*) FROM sync_queue WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+
+	query := `
+        SELECT ` + queueColumns + `
+        FROM sync_queue
+        WHERE ` + where + `
+        ORDER BY last_attempt DESC, id DESC
+        LIMIT ? OFFSET ?
+    `
+
+	rows, err := s.db.QueryContext(s.context(), query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*models.SyncQueueItem{}
+	for rows.Next() {
+		item, err := scanQueueItem(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan dead letter: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, total, rows.Err()
+}
+
+// ResetQueue empties the sync queue, dead-lettered items included, in one
+// transaction. With resetErrored, tasks whose sync failed are marked pending
+// again and each is queued afresh as it stands now, so the next pass pushes it.
+// It is an operator action that covers every user's queue.
+func (s *SyncService) ResetQueue(resetErrored bool) error {
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	// The queue size changes however this ends
+	defer s.invalidateQueueSize()
+
+	if _, err := tx.Exec(`DELETE FROM sync_queue`); err != nil {
+		return fmt.Errorf("failed to clear sync queue: %w", err)
+	}
+	s.invalidateQueueSize()
+
+	if resetErrored {
+		if err := s.requeueErroredTx(tx); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// requeueErroredTx marks every task whose sync failed pending and queues the
+// operation that brings the server up to date with it: a delete for a deleted
+// task, a create for one the server never acknowledged, and otherwise an update.
+func (s *SyncService) requeueErroredTx(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT `+taskColumns+` FROM tasks WHERE sync_status = ?`, models.SyncStatusError)
+	if err != nil {
+		return fmt.Errorf("failed to query errored tasks: %w", err)
+	}
+	var tasks []*models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read errored tasks: %w", err)
+	}
+
+	for _, task := range tasks {
+		opType := models.OperationTypeUpdate
+		switch {
+		case task.IsDeleted:
+			opType = models.OperationTypeDelete
+		case task.ServerID == nil:
+			opType = models.OperationTypeCreate
+		}
+
+		task.SyncStatus = models.SyncStatusPending
+		if _, err := tx.Exec(`UPDATE tasks SET sync_status = ? WHERE id = ?`, task.SyncStatus, task.ID); err != nil {
+			return fmt.Errorf("failed to reset errored task: %w", err)
+		}
+		if _, err := s.AddToQueueTx(tx, task.ID, opType, task); err != nil {
+			return fmt.Errorf("failed to requeue task %s: %w", task.ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *SyncService) GetSyncQueueContents() ([]*models.SyncQueueItem, error) {
+	return s.ListSyncQueue(&models.SyncQueueFilter{})
+}
+
+// GetQueueItem returns the queue item with the given ID along with its decoded
+// payload. A payload that can't be decoded is reported in DecodeError rather
+// than failing the lookup, since that is often why the item is stuck. A scoped
+// service reports another user's item as not found.
+func (s *SyncService) GetQueueItem(id int) (*models.SyncQueueItemDetail, error) {
+	owned, ownerArgs := s.ownerCondition("user_id = ?")
+	row := s.db.QueryRowContext(s.context(), `SELECT `+queueColumns+` FROM sync_queue WHERE id = ? AND `+owned,
+		append([]interface{}{id}, ownerArgs...)...)
+	item, err := scanQueueItem(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQueueItemNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync queue item: %w", err)
+	}
+
+	detail := &models.SyncQueueItemDetail{
+		SyncQueueItem: item,
+		DeadLettered:  item.RetryCount >= s.maxRetriesFor(item.OperationType, s.config.MaxRetries),
+	}
+	if task, err := item.GetTaskData(); err != nil {
+		detail.DecodeError = err.Error()
+	} else {
+		detail.TaskData = task
+	}
+	return detail, nil
+}
+
+// ListSyncQueue returns the queue items matching the filter, oldest first.
+func (s *SyncService) ListSyncQueue(filter *models.SyncQueueFilter) ([]*models.SyncQueueItem, error) {
+	owned, args := s.ownerCondition("user_id = ?")
+	conditions := []string{owned}
+
+	if filter.OperationType != "" {
+		conditions = append(conditions, "operation_type = ?")
+		args = append(args, filter.OperationType)
+	}
+	if filter.MinRetries > 0 {
+		conditions = append(conditions, "retry_count >= ?")
+		args = append(args, filter.MinRetries)
+	}
+
+	query := `
+        SELECT ` + queueColumns + `
+        FROM sync_queue
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY created_at ASC, id ASC
+    `
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(s.context(), query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query sync queue: %w", err)
 	}
@@ -264,12 +1987,7 @@ func (s *SyncService) GetSyncQueueContents() ([]*models.SyncQueueItem, error) {
 
 	var items []*models.SyncQueueItem
 	for rows.Next() {
-		item := &models.SyncQueueItem{}
-		err := rows.Scan(
-			&item.ID, &item.TaskID, &item.OperationType,
-			&item.TaskData, &item.RetryCount, &item.CreatedAt,
-			&item.LastAttempt, &item.ErrorMessage,
-		)
+		item, err := scanQueueItem(rows)
 		if err != nil {
 			log.Printf("Failed to scan sync queue item: %v", err)
 			continue
@@ -277,5 +1995,5 @@ func (s *SyncService) GetSyncQueueContents() ([]*models.SyncQueueItem, error) {
 		items = append(items, item)
 	}
 
-	return items, nil
+	return items, rows.Err()
 }
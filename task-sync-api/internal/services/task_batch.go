@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+// BatchCreateResult is the outcome of one request in a batch create: the created
+// task, or the error that kept it from being created.
+type BatchCreateResult struct {
+	Task *models.Task
+	Err  error
+}
+
+// BatchItemError is returned by an atomic batch create when one of its requests
+// fails. It wraps that request's error, so errors.Is still sees ErrDuplicateTitle
+// and the like.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("tasks[%d]: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// CreateTasks creates a task for each validated request in one transaction and
+// returns one result per request, in order.
+//
+// By default the batch is atomic: the first request that fails rolls back the
+// whole batch and is returned as a *BatchItemError. With partial set, each
+// request runs under its own savepoint, so a failing one is undone and reported
+// in its result while the others are still committed.
+func (s *TaskService) CreateTasks(reqs []*models.CreateTaskRequest, partial bool) ([]BatchCreateResult, error) {
+	s, span := s.startSpan("CreateTasks")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]BatchCreateResult, len(reqs))
+	rolledBack := false
+	for i, req := range reqs {
+		task := s.newTask(req)
+
+		if partial {
+			if _, err := tx.Exec(`SAVEPOINT batch_item`); err != nil {
+				return nil, fmt.Errorf("failed to create savepoint: %w", err)
+			}
+		}
+
+		err := s.checkTitleTx(tx, task)
+		if err == nil {
+			_, err = s.insertTaskTx(tx, task)
+		}
+
+		switch {
+		case err != nil && !partial:
+			return nil, &BatchItemError{Index: i, Err: err}
+		case err != nil:
+			if _, rbErr := tx.Exec(`ROLLBACK TO batch_item`); rbErr != nil {
+				return nil, fmt.Errorf("failed to roll back to savepoint: %w", rbErr)
+			}
+			rolledBack = true
+			results[i].Err = err
+		default:
+			results[i].Task = task
+		}
+
+		if partial {
+			if _, err := tx.Exec(`RELEASE batch_item`); err != nil {
+				return nil, fmt.Errorf("failed to release savepoint: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// A rolled back insert may have counted against the queue size
+	if rolledBack {
+		s.syncService.invalidateQueueSize()
+	}
+	for _, result := range results {
+		if result.Task != nil {
+			s.notify(models.TaskEventCreated, result.Task)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+// TaskChanges is the set of tasks changed since a point in time.
+type TaskChanges struct {
+	Tasks []*models.Task
+	// ServerTime is when the changes were read. Passing it as the next since
+	// picks up everything changed afterwards without relying on the caller's clock.
+	ServerTime time.Time
+}
+
+// GetChangedSince returns the user's tasks updated after since, oldest change
+// first. Soft-deleted and archived tasks are included so clients can drop or
+// hide them; hard-deleted tasks no longer exist and can't be reported. A task
+// changed while the query runs may be returned again by the next call.
+func (s *TaskService) GetChangedSince(since time.Time) (*TaskChanges, error) {
+	s, span := s.startSpan("GetChangedSince")
+	defer span.End()
+
+	// Read the clock first so a change racing the query is never skipped
+	serverTime := time.Now()
+
+	query := `
+        SELECT ` + taskColumns + `
+        FROM tasks
+        WHERE user_id = ? AND updated_at > ?
+        ORDER BY updated_at ASC, id ASC
+    `
+
+	rows, err := s.db.QueryContext(s.context(), query, s.userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changed tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []*models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query changed tasks: %w", err)
+	}
+
+	return &TaskChanges{Tasks: tasks, ServerTime: serverTime}, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// AddComment records a comment on one of the caller's active tasks. Comments
+// are local and are not synced.
+func (s *TaskService) AddComment(taskID string, req *models.CreateCommentRequest) (*models.TaskComment, error) {
+	s, span := s.startSpan("AddComment")
+	defer span.End()
+
+	if _, err := getTask(s.context(), s.db, taskID, s.userID); err != nil {
+		return nil, err
+	}
+
+	comment := &models.TaskComment{
+		ID:        uuid.New().String(),
+		TaskID:    taskID,
+		Body:      req.Body,
+		Author:    req.Author,
+		CreatedAt: time.Now(),
+	}
+	if comment.Author == "" {
+		comment.Author = s.actor()
+	}
+
+	_, err := s.db.ExecContext(s.context(), `
+        INSERT INTO task_comments (id, task_id, body, author, created_at)
+        VALUES (?, ?, ?, ?, ?)
+    `, comment.ID, comment.TaskID, comment.Body, comment.Author, comment.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// GetComments returns the comments on one of the caller's active tasks, oldest
+// first.
+func (s *TaskService) GetComments(taskID string) ([]*models.TaskComment, error) {
+	s, span := s.startSpan("GetComments")
+	defer span.End()
+
+	if _, err := getTask(s.context(), s.db, taskID, s.userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(s.context(), `
+        SELECT id, task_id, body, author, created_at
+        FROM task_comments
+        WHERE task_id = ?
+        ORDER BY created_at ASC, rowid ASC
+    `, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []*models.TaskComment{}
+	for rows.Next() {
+		comment := &models.TaskComment{}
+		if err := rows.Scan(&comment.ID, &comment.TaskID, &comment.Body, &comment.Author, &comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+// ErrDependencyCycle is returned when a dependency would make a task depend,
+// directly or through other tasks, on itself.
+var ErrDependencyCycle = errors.New("dependency would create a cycle")
+
+// ErrDependencyNotFound is returned when removing a dependency that doesn't exist.
+var ErrDependencyNotFound = errors.New("dependency not found")
+
+// ErrIncompleteDependencies is returned when completing a task that still has
+// incomplete dependencies and the change wasn't forced.
+var ErrIncompleteDependencies = errors.New("task has incomplete dependencies")
+
+// AddDependency records that taskID is blocked by dependsOnID. Both tasks must
+// be the caller's active tasks. Adding an existing dependency is a no-op.
+// Dependencies are local and are not synced.
+func (s *TaskService) AddDependency(taskID, dependsOnID string) error {
+	s, span := s.startSpan("AddDependency")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range []string{taskID, dependsOnID} {
+		if _, err := getTask(s.context(), tx, id, s.userID); err != nil {
+			return err
+		}
+	}
+
+	// The new edge closes a cycle if taskID is already reachable from dependsOnID
+	var reachable int
+	err = tx.QueryRow(`
+        WITH RECURSIVE upstream(id) AS (
+            SELECT ?
+            UNION
+            SELECT d.depends_on_id FROM task_dependencies d
+            JOIN upstream ON d.task_id = upstream.id
+        )
+        SELECT COUNT(*) FROM upstream WHERE id = ?
+    `, dependsOnID, taskID).Scan(&reachable)
+	if err != nil {
+		return fmt.Errorf("failed to check for dependency cycle: %w", err)
+	}
+	if reachable > 0 {
+		return ErrDependencyCycle
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO task_dependencies (task_id, depends_on_id, created_at)
+        VALUES (?, ?, ?)
+        ON CONFLICT (task_id, depends_on_id) DO NOTHING
+    `, taskID, dependsOnID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to add dependency: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// RemoveDependency drops the dependency of taskID on dependsOnID.
+func (s *TaskService) RemoveDependency(taskID, dependsOnID string) error {
+	s, span := s.startSpan("RemoveDependency")
+	defer span.End()
+
+	if _, err := getTask(s.context(), s.db, taskID, s.userID); err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(s.context(), `DELETE FROM task_dependencies WHERE task_id = ? AND depends_on_id = ?`,
+		taskID, dependsOnID)
+	if err != nil {
+		return fmt.Errorf("failed to remove dependency: %w", err)
+	}
+
+	if removed, _ := result.RowsAffected(); removed == 0 {
+		return ErrDependencyNotFound
+	}
+	return nil
+}
+
+// GetDependencies returns the active tasks taskID depends on, oldest first.
+func (s *TaskService) GetDependencies(taskID string) ([]*models.Task, error) {
+	s, span := s.startSpan("GetDependencies")
+	defer span.End()
+
+	if _, err := getTask(s.context(), s.db, taskID, s.userID); err != nil {
+		return nil, err
+	}
+
+	query := `
+        SELECT ` + taskColumns + `
+        FROM tasks
+        WHERE id IN (SELECT depends_on_id FROM task_dependencies WHERE task_id = ?)
+          AND is_deleted = 0
+        ORDER BY created_at ASC, id ASC
+    `
+
+	rows, err := s.db.QueryContext(s.context(), query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := []*models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// checkDependenciesTx returns ErrIncompleteDependencies if any active task that
+// taskID depends on is not yet completed.
+func checkDependenciesTx(tx *sql.Tx, taskID string) error {
+	var incomplete int
+	err := tx.QueryRow(`
+        SELECT COUNT(*) FROM task_dependencies
+        JOIN tasks ON tasks.id = task_dependencies.depends_on_id
+        WHERE task_dependencies.task_id = ? AND tasks.completed = 0 AND tasks.is_deleted = 0
+    `, taskID).Scan(&incomplete)
+	if err != nil {
+		return fmt.Errorf("failed to check dependencies: %w", err)
+	}
+	if incomplete > 0 {
+		return ErrIncompleteDependencies
+	}
+	return nil
+}
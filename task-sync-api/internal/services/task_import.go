@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+// importBatchSize is how many lines are applied per import transaction.
+const importBatchSize = 100
+
+// maxImportLineSize bounds a single NDJSON line.
+const maxImportLineSize = 1 << 20
+
+// ImportLineError describes an import line that was skipped.
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportError lists the lines an import skipped. The remaining lines were
+// still applied.
+type ImportError struct {
+	Lines []ImportLineError
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("%d import lines were skipped", len(e.Lines))
+}
+
+// ImportTasks upserts tasks read from r as newline-delimited JSON, one full task
+// per line. A task whose id is new is inserted; an existing task is replaced
+// only when the imported copy has a later updated_at. Either way the change is
+// queued for sync. Lines that can't be applied are collected into an
+// *ImportError, returned alongside the counts, without stopping the import.
+func (s *TaskService) ImportTasks(r io.Reader) (imported, updated int, err error) {
+	s, span := s.startSpan("ImportTasks")
+	defer span.End()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+
+	var skipped []ImportLineError
+	var batch []importLine
+	lineNo := 0
+
+	flush := func() error {
+		ins, upd, lineErrs, err := s.importBatch(batch)
+		if err != nil {
+			return err
+		}
+		imported += ins
+		updated += upd
+		skipped = append(skipped, lineErrs...)
+		batch = batch[:0]
+		return nil
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		task, err := parseImportLine(line)
+		if err != nil {
+			skipped = append(skipped, ImportLineError{Line: lineNo, Error: err.Error()})
+			continue
+		}
+
+		batch = append(batch, importLine{number: lineNo, task: task})
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return imported, updated, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return imported, updated, fmt.Errorf("failed to read import: %w", err)
+	}
+	if err := flush(); err != nil {
+		return imported, updated, err
+	}
+
+	if len(skipped) > 0 {
+		return imported, updated, &ImportError{Lines: skipped}
+	}
+	return imported, updated, nil
+}
+
+type importLine struct {
+	number int
+	task   *models.Task
+}
+
+func parseImportLine(line string) (*models.Task, error) {
+	var task models.Task
+	if err := json.Unmarshal([]byte(line), &task); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if task.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+
+	// Imported tasks go through the same checks as created ones
+	req := &models.CreateTaskRequest{Title: task.Title, Tags: task.Tags, RecurrenceRule: task.RecurrenceRule}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	task.Title = req.Title
+	task.Tags = req.Tags
+
+	if task.UpdatedAt.IsZero() {
+		return nil, fmt.Errorf("updated_at is required")
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = task.UpdatedAt
+	}
+
+	// Exports from before deleted_at existed only carry the flag
+	if !task.IsDeleted {
+		task.DeletedAt = nil
+	} else if task.DeletedAt == nil {
+		task.DeletedAt = &task.UpdatedAt
+	}
+	return &task, nil
+}
+
+// importBatch applies one batch of parsed lines in a single transaction.
+func (s *TaskService) importBatch(lines []importLine) (imported, updated int, skipped []ImportLineError, err error) {
+	if len(lines) == 0 {
+		return 0, 0, nil, nil
+	}
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, line := range lines {
+		task := line.task
+		task.UserID = s.userID
+		task.SyncStatus = models.SyncStatusPending
+		task.UpdatedBy = s.actor()
+
+		var owner, createdBy string
+		var existingUpdatedAt sql.NullTime
+		err := tx.QueryRow(`SELECT user_id, updated_at, created_by FROM tasks WHERE id = ?`, task.ID).Scan(&owner, &existingUpdatedAt, &createdBy)
+		switch {
+		case err == sql.ErrNoRows:
+			if clash, err := s.importTitleClashTx(tx, task); err != nil {
+				return 0, 0, nil, err
+			} else if clash {
+				skipped = append(skipped, ImportLineError{Line: line.number, Error: ErrDuplicateTitle.Error()})
+				continue
+			}
+			task.CreatedBy = s.actor()
+			if _, err := s.insertTaskTx(tx, task); err != nil {
+				return 0, 0, nil, err
+			}
+			imported++
+		case err != nil:
+			return 0, 0, nil, fmt.Errorf("failed to look up task: %w", err)
+		case owner != s.userID:
+			// Don't reveal or touch another user's task
+			skipped = append(skipped, ImportLineError{Line: line.number, Error: "id is already in use"})
+		case existingUpdatedAt.Valid && !task.UpdatedAt.After(existingUpdatedAt.Time):
+			// The stored copy is as new or newer
+		default:
+			if clash, err := s.importTitleClashTx(tx, task); err != nil {
+				return 0, 0, nil, err
+			} else if clash {
+				skipped = append(skipped, ImportLineError{Line: line.number, Error: ErrDuplicateTitle.Error()})
+				continue
+			}
+			task.CreatedBy = createdBy
+			if err := s.replaceTaskTx(tx, task); err != nil {
+				return 0, 0, nil, err
+			}
+			updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return imported, updated, skipped, nil
+}
+
+// importTitleClashTx reports whether writing an imported task would break
+// ENFORCE_UNIQUE_TITLES. It runs the same check as create and update, against
+// the batch's own transaction so earlier lines in the batch count too. Deleted
+// tasks never clash.
+func (s *TaskService) importTitleClashTx(tx *sql.Tx, task *models.Task) (bool, error) {
+	if task.IsDeleted {
+		return false, nil
+	}
+	err := s.checkTitleTx(tx, task)
+	if errors.Is(err, ErrDuplicateTitle) {
+		return true, nil
+	}
+	return false, err
+}
+
+// replaceTaskTx overwrites an existing task with an imported copy and queues the change.
+func (s *TaskService) replaceTaskTx(tx *sql.Tx, task *models.Task) error {
+	metadata, err := metadataJSON(task.Metadata)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE tasks
+        SET title = ?, description = ?, completed = ?, priority = ?, created_at = ?, updated_at = ?,
+            is_deleted = ?, deleted_at = ?, archived = ?, sync_status = ?, server_id = COALESCE(?, server_id),
+            due_date = ?, recurrence_rule = ?, updated_by = ?, metadata = ?
+        WHERE id = ?
+    `
+
+	_, err = tx.Exec(query, task.Title, task.Description, task.Completed, task.Priority, task.CreatedAt,
+		task.UpdatedAt, task.IsDeleted, task.DeletedAt, task.Archived, task.SyncStatus, task.ServerID,
+		task.DueDate, task.RecurrenceRule, task.UpdatedBy, metadata, task.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+
+	if err := setTaskTagsTx(tx, task.ID, task.Tags); err != nil {
+		return err
+	}
+
+	opType, event := models.OperationTypeUpdate, models.TaskEventUpdated
+	if task.IsDeleted {
+		opType, event = models.OperationTypeDelete, models.TaskEventDeleted
+	}
+	if _, err := s.syncService.AddToQueueTx(tx, task.ID, opType, task); err != nil {
+		return fmt.Errorf("failed to add to sync queue: %w", err)
+	}
+
+	return recordEventTx(tx, event, task)
+}
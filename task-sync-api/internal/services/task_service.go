@@ -1,16 +1,50 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/tracing"
 )
 
+// ErrTaskNotFound is returned when a task does not exist or has been deleted.
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrDuplicateTitle is returned when unique titles are enforced and another
+// active task already uses the title.
+var ErrDuplicateTitle = errors.New("a task with this title already exists")
+
+// ErrPreconditionFailed is returned when an update's If-Match doesn't name the
+// task's current version.
+var ErrPreconditionFailed = errors.New("task has changed since it was read")
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidSort is returned when a sort order names a field that can't be
+// sorted on or a direction other than asc or desc.
+var ErrInvalidSort = errors.New("invalid sort")
+
+// Notifier is told about task changes once they are committed.
+type Notifier interface {
+	Notify(event models.TaskEventType, task *models.Task)
+}
+
 type TaskService struct {
 	db          *database.DB
 	syncService *SyncService
+	notifier    Notifier
+	userID      string
+	ctx         context.Context
 }
 
 func NewTaskService(db *database.DB, syncService *SyncService) *TaskService {
@@ -20,16 +54,230 @@ func NewTaskService(db *database.DB, syncService *SyncService) *TaskService {
 	}
 }
 
+// ForUser returns a view of the service scoped to userID. Tasks it creates are
+// owned by that user, and reads and writes only see that user's tasks. The
+// unscoped service acts for the empty user ID, which owns tasks created
+// before ownership existed.
+func (s *TaskService) ForUser(userID string) *TaskService {
+	scoped := *s
+	scoped.userID = userID
+	return &scoped
+}
+
+// WithContext returns a view of the service whose database calls run under ctx,
+// so they are abandoned once ctx is cancelled or its deadline passes.
+func (s *TaskService) WithContext(ctx context.Context) *TaskService {
+	scoped := *s
+	scoped.ctx = ctx
+	return &scoped
+}
+
+// context is the context database calls run under.
+func (s *TaskService) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// startSpan returns a view of the service whose calls belong to a new span for
+// the named method.
+func (s *TaskService) startSpan(method string) (*TaskService, *tracing.Span) {
+	ctx, span := tracing.Start(s.context(), "TaskService."+method, tracing.SpanKindInternal)
+	return s.WithContext(ctx), span
+}
+
+// actor is the identity recorded in created_by and updated_by for changes made
+// through this service.
+func (s *TaskService) actor() string {
+	if s.userID == "" {
+		return models.SystemActor
+	}
+	return s.userID
+}
+
+// taskColumns selects every task column plus the task's tags as a JSON array.
+const taskColumns = `
+        id, user_id, title, description, completed, priority, created_at, updated_at,
+        is_deleted, deleted_at, archived, sync_status, server_id, last_synced_at, due_date, recurrence_rule,
+        created_by, updated_by, metadata,
+        (SELECT COALESCE(json_group_array(name), '[]') FROM (
+            SELECT tags.name FROM task_tags
+            JOIN tags ON tags.id = task_tags.tag_id
+            WHERE task_tags.task_id = tasks.id
+            ORDER BY tags.name
+        )) AS tags
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row rowScanner) (*models.Task, error) {
+	task := &models.Task{}
+	var description, serverID, recurrenceRule sql.NullString
+	var lastSyncedAt, dueDate, deletedAt sql.NullTime
+	var tags, metadata string
+
+	err := row.Scan(
+		&task.ID, &task.UserID, &task.Title, &description, &task.Completed, &task.Priority,
+		&task.CreatedAt, &task.UpdatedAt, &task.IsDeleted, &deletedAt, &task.Archived,
+		&task.SyncStatus, &serverID, &lastSyncedAt, &dueDate, &recurrenceRule,
+		&task.CreatedBy, &task.UpdatedBy, &metadata, &tags,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle nullable fields
+	if description.Valid {
+		task.Description = &description.String
+	}
+	if serverID.Valid {
+		task.ServerID = &serverID.String
+	}
+	if lastSyncedAt.Valid {
+		task.LastSyncedAt = &lastSyncedAt.Time
+	}
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.Time
+	}
+	if dueDate.Valid {
+		task.DueDate = &dueDate.Time
+	}
+	if recurrenceRule.Valid {
+		task.RecurrenceRule = &recurrenceRule.String
+	}
+
+	if err := json.Unmarshal([]byte(tags), &task.Tags); err != nil {
+		return nil, fmt.Errorf("failed to parse tags: %w", err)
+	}
+	if err := json.Unmarshal([]byte(metadata), &task.Metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse metadata: %w", err)
+	}
+
+	return task, nil
+}
+
+// metadataJSON encodes metadata for the tasks.metadata column, writing an empty
+// object for none.
+func metadataJSON(metadata map[string]interface{}) (string, error) {
+	if metadata == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	return string(data), nil
+}
+
+// SetNotifier registers a notifier for task lifecycle events.
+func (s *TaskService) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+func (s *TaskService) notify(event models.TaskEventType, task *models.Task) {
+	if s.notifier != nil {
+		s.notifier.Notify(event, task)
+	}
+}
+
 func (s *TaskService) GetAllTasks() ([]*models.Task, error) {
+	return s.ListTasks(&models.TaskFilter{})
+}
+
+// defaultTaskOrder is the listing order when neither the filter nor the
+// configuration names one: most recently updated first.
+const defaultTaskOrder = "updated_at DESC, created_at DESC"
+
+// taskSortColumns maps the fields a listing may be sorted by to the SQL that
+// sorts them. Only these strings ever reach the ORDER BY clause.
+var taskSortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title COLLATE NOCASE",
+	"priority":   "priority",
+}
+
+// ValidateTaskSort checks a sort order of the form "field" or "field:asc" or
+// "field:desc", as accepted by ListTasks. An empty sort is valid.
+func ValidateTaskSort(sort string) error {
+	_, err := taskOrderBy(sort)
+	return err
+}
+
+// taskOrderBy turns a sort order into an ORDER BY clause, with id breaking ties
+// so the order is stable. The direction defaults to ascending.
+func taskOrderBy(sort string) (string, error) {
+	if sort == "" {
+		return defaultTaskOrder, nil
+	}
+
+	field, direction, _ := strings.Cut(sort, ":")
+	column, ok := taskSortColumns[field]
+	if !ok {
+		return "", fmt.Errorf("%w: sort must be one of created_at, updated_at, title, priority", ErrInvalidSort)
+	}
+
+	switch direction {
+	case "", "asc":
+		direction = "ASC"
+	case "desc":
+		direction = "DESC"
+	default:
+		return "", fmt.Errorf("%w: sort direction must be asc or desc", ErrInvalidSort)
+	}
+	return column + " " + direction + ", id " + direction, nil
+}
+
+// ListTasks returns the non-deleted tasks matching the filter, in the filter's
+// sort order, or else the configured DefaultTaskSort. A sort order that isn't
+// allowed returns ErrInvalidSort.
+func (s *TaskService) ListTasks(filter *models.TaskFilter) ([]*models.Task, error) {
+	s, span := s.startSpan("ListTasks")
+	defer span.End()
+
+	sort := filter.Sort
+	if sort == "" {
+		sort = s.syncService.config.DefaultTaskSort
+	}
+	orderBy, err := taskOrderBy(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"is_deleted = 0", "user_id = ?"}
+	args := []interface{}{s.userID}
+
+	if !filter.IncludeArchived {
+		conditions = append(conditions, "archived = 0")
+	}
+	if filter.UpdatedAfter != nil {
+		conditions = append(conditions, "updated_at >= ?")
+		args = append(args, filter.UpdatedAfter)
+	}
+	if filter.UpdatedBefore != nil {
+		conditions = append(conditions, "updated_at < ?")
+		args = append(args, filter.UpdatedBefore)
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, `id IN (
+            SELECT task_tags.task_id FROM task_tags
+            JOIN tags ON tags.id = task_tags.tag_id
+            WHERE tags.name = ?
+        )`)
+		args = append(args, filter.Tag)
+	}
+
 	query := `
-        SELECT id, title, description, completed, created_at, updated_at, 
-               is_deleted, sync_status, server_id, last_synced_at
+        SELECT ` + taskColumns + `
         FROM tasks 
-        WHERE is_deleted = 0
-        ORDER BY updated_at DESC, created_at DESC
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY ` + orderBy + `
     `
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.Reader().QueryContext(s.context(), query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tasks: %w", err)
 	}
@@ -37,191 +285,1072 @@ func (s *TaskService) GetAllTasks() ([]*models.Task, error) {
 
 	var tasks []*models.Task
 	for rows.Next() {
-		task := &models.Task{}
-		var description, serverID sql.NullString
-		var lastSyncedAt sql.NullTime
-
-		err := rows.Scan(
-			&task.ID, &task.Title, &description, &task.Completed,
-			&task.CreatedAt, &task.UpdatedAt, &task.IsDeleted,
-			&task.SyncStatus, &serverID, &lastSyncedAt,
-		)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan task: %w", err)
 		}
+		tasks = append(tasks, task)
+	}
 
-		// Handle nullable fields
-		if description.Valid {
-			task.Description = &description.String
-		}
-		if serverID.Valid {
-			task.ServerID = &serverID.String
-		}
-		if lastSyncedAt.Valid {
-			task.LastSyncedAt = &lastSyncedAt.Time
+	return tasks, rows.Err()
+}
+
+// taskCursor is the position of the last task on a page. It is handed to
+// clients as opaque base64 so they treat it as a token.
+type taskCursor struct {
+	UpdatedAt time.Time `json:"u"`
+	ID        string    `json:"id"`
+}
+
+func encodeTaskCursor(task *models.Task) string {
+	data, _ := json.Marshal(taskCursor{UpdatedAt: task.UpdatedAt, ID: task.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(cursor string) (*taskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c taskCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" || c.UpdatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// GetTasksAfter returns up to limit non-deleted, unarchived tasks following
+// cursor, newest first, ordered by (updated_at, id). An empty cursor starts at the
+// first page. The returned cursor is empty once there are no more tasks.
+func (s *TaskService) GetTasksAfter(cursor string, limit int) ([]*models.Task, string, error) {
+	s, span := s.startSpan("GetTasksAfter")
+	defer span.End()
+
+	conditions := []string{"is_deleted = 0", "archived = 0", "user_id = ?"}
+	args := []interface{}{s.userID}
+
+	if cursor != "" {
+		after, err := decodeTaskCursor(cursor)
+		if err != nil {
+			return nil, "", err
 		}
+		conditions = append(conditions, "(updated_at < ? OR (updated_at = ? AND id < ?))")
+		args = append(args, after.UpdatedAt, after.UpdatedAt, after.ID)
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	query := `
+        SELECT ` + taskColumns + `
+        FROM tasks 
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY updated_at DESC, id DESC
+        LIMIT ?
+    `
+	args = append(args, limit+1)
+
+	rows, err := s.db.Reader().QueryContext(s.context(), query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
 
+	tasks := []*models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan task: %w", err)
+		}
 		tasks = append(tasks, task)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read tasks: %w", err)
+	}
+
+	var next string
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		next = encodeTaskCursor(tasks[limit-1])
+	}
+
+	return tasks, next, nil
+}
+
+// TaskPage is one page of tasks with what clients need to move between pages.
+// NextCursor is empty on the last page. HasPrev is false on the first page; when
+// it is true an empty PrevCursor means the previous page is the first one.
+type TaskPage struct {
+	Tasks      []*models.Task
+	NextCursor string
+	PrevCursor string
+	HasPrev    bool
+	Total      int
+}
+
+// GetTaskPage is GetTasksAfter plus the cursor for the previous page and the
+// number of tasks across all pages.
+func (s *TaskService) GetTaskPage(cursor string, limit int) (*TaskPage, error) {
+	s, span := s.startSpan("GetTaskPage")
+	defer span.End()
+
+	tasks, next, err := s.GetTasksAfter(cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &TaskPage{Tasks: tasks, NextCursor: next}
 
-	return tasks, nil
+	err = s.db.Reader().QueryRowContext(s.context(), `SELECT COUNT(*) FROM tasks WHERE is_deleted = 0 AND archived = 0 AND user_id = ?`,
+		s.userID).Scan(&page.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	if cursor == "" {
+		return page, nil
+	}
+	page.HasPrev = true
+
+	// The cursor is the last task of the previous page, so that page is the cursor
+	// task and the limit-1 tasks before it. Its own cursor is the task just
+	// before those, if there is one.
+	after, err := decodeTaskCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Reader().QueryContext(s.context(), `
+        SELECT id, updated_at FROM tasks
+        WHERE is_deleted = 0 AND archived = 0 AND user_id = ?
+          AND (updated_at > ? OR (updated_at = ? AND id > ?))
+        ORDER BY updated_at ASC, id ASC
+        LIMIT ?
+    `, s.userID, after.UpdatedAt, after.UpdatedAt, after.ID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query previous page: %w", err)
+	}
+	defer rows.Close()
+
+	var before []*models.Task
+	for rows.Next() {
+		task := &models.Task{}
+		if err := rows.Scan(&task.ID, &task.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		before = append(before, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tasks: %w", err)
+	}
+
+	if len(before) == limit {
+		page.PrevCursor = encodeTaskCursor(before[limit-1])
+	}
+	return page, nil
 }
 
 func (s *TaskService) GetTaskByID(id string) (*models.Task, error) {
+	s, span := s.startSpan("GetTaskByID")
+	defer span.End()
+
+	return getTask(s.context(), s.db.Reader(), id, s.userID)
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// getTask loads a non-deleted task owned by userID through db, which may be a
+// transaction. Other users' tasks are reported as not found.
+func getTask(ctx context.Context, db queryRower, id, userID string) (*models.Task, error) {
 	query := `
-        SELECT id, title, description, completed, created_at, updated_at, 
-               is_deleted, sync_status, server_id, last_synced_at
+        SELECT ` + taskColumns + `
         FROM tasks 
-        WHERE id = ? AND is_deleted = 0
+        WHERE id = ? AND user_id = ? AND is_deleted = 0
     `
 
-	task := &models.Task{}
-	var description, serverID sql.NullString
-	var lastSyncedAt sql.NullTime
+	task, err := scanTask(db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
 
-	err := s.db.QueryRow(query, id).Scan(
-		&task.ID, &task.Title, &description, &task.Completed,
-		&task.CreatedAt, &task.UpdatedAt, &task.IsDeleted,
-		&task.SyncStatus, &serverID, &lastSyncedAt,
-	)
+	return task, nil
+}
+
+// GetByServerID returns the user's active task that the sync server knows by
+// serverID, or ErrTaskNotFound when there is none.
+func (s *TaskService) GetByServerID(serverID string) (*models.Task, error) {
+	s, span := s.startSpan("GetByServerID")
+	defer span.End()
 
+	query := `
+        SELECT ` + taskColumns + `
+        FROM tasks
+        WHERE server_id = ? AND user_id = ? AND is_deleted = 0
+    `
+
+	task, err := scanTask(s.db.Reader().QueryRowContext(s.context(), query, serverID, s.userID))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("task not found")
+		return nil, ErrTaskNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// Handle nullable fields
-	if description.Valid {
-		task.Description = &description.String
+	return task, nil
+}
+
+// isSoftDeleted reports whether the user owns a task with this ID that has
+// already been soft-deleted.
+func isSoftDeleted(ctx context.Context, db queryRower, id, userID string) (bool, error) {
+	var exists int
+	err := db.QueryRowContext(ctx, `
+        SELECT 1 FROM tasks WHERE id = ? AND user_id = ? AND is_deleted = 1
+    `, id, userID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
 	}
-	if serverID.Valid {
-		task.ServerID = &serverID.String
+	if err != nil {
+		return false, fmt.Errorf("failed to check deleted task: %w", err)
 	}
-	if lastSyncedAt.Valid {
-		task.LastSyncedAt = &lastSyncedAt.Time
+	return true, nil
+}
+
+// setTaskTagsTx replaces the task's tag set, creating any tags that don't exist yet.
+func setTaskTagsTx(tx *sql.Tx, taskID string, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, taskID); err != nil {
+		return fmt.Errorf("failed to clear task tags: %w", err)
 	}
 
-	return task, nil
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, tag); err != nil {
+			return fmt.Errorf("failed to insert tag: %w", err)
+		}
+
+		_, err := tx.Exec(`
+            INSERT INTO task_tags (task_id, tag_id)
+            SELECT ?, id FROM tags WHERE name = ?
+        `, taskID, tag)
+		if err != nil {
+			return fmt.Errorf("failed to tag task: %w", err)
+		}
+	}
+
+	return nil
 }
 
 func (s *TaskService) CreateTask(req *models.CreateTaskRequest) (*models.Task, error) {
-	task := models.NewTask(req.Title, req.Description)
+	task, _, err := s.CreateTaskWithSyncItem(req)
+	return task, err
+}
+
+// CreateTaskWithSyncItem creates the task like CreateTask and also returns the
+// create operation it queued.
+func (s *TaskService) CreateTaskWithSyncItem(req *models.CreateTaskRequest) (*models.Task, *models.SyncQueueItem, error) {
+	s, span := s.startSpan("CreateTask")
+	defer span.End()
+
+	task := s.newTask(req)
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(s.context(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Insert task
-	query := `
-        INSERT INTO tasks (id, title, description, completed, created_at, updated_at, 
-                          is_deleted, sync_status, server_id, last_synced_at)
-        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-    `
-
-	_, err = tx.Exec(query, task.ID, task.Title, task.Description, task.Completed,
-		task.CreatedAt, task.UpdatedAt, task.IsDeleted, task.SyncStatus,
-		task.ServerID, task.LastSyncedAt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to insert task: %w", err)
+	if err := s.checkTitleTx(tx, task); err != nil {
+		return nil, nil, err
 	}
 
-	// Add to sync queue
-	if err := s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeCreate, task); err != nil {
-		return nil, fmt.Errorf("failed to add to sync queue: %w", err)
+	item, err := s.insertTaskTx(tx, task)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return task, nil
+	s.notify(models.TaskEventCreated, task)
+	return task, item, nil
 }
 
-func (s *TaskService) UpdateTask(id string, req *models.UpdateTaskRequest) (*models.Task, error) {
-	tx, err := s.db.Begin()
+// newTask builds the caller's new task from a validated request.
+func (s *TaskService) newTask(req *models.CreateTaskRequest) *models.Task {
+	task := models.NewTask(req.Title, req.Description)
+	task.UserID = s.userID
+	task.Tags = req.Tags
+	task.Priority = req.Priority
+	task.DueDate = req.DueDate
+	task.RecurrenceRule = req.RecurrenceRule
+	task.Metadata = req.Metadata
+	task.CreatedBy = s.actor()
+	task.UpdatedBy = s.actor()
+	return task
+}
+
+// checkTitleTx rejects a title already used by another of the user's active tasks
+// when unique titles are enforced. Running inside the write transaction keeps
+// concurrent writers from slipping in the same title.
+func (s *TaskService) checkTitleTx(tx *sql.Tx, task *models.Task) error {
+	if !s.syncService.config.EnforceUniqueTitles {
+		return nil
+	}
+
+	var count int
+	err := tx.QueryRow(`
+        SELECT COUNT(*) FROM tasks
+        WHERE title = ? AND user_id = ? AND id != ? AND is_deleted = 0
+    `, task.Title, task.UserID, task.ID).Scan(&count)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to check title: %w", err)
 	}
-	defer tx.Rollback()
+	if count > 0 {
+		return ErrDuplicateTitle
+	}
+	return nil
+}
 
-	// Get existing task
-	task, err := s.GetTaskByID(id)
+// insertTaskTx inserts a new task with its tags and queues its create operation,
+// which it returns.
+func (s *TaskService) insertTaskTx(tx *sql.Tx, task *models.Task) (*models.SyncQueueItem, error) {
+	metadata, err := metadataJSON(task.Metadata)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update task
-	task.Update(req)
-
 	query := `
-        UPDATE tasks 
-        SET title = ?, description = ?, completed = ?, updated_at = ?, sync_status = ?
-        WHERE id = ? AND is_deleted = 0
+        INSERT INTO tasks (id, user_id, title, description, completed, priority, created_at, updated_at, 
+                          is_deleted, deleted_at, archived, sync_status, server_id, last_synced_at, due_date,
+                          recurrence_rule, created_by, updated_by, metadata)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
-	result, err := tx.Exec(query, task.Title, task.Description, task.Completed,
-		task.UpdatedAt, task.SyncStatus, id)
+	_, err = tx.Exec(query, task.ID, task.UserID, task.Title, task.Description, task.Completed, task.Priority,
+		task.CreatedAt, task.UpdatedAt, task.IsDeleted, task.DeletedAt, task.Archived, task.SyncStatus,
+		task.ServerID, task.LastSyncedAt, task.DueDate, task.RecurrenceRule,
+		task.CreatedBy, task.UpdatedBy, metadata)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update task: %w", err)
+		return nil, fmt.Errorf("failed to insert task: %w", err)
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return nil, fmt.Errorf("task not found")
+	if err := setTaskTagsTx(tx, task.ID, task.Tags); err != nil {
+		return nil, err
 	}
 
 	// Add to sync queue
-	if err := s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeUpdate, task); err != nil {
+	item, err := s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeCreate, task)
+	if err != nil {
 		return nil, fmt.Errorf("failed to add to sync queue: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	if err := recordEventTx(tx, models.TaskEventCreated, task); err != nil {
+		return nil, err
 	}
+	return item, nil
+}
 
-	return task, nil
+// UpdateTask applies the request to the task. Completing a task with incomplete
+// dependencies fails with ErrIncompleteDependencies unless req.Force is set.
+// Completing a recurring task also creates its next occurrence, which shares
+// its title even when unique titles are enforced.
+func (s *TaskService) UpdateTask(id string, req *models.UpdateTaskRequest) (*models.Task, error) {
+	task, _, err := s.UpdateTaskWithSyncItem(id, req)
+	return task, err
 }
 
-func (s *TaskService) DeleteTask(id string) error {
-	tx, err := s.db.Begin()
+// UpdateTaskWithSyncItem updates the task like UpdateTask and also returns the
+// update operation it queued.
+func (s *TaskService) UpdateTaskWithSyncItem(id string, req *models.UpdateTaskRequest) (*models.Task, *models.SyncQueueItem, error) {
+	s, span := s.startSpan("UpdateTask")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Get existing task
-	task, err := s.GetTaskByID(id)
+	task, next, item, err := s.updateTaskTx(tx, id, req)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	// Soft delete
-	task.SoftDelete()
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
-	query := `
-        UPDATE tasks 
-        SET is_deleted = 1, updated_at = ?, sync_status = ?
-        WHERE id = ?
-    `
+	s.notify(models.TaskEventUpdated, task)
+	if next != nil {
+		s.notify(models.TaskEventCreated, next)
+	}
+	return task, item, nil
+}
 
-	result, err := tx.Exec(query, task.UpdatedAt, task.SyncStatus, id)
+// updateTaskTx applies the request to the task inside tx and queues the update,
+// returning the queued item. When the change completes a recurring task it also
+// inserts and returns the next occurrence.
+func (s *TaskService) updateTaskTx(tx *sql.Tx, id string, req *models.UpdateTaskRequest) (task, next *models.Task, item *models.SyncQueueItem, err error) {
+	// Get existing task
+	task, err = getTask(s.context(), tx, id, s.userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete task: %w", err)
+		return nil, nil, nil, err
+	}
+	// Checked inside the transaction so no other write slips in between
+	if req.IfMatch != "" && !task.MatchesETag(req.IfMatch) {
+		return nil, nil, nil, ErrPreconditionFailed
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return fmt.Errorf("task not found")
+	if !task.Completed && req.Completed != nil && *req.Completed && !req.Force {
+		if err := checkDependenciesTx(tx, id); err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
-	// Add to sync queue
-	if err := s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeDelete, task); err != nil {
-		return fmt.Errorf("failed to add to sync queue: %w", err)
+	// Update task
+	wasCompleted := task.Completed
+	task.Update(req)
+	task.UpdatedBy = s.actor()
+
+	if req.Title != nil {
+		if err := s.checkTitleTx(tx, task); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	// The request's metadata fit on its own but may not once merged
+	if req.Metadata != nil {
+		if err := models.ValidateMetadata(task.Metadata); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	metadata, err := metadataJSON(task.Metadata)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	query := `
+        UPDATE tasks 
+        SET title = ?, description = ?, completed = ?, priority = ?, updated_at = ?, sync_status = ?,
+            due_date = ?, recurrence_rule = ?, updated_by = ?, metadata = ?
+        WHERE id = ? AND is_deleted = 0
+    `
+
+	result, err := tx.Exec(query, task.Title, task.Description, task.Completed, task.Priority,
+		task.UpdatedAt, task.SyncStatus, task.DueDate, task.RecurrenceRule, task.UpdatedBy, metadata, id)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to update task: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return nil, nil, nil, ErrTaskNotFound
+	}
+
+	if req.Tags != nil {
+		if err := setTaskTagsTx(tx, task.ID, task.Tags); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// Add to sync queue
+	item, err = s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeUpdate, task)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to add to sync queue: %w", err)
+	}
+
+	if err := recordEventTx(tx, models.TaskEventUpdated, task); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if !wasCompleted && task.Completed && task.RecurrenceRule != nil {
+		next, err = task.NextOccurrence(time.Now())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to schedule next occurrence: %w", err)
+		}
+		next.CreatedBy = task.UpdatedBy
+		next.UpdatedBy = task.UpdatedBy
+		if _, err := s.insertTaskTx(tx, next); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return task, next, item, nil
+}
+
+// BulkSetCompleted sets the completed flag on every listed task in one
+// transaction, queueing an update for each. IDs that don't name one of the
+// caller's active tasks are skipped and returned in notFound rather than
+// failing the batch; any other error, including ErrIncompleteDependencies,
+// rolls back every change.
+func (s *TaskService) BulkSetCompleted(ids []string, completed bool) (updated int, notFound []string, err error) {
+	s, span := s.startSpan("BulkSetCompleted")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	notFound = []string{}
+	var changed, created []*models.Task
+	seen := make(map[string]bool, len(ids))
+	req := &models.UpdateTaskRequest{Completed: &completed}
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		task, next, _, err := s.updateTaskTx(tx, id, req)
+		if errors.Is(err, ErrTaskNotFound) {
+			notFound = append(notFound, id)
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		changed = append(changed, task)
+		if next != nil {
+			created = append(created, next)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, task := range changed {
+		s.notify(models.TaskEventUpdated, task)
+	}
+	for _, task := range created {
+		s.notify(models.TaskEventCreated, task)
+	}
+	return len(changed), notFound, nil
+}
+
+// DeleteTask soft-deletes the task. Deleting a task that is already deleted
+// succeeds without queueing anything; an ID the user never had still returns
+// ErrTaskNotFound.
+func (s *TaskService) DeleteTask(id string) error {
+	_, err := s.DeleteTaskWithSyncItem(id)
+	return err
+}
+
+// DeleteTaskWithSyncItem soft-deletes the task like DeleteTask and also returns
+// the delete operation it queued, which is nil when the task was already deleted.
+func (s *TaskService) DeleteTaskWithSyncItem(id string) (*models.SyncQueueItem, error) {
+	s, span := s.startSpan("DeleteTask")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	task, item, err := s.deleteTaskTx(tx, id)
+	if err != nil || task == nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.notify(models.TaskEventDeleted, task)
+	return item, nil
+}
+
+// deleteTaskTx soft-deletes the task inside tx and queues the delete, returning
+// the task and the queued item. Both are nil when the task was already deleted.
+func (s *TaskService) deleteTaskTx(tx *sql.Tx, id string) (*models.Task, *models.SyncQueueItem, error) {
+	// Get existing task
+	task, err := getTask(s.context(), tx, id, s.userID)
+	if errors.Is(err, ErrTaskNotFound) {
+		deleted, checkErr := isSoftDeleted(s.context(), tx, id, s.userID)
+		if checkErr != nil {
+			return nil, nil, checkErr
+		}
+		if deleted {
+			return nil, nil, nil
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Soft delete
+	task.SoftDelete()
+	task.UpdatedBy = s.actor()
+
+	query := `
+        UPDATE tasks 
+        SET is_deleted = 1, deleted_at = ?, updated_at = ?, sync_status = ?, updated_by = ?
+        WHERE id = ?
+    `
+
+	result, err := tx.Exec(query, task.DeletedAt, task.UpdatedAt, task.SyncStatus, task.UpdatedBy, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to delete task: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return nil, nil, ErrTaskNotFound
+	}
+
+	// Deleted tasks no longer carry tags; the queued payload keeps the last set
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, id); err != nil {
+		return nil, nil, fmt.Errorf("failed to clear task tags: %w", err)
+	}
+
+	// Add to sync queue
+	item, err := s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeDelete, task)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to add to sync queue: %w", err)
+	}
+
+	if err := recordEventTx(tx, models.TaskEventDeleted, task); err != nil {
+		return nil, nil, err
+	}
+	return task, item, nil
+}
+
+// BulkDelete soft-deletes the listed tasks and queues their deletes in one
+// transaction. IDs that don't name one of the user's tasks are skipped and
+// returned in notFound rather than failing the rest. Tasks that were already
+// deleted are neither counted nor reported missing.
+func (s *TaskService) BulkDelete(ids []string) (deleted int, notFound []string, err error) {
+	s, span := s.startSpan("BulkDelete")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	notFound = []string{}
+	var removed []*models.Task
+	seen := make(map[string]bool, len(ids))
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		task, _, err := s.deleteTaskTx(tx, id)
+		if errors.Is(err, ErrTaskNotFound) {
+			notFound = append(notFound, id)
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		if task != nil {
+			removed = append(removed, task)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, task := range removed {
+		s.notify(models.TaskEventDeleted, task)
+	}
+	return len(removed), notFound, nil
+}
+
+// HardDeleteTask permanently removes the task along with its tags, sync
+// history, activity entries and conflict records. Any queued operations for it
+// are replaced by a single delete, which stays queued after the row is gone so
+// the server still learns of the deletion.
+func (s *TaskService) HardDeleteTask(id string) error {
+	_, err := s.HardDeleteTaskWithSyncItem(id)
+	return err
+}
+
+// HardDeleteTaskWithSyncItem removes the task like HardDeleteTask and also
+// returns the delete operation it queued.
+func (s *TaskService) HardDeleteTaskWithSyncItem(id string) (*models.SyncQueueItem, error) {
+	s, span := s.startSpan("HardDeleteTask")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A task that was already soft-deleted can still be removed for good
+	query := `
+        SELECT ` + taskColumns + `
+        FROM tasks
+        WHERE id = ? AND user_id = ?
+    `
+	task, err := scanTask(tx.QueryRow(query, id, s.userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	if !task.IsDeleted {
+		task.SoftDelete()
+	}
+	task.UpdatedBy = s.actor()
+
+	// Earlier changes no longer need to reach the server
+	if _, err := tx.Exec(`DELETE FROM sync_queue WHERE task_id = ?`, id); err != nil {
+		return nil, fmt.Errorf("failed to clear sync queue: %w", err)
+	}
+	item, err := s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeDelete, task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add to sync queue: %w", err)
+	}
+
+	// These tables keep copies of the task and don't cascade from it
+	for _, query := range []string{
+		`DELETE FROM task_events WHERE task_id = ?`,
+		`DELETE FROM sync_conflicts WHERE task_id = ?`,
+	} {
+		if _, err := tx.Exec(query, id); err != nil {
+			return nil, fmt.Errorf("failed to remove task records: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM tasks WHERE id = ?`, id); err != nil {
+		return nil, fmt.Errorf("failed to delete task: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.syncService.invalidateQueueSize()
+	s.notify(models.TaskEventDeleted, task)
+	return item, nil
+}
+
+// ArchiveTask hides the task from default listings without deleting it and
+// queues the change for sync.
+func (s *TaskService) ArchiveTask(id string) (*models.Task, error) {
+	s, span := s.startSpan("ArchiveTask")
+	defer span.End()
+
+	return s.setArchived(id, true)
+}
+
+// UnarchiveTask returns an archived task to the default listings and queues the
+// change for sync.
+func (s *TaskService) UnarchiveTask(id string) (*models.Task, error) {
+	s, span := s.startSpan("UnarchiveTask")
+	defer span.End()
+
+	return s.setArchived(id, false)
+}
+
+func (s *TaskService) setArchived(id string, archived bool) (*models.Task, error) {
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	task, err := getTask(s.context(), tx, id, s.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	task.SetArchived(archived)
+	task.UpdatedBy = s.actor()
+
+	query := `
+        UPDATE tasks
+        SET archived = ?, updated_at = ?, sync_status = ?, updated_by = ?
+        WHERE id = ? AND is_deleted = 0
+    `
+
+	if _, err := tx.Exec(query, task.Archived, task.UpdatedAt, task.SyncStatus, task.UpdatedBy, id); err != nil {
+		return nil, fmt.Errorf("failed to update task: %w", err)
+	}
+
+	// Unarchiving has no operation of its own and syncs as an update
+	opType := models.OperationTypeUpdate
+	if archived {
+		opType = models.OperationTypeArchive
+	}
+	if _, err := s.syncService.AddToQueueTx(tx, task.ID, opType, task); err != nil {
+		return nil, fmt.Errorf("failed to add to sync queue: %w", err)
+	}
+
+	if err := recordEventTx(tx, models.TaskEventUpdated, task); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.notify(models.TaskEventUpdated, task)
+	return task, nil
+}
+
+// RestoreTask brings back a soft-deleted task and queues a restore for sync.
+// Tags cleared by the delete are not brought back. It returns ErrTaskNotFound
+// unless the user has a deleted task with this ID.
+func (s *TaskService) RestoreTask(id string) (*models.Task, error) {
+	s, span := s.startSpan("RestoreTask")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+        SELECT ` + taskColumns + `
+        FROM tasks
+        WHERE id = ? AND user_id = ? AND is_deleted = 1
+    `
+	task, err := scanTask(tx.QueryRow(query, id, s.userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	task.Restore()
+	task.UpdatedBy = s.actor()
+	if err := s.checkTitleTx(tx, task); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+        UPDATE tasks
+        SET is_deleted = 0, deleted_at = NULL, updated_at = ?, sync_status = ?, updated_by = ?
+        WHERE id = ?
+    `, task.UpdatedAt, task.SyncStatus, task.UpdatedBy, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore task: %w", err)
+	}
+
+	if _, err := s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeRestore, task); err != nil {
+		return nil, fmt.Errorf("failed to add to sync queue: %w", err)
+	}
+
+	if err := recordEventTx(tx, models.TaskEventUpdated, task); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.notify(models.TaskEventUpdated, task)
+	return task, nil
+}
+
+// StreamTasks writes every one of the user's tasks, deleted ones included, to w
+// as newline-delimited JSON. Rows are encoded as they are read so memory use
+// doesn't grow with the table.
+func (s *TaskService) StreamTasks(w io.Writer) error {
+	s, span := s.startSpan("StreamTasks")
+	defer span.End()
+
+	query := `
+        SELECT ` + taskColumns + `
+        FROM tasks 
+        WHERE user_id = ?
+        ORDER BY created_at ASC, id ASC
+    `
+
+	rows, err := s.db.Reader().QueryContext(s.context(), query, s.userID)
+	if err != nil {
+		return fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	encoder := json.NewEncoder(w)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan task: %w", err)
+		}
+		if err := encoder.Encode(task); err != nil {
+			return fmt.Errorf("failed to write task: %w", err)
+		}
+	}
+
+	return rows.Err()
+}
+
+// TaskStats holds task counts for dashboards. Active, Completed and Deleted
+// partition the user's tasks by lifecycle; PendingSync and ErrorSync count
+// tasks in those sync states, including deleted tasks whose delete hasn't synced.
+type TaskStats struct {
+	Active      int `json:"active"`
+	Completed   int `json:"completed"`
+	PendingSync int `json:"pending_sync"`
+	ErrorSync   int `json:"error_sync"`
+	Deleted     int `json:"deleted"`
+}
+
+// GetStats counts the user's tasks in a single pass over the table.
+func (s *TaskService) GetStats() (*TaskStats, error) {
+	s, span := s.startSpan("GetStats")
+	defer span.End()
+
+	query := `
+        SELECT
+            COALESCE(SUM(CASE WHEN is_deleted = 0 THEN 1 ELSE 0 END), 0),
+            COALESCE(SUM(CASE WHEN is_deleted = 0 AND completed = 1 THEN 1 ELSE 0 END), 0),
+            COALESCE(SUM(CASE WHEN sync_status = ? THEN 1 ELSE 0 END), 0),
+            COALESCE(SUM(CASE WHEN sync_status = ? THEN 1 ELSE 0 END), 0),
+            COALESCE(SUM(CASE WHEN is_deleted = 1 THEN 1 ELSE 0 END), 0)
+        FROM tasks
+        WHERE user_id = ?
+    `
+
+	stats := &TaskStats{}
+	err := s.db.Reader().QueryRowContext(s.context(), query, models.SyncStatusPending, models.SyncStatusError, s.userID).Scan(
+		&stats.Active, &stats.Completed, &stats.PendingSync, &stats.ErrorSync, &stats.Deleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	}
+
+	return stats, nil
+}
+
+// ForceResync queues a fresh update for a task regardless of its current sync
+// status and marks it pending again. The task's content and updated_at are unchanged.
+func (s *TaskService) ForceResync(id string) (*models.Task, error) {
+	s, span := s.startSpan("ForceResync")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	task, err := getTask(s.context(), tx, id, s.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	task.SyncStatus = models.SyncStatusPending
+	if _, err := tx.Exec(`UPDATE tasks SET sync_status = ? WHERE id = ?`, task.SyncStatus, id); err != nil {
+		return nil, fmt.Errorf("failed to update sync status: %w", err)
+	}
+
+	if _, err := s.syncService.AddToQueueTx(tx, task.ID, models.OperationTypeUpdate, task); err != nil {
+		return nil, fmt.Errorf("failed to add to sync queue: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return task, nil
+}
+
+// PurgeDeleted permanently removes tasks soft-deleted more than olderThan ago,
+// going by their deleted_at rather than their last update, along with any dead-lettered sync items. Tasks that
+// still have pending sync operations are kept so their deletes can reach the server.
+// Purging is an operator action and covers every user's tasks.
+func (s *TaskService) PurgeDeleted(olderThan time.Duration) (int, error) {
+	s, span := s.startSpan("PurgeDeleted")
+	defer span.End()
+
+	cutoff := time.Now().Add(-olderThan)
+	retryLimit, limitArgs := s.syncService.retryLimitSQL(s.syncService.config.MaxRetries)
+	args := append([]interface{}{cutoff}, limitArgs...)
+
+	tx, err := s.db.BeginTx(s.context(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	purgeable := `
+        SELECT id FROM tasks
+        WHERE is_deleted = 1 AND deleted_at < ?
+          AND NOT EXISTS (
+              SELECT 1 FROM sync_queue
+              WHERE sync_queue.task_id = tasks.id AND sync_queue.retry_count < ` + retryLimit + `
+          )
+    `
+
+	// Remove dead-lettered sync items first so nothing is left behind
+	_, err = tx.Exec(`DELETE FROM sync_queue WHERE task_id IN (`+purgeable+`)`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge sync queue items: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM tasks WHERE id IN (`+purgeable+`)`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge tasks: %w", err)
+	}
+
+	purged, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(purged), nil
+}
+
+// GetSyncHistory returns every sync attempt for the task, oldest first. Deleted
+// tasks keep their history until they are purged.
+func (s *TaskService) GetSyncHistory(id string) ([]*models.SyncAttempt, error) {
+	s, span := s.startSpan("GetSyncHistory")
+	defer span.End()
+
+	var exists int
+	if err := s.db.QueryRowContext(s.context(), `SELECT COUNT(*) FROM tasks WHERE id = ? AND user_id = ?`, id, s.userID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	if exists == 0 {
+		return nil, ErrTaskNotFound
+	}
+
+	query := `
+        SELECT id, task_id, operation_type, success, error_message, attempted_at
+        FROM sync_attempts
+        WHERE task_id = ?
+        ORDER BY attempted_at ASC, id ASC
+    `
+
+	rows, err := s.db.QueryContext(s.context(), query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync history: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []*models.SyncAttempt{}
+	for rows.Next() {
+		attempt := &models.SyncAttempt{}
+		var errorMessage sql.NullString
+		err := rows.Scan(&attempt.ID, &attempt.TaskID, &attempt.OperationType,
+			&attempt.Success, &errorMessage, &attempt.AttemptedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sync attempt: %w", err)
+		}
+		if errorMessage.Valid {
+			attempt.ErrorMessage = &errorMessage.String
+		}
+		attempts = append(attempts, attempt)
 	}
 
-	return tx.Commit()
+	return attempts, rows.Err()
 }
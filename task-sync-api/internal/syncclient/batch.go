@@ -0,0 +1,106 @@
+package syncclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/tracing"
+)
+
+// ErrBatchUnsupported is returned when the server has no batch endpoint.
+var ErrBatchUnsupported = errors.New("sync server does not support batch sync")
+
+type BatchStatus string
+
+const (
+	BatchStatusSuccess  BatchStatus = "success"
+	BatchStatusConflict BatchStatus = "conflict"
+	BatchStatusError    BatchStatus = "error"
+)
+
+// BatchItem is one queued operation sent to the server's batch endpoint.
+type BatchItem struct {
+	ID         string               `json:"id"`
+	TaskID     string               `json:"task_id"`
+	Operation  models.OperationType `json:"operation"`
+	Data       *models.Task         `json:"data"`
+	CreatedAt  time.Time            `json:"created_at"`
+	RetryCount int                  `json:"retry_count"`
+}
+
+// BatchResult is the server's outcome for one BatchItem, matched by ID.
+type BatchResult struct {
+	ID           string       `json:"id"`
+	ClientID     string       `json:"client_id"`
+	ServerID     string       `json:"server_id"`
+	Status       BatchStatus  `json:"status"`
+	ResolvedData *models.Task `json:"resolved_data"`
+	Error        string       `json:"error"`
+}
+
+type batchRequest struct {
+	Items           []BatchItem `json:"items"`
+	ClientTimestamp time.Time   `json:"client_timestamp"`
+}
+
+type batchResponse struct {
+	ProcessedItems []BatchResult `json:"processed_items"`
+}
+
+// BatchSync pushes several operations in one request and returns a result per item.
+func (c *Client) BatchSync(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	payload, err := json.Marshal(batchRequest{Items: items, ClientTimestamp: time.Now()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+
+	ctx, span := startSpan(ctx, http.MethodPost, c.baseURL+"/batch")
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/batch", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	tracing.Inject(ctx, req.Header)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("%w: %v", ErrServerUnavailable, err)
+	}
+	defer resp.Body.Close()
+	span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed ||
+		resp.StatusCode == http.StatusNotImplemented:
+		return nil, ErrBatchUnsupported
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, unavailableError(resp)
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("sync server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var result batchResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode batch response: %w", err)
+	}
+
+	return result.ProcessedItems, nil
+}
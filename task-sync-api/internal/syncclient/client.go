@@ -0,0 +1,160 @@
+package syncclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/tracing"
+)
+
+var (
+	// ErrConflict is returned when the server holds a different version of the task.
+	// The server's copy is returned alongside it when the response includes one.
+	ErrConflict = errors.New("sync conflict")
+	// ErrServerUnavailable is returned when the server can't be reached or is overloaded.
+	ErrServerUnavailable = errors.New("sync server unavailable")
+	// ErrInvalidPayload is returned when the server sends back a task that fails
+	// validation. Retrying won't fix it, so it must not be stored.
+	ErrInvalidPayload = errors.New("invalid task from sync server")
+)
+
+// SyncTransport pushes task changes to the sync server. Client implements it
+// over HTTP; tests substitute a fake to force conflicts, outages and timeouts
+// on demand. It has a method per operation rather than a single Sync returning
+// the server ID, because conflict resolution needs the server's whole copy of
+// the task, which comes back from CreateTask and UpdateTask and with ErrConflict.
+type SyncTransport interface {
+	CreateTask(ctx context.Context, task *models.Task) (*models.Task, error)
+	UpdateTask(ctx context.Context, task *models.Task) (*models.Task, error)
+	DeleteTask(ctx context.Context, task *models.Task) error
+}
+
+// Client talks to the remote sync server's task API.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// CreateTask pushes a new task and returns the server's copy.
+func (c *Client) CreateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return c.send(ctx, http.MethodPost, "/tasks", task)
+}
+
+// UpdateTask pushes changes to an existing task and returns the server's copy.
+func (c *Client) UpdateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return c.send(ctx, http.MethodPut, "/tasks/"+url.PathEscape(task.ID), task)
+}
+
+// DeleteTask removes the task on the server.
+func (c *Client) DeleteTask(ctx context.Context, task *models.Task) error {
+	_, err := c.send(ctx, http.MethodDelete, "/tasks/"+url.PathEscape(task.ID), nil)
+	return err
+}
+
+// startSpan records a client span for a request to the sync server. The request
+// carries the span in its traceparent header.
+func startSpan(ctx context.Context, method, url string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "sync server "+method, tracing.SpanKindClient)
+	span.SetAttribute("http.method", method)
+	span.SetAttribute("http.url", url)
+	return ctx, span
+}
+
+// send performs one request. A cancelled or expired ctx is reported as
+// ErrServerUnavailable so the item is retried later.
+func (c *Client) send(ctx context.Context, method, path string, task *models.Task) (*models.Task, error) {
+	var body io.Reader
+	if task != nil {
+		payload, err := json.Marshal(task)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode task: %w", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	ctx, span := startSpan(ctx, method, c.baseURL+path)
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	tracing.Inject(ctx, req.Header)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("%w: %v", ErrServerUnavailable, err)
+	}
+	defer resp.Body.Close()
+	span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, unavailableError(resp)
+	case resp.StatusCode >= 300 && resp.StatusCode != http.StatusConflict:
+		return nil, fmt.Errorf("sync server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	// A successful delete's reply carries no task, whatever its body
+	if method == http.MethodDelete && resp.StatusCode != http.StatusConflict {
+		return nil, nil
+	}
+
+	remote, err := decodeTask(respBody)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusConflict {
+		return remote, ErrConflict
+	}
+	return remote, nil
+}
+
+// decodeTask parses a task from a response body, returning nil when the body is
+// empty. A body that isn't a task, or a task that fails validation, is reported
+// as ErrInvalidPayload.
+func decodeTask(body []byte) (*models.Task, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+	var task models.Task
+	if err := json.Unmarshal(body, &task); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if task.ID == "" {
+		return nil, fmt.Errorf("%w: missing id", ErrInvalidPayload)
+	}
+	if err := task.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	return &task, nil
+}
@@ -0,0 +1,76 @@
+package syncclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/tracing"
+)
+
+// ErrPullUnsupported is returned when the server can't list its tasks.
+var ErrPullUnsupported = errors.New("sync server does not support listing tasks")
+
+// ListTasks returns the server's tasks changed after since, or all of them when
+// since is zero. A task that fails validation is reported as ErrInvalidPayload.
+func (c *Client) ListTasks(ctx context.Context, since time.Time) ([]*models.Task, error) {
+	target := c.baseURL + "/tasks"
+	if !since.IsZero() {
+		target += "?since=" + url.QueryEscape(since.UTC().Format(time.RFC3339Nano))
+	}
+
+	ctx, span := startSpan(ctx, http.MethodGet, target)
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("%w: %v", ErrServerUnavailable, err)
+	}
+	defer resp.Body.Close()
+	span.SetAttribute("http.status_code", strconv.Itoa(resp.StatusCode))
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed ||
+		resp.StatusCode == http.StatusNotImplemented:
+		return nil, ErrPullUnsupported
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, unavailableError(resp)
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("sync server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var tasks []*models.Task
+	if err := json.Unmarshal(respBody, &tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode task list: %w", err)
+	}
+	for _, task := range tasks {
+		if task == nil {
+			return nil, fmt.Errorf("%w: null task", ErrInvalidPayload)
+		}
+		if err := task.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+		}
+	}
+	return tasks, nil
+}
@@ -0,0 +1,70 @@
+package syncclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryAfterError is returned when the server is overloaded or unavailable and
+// said how long to wait before trying again. It wraps ErrServerUnavailable.
+type RetryAfterError struct {
+	StatusCode int
+	Delay      time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%v: status %d, retry after %s", ErrServerUnavailable, e.StatusCode, e.Delay)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return ErrServerUnavailable
+}
+
+// RetryAfter returns the delay the server asked for when err carries one.
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryErr *RetryAfterError
+	if errors.As(err, &retryErr) {
+		return retryErr.Delay, true
+	}
+	return 0, false
+}
+
+// unavailableError reports a 429 or 5xx response, keeping any Retry-After hint
+// sent with a 429 or 503.
+func unavailableError(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return &RetryAfterError{StatusCode: resp.StatusCode, Delay: delay}
+		}
+	}
+	return fmt.Errorf("%w: status %d", ErrServerUnavailable, resp.StatusCode)
+}
+
+// parseRetryAfter reads a Retry-After value given either as delay seconds or as
+// an HTTP date. A date in the past means no wait.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if delay := at.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
@@ -0,0 +1,179 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind says what side of a call a span records. The values match OTLP's.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// SpanContext identifies a span within a trace, as carried by a traceparent
+// header. IDs are lowercase hex.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// IsValid reports whether sc names a trace and span.
+func (sc SpanContext) IsValid() bool {
+	return len(sc.TraceID) == 32 && len(sc.SpanID) == 16 &&
+		sc.TraceID != zeroTraceID && sc.SpanID != zeroSpanID
+}
+
+const (
+	zeroTraceID = "00000000000000000000000000000000"
+	zeroSpanID  = "0000000000000000"
+)
+
+// SpanData is a finished span as handed to an Exporter.
+type SpanData struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  SpanContext
+	ParentSpanID string
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]string
+	// Error is the status message of a span that failed; empty when it didn't
+	Error string
+}
+
+// Exporter receives spans as they end. It must not block the caller for long.
+type Exporter interface {
+	ExportSpan(span SpanData)
+}
+
+// Provider starts spans and sends them to its exporter once they end.
+type Provider struct {
+	exporter Exporter
+}
+
+func NewProvider(exporter Exporter) *Provider {
+	return &Provider{exporter: exporter}
+}
+
+var global atomic.Pointer[Provider]
+
+// SetProvider makes p the provider Start uses. A nil p turns tracing off, which
+// is the default: Start then returns no span and costs next to nothing.
+func SetProvider(p *Provider) {
+	global.Store(p)
+}
+
+// Span is an operation being timed. A nil *Span is valid and does nothing, so
+// callers needn't check whether tracing is on.
+type Span struct {
+	provider *Provider
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// Start begins a span named name as a child of the span in ctx, or of the
+// remote span Extract put there, or as the root of a new trace. A child keeps
+// its parent's sampling decision; a new trace is sampled. The returned context
+// carries the new span.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	p := global.Load()
+	if p == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	sc := SpanContext{TraceID: parent.TraceID, SpanID: newID(8), Sampled: parent.Sampled}
+	if !parent.IsValid() {
+		sc.TraceID = newID(16)
+		sc.Sampled = true
+	}
+
+	span := &Span{
+		provider: p,
+		data: SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			Start:       time.Now(),
+			Attributes:  map[string]string{},
+		},
+	}
+	if parent.IsValid() {
+		span.data.ParentSpanID = parent.SpanID
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SpanContextFromContext returns the context of the span in ctx, falling back
+// to a remote parent put there by Extract.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok && span != nil {
+		return span.data.SpanContext
+	}
+	if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		return sc
+	}
+	return SpanContext{}
+}
+
+// SetAttribute records a key/value pair on the span.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes[key] = value
+}
+
+// RecordError marks the span as failed with err's message. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = err.Error()
+}
+
+// End stops the span's clock and exports it, unless the trace isn't sampled.
+// Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.provider.exporter != nil && data.SpanContext.Sampled {
+		s.provider.exporter.ExportSpan(data)
+	}
+}
+
+func newID(bytes int) string {
+	id := make([]byte, bytes)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+)
+
+const queueSize = 100
+
+// WebhookNotifier POSTs task events and sync run summaries to a URL from a
+// background worker so requests and sync passes never wait on delivery. Failed
+// deliveries are retried with backoff.
+type WebhookNotifier struct {
+	url        string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+
+	events chan delivery
+	wg     sync.WaitGroup
+}
+
+// delivery is one queued webhook payload; name describes it in log lines.
+type delivery struct {
+	name    string
+	payload interface{}
+}
+
+func NewWebhookNotifier(url string, httpClient *http.Client, maxRetries int) *WebhookNotifier {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 5 * time.Second}
+	}
+
+	n := &WebhookNotifier{
+		url:        url,
+		httpClient: httpClient,
+		maxRetries: maxRetries,
+		backoff:    500 * time.Millisecond,
+		events:     make(chan delivery, queueSize),
+	}
+
+	n.wg.Add(1)
+	go n.run()
+	return n
+}
+
+// Notify queues an event for delivery. When the queue is full the event is dropped.
+func (n *WebhookNotifier) Notify(event models.TaskEventType, task *models.Task) {
+	n.enqueue(delivery{
+		name:    fmt.Sprintf("%s event for task %s", event, task.ID),
+		payload: models.TaskEvent{Event: event, Task: task},
+	})
+}
+
+// NotifySyncRun queues a sync run summary for delivery. When the queue is full
+// the summary is dropped.
+func (n *WebhookNotifier) NotifySyncRun(summary models.SyncRunSummary) {
+	n.enqueue(delivery{name: "sync run summary", payload: summary})
+}
+
+func (n *WebhookNotifier) enqueue(d delivery) {
+	select {
+	case n.events <- d:
+	default:
+		log.Printf("Webhook queue full, dropping %s", d.name)
+	}
+}
+
+// Close stops accepting events and waits for queued ones to be delivered.
+func (n *WebhookNotifier) Close() {
+	close(n.events)
+	n.wg.Wait()
+}
+
+func (n *WebhookNotifier) run() {
+	defer n.wg.Done()
+
+	for d := range n.events {
+		if err := n.deliver(d.payload); err != nil {
+			log.Printf("Failed to deliver webhook for %s: %v", d.name, err)
+		}
+	}
+}
+
+func (n *WebhookNotifier) deliver(event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= n.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(n.backoff * time.Duration(1<<(attempt-1)))
+		}
+
+		resp, err := n.httpClient.Post(n.url, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return lastErr
+}
@@ -1,9 +1,20 @@
 package tests
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/config"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,7 +26,7 @@ func TestDatabaseSetup(t *testing.T) {
 	defer db.Close()
 
 	// Check tables exist
-	tables := []string{"tasks", "sync_queue"}
+	tables := []string{"tasks", "sync_queue", "tags", "task_tags"}
 	for _, table := range tables {
 		var count int
 		err = db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?", table).Scan(&count)
@@ -29,3 +40,315 @@ func TestDatabaseSetup(t *testing.T) {
 
 	t.Log("Database setup successful - all tables created and accessible")
 }
+
+func TestDatabaseMaintenance(t *testing.T) {
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "tasks.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	cfg := &config.Config{SyncBatchSize: 5, MaxRetries: 3}
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+
+	description := strings.Repeat("padding ", 200)
+	for i := 0; i < 200; i++ {
+		_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Task %d", i), Description: &description})
+		require.NoError(t, err)
+	}
+
+	_, err = db.Exec("DELETE FROM sync_queue")
+	require.NoError(t, err)
+	_, err = db.Exec("DELETE FROM tasks")
+	require.NoError(t, err)
+
+	result, err := db.Maintenance()
+	require.NoError(t, err)
+	assert.Greater(t, result.PagesBefore, 0)
+	assert.Greater(t, result.PagesReclaimed, 0)
+	assert.Equal(t, result.PagesBefore-result.PagesAfter, result.PagesReclaimed)
+
+	// Running it again is harmless
+	_, err = db.Maintenance()
+	require.NoError(t, err)
+}
+
+func TestDatabaseBackup_KeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.NewSQLiteDB(filepath.Join(dir, "tasks.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	backupDir := filepath.Join(dir, "backups")
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := db.Backup(backupDir, 2)
+		require.NoError(t, err)
+		paths = append(paths, path)
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.NoFileExists(t, paths[0])
+	assert.FileExists(t, paths[1])
+	assert.FileExists(t, paths[2])
+
+	memory, err := database.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer memory.Close()
+
+	_, err = memory.Backup(backupDir, 2)
+	assert.ErrorIs(t, err, database.ErrBackupUnsupported)
+}
+
+func TestMigrate_AppliesEachVersionOnce(t *testing.T) {
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "tasks.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	version, err := database.SchemaVersion(db)
+	require.NoError(t, err)
+	require.Greater(t, version, 0)
+
+	// Every version up to the latest is recorded exactly once
+	var count, distinct int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT version) FROM schema_migrations`).Scan(&count, &distinct))
+	assert.Equal(t, version, count)
+	assert.Equal(t, version, distinct)
+
+	var firstApplied string
+	require.NoError(t, db.QueryRow(`SELECT applied_at FROM schema_migrations WHERE version = 1`).Scan(&firstApplied))
+
+	require.NoError(t, database.Migrate(db))
+	require.NoError(t, database.Migrate(db))
+
+	again, err := database.SchemaVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, version, again)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count))
+	assert.Equal(t, version, count)
+
+	var stillApplied string
+	require.NoError(t, db.QueryRow(`SELECT applied_at FROM schema_migrations WHERE version = 1`).Scan(&stillApplied))
+	assert.Equal(t, firstApplied, stillApplied)
+}
+
+func TestMigrate_AdoptsUnversionedDatabase(t *testing.T) {
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "tasks.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO tasks (id, title) VALUES ('kept', 'Kept')`)
+	require.NoError(t, err)
+
+	// A database from before versioning has the schema but no record of it
+	_, err = db.Exec(`DROP TABLE schema_migrations`)
+	require.NoError(t, err)
+
+	require.NoError(t, database.Migrate(db))
+
+	version, err := database.SchemaVersion(db)
+	require.NoError(t, err)
+	assert.Greater(t, version, 0)
+
+	var title string
+	require.NoError(t, db.QueryRow(`SELECT title FROM tasks WHERE id = 'kept'`).Scan(&title))
+	assert.Equal(t, "Kept", title)
+}
+
+func TestDatabase_StoresTimestampsAsUTCText(t *testing.T) {
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "tasks.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	at := time.Date(2024, 3, 1, 17, 0, 0, 120000000, zone)
+	_, err = db.Exec(`INSERT INTO tasks (id, title, created_at, updated_at) VALUES ('t', 'T', ?, ?)`, at, at)
+	require.NoError(t, err)
+
+	var text string
+	require.NoError(t, db.QueryRow(`SELECT updated_at || '' FROM tasks WHERE id = 't'`).Scan(&text))
+	assert.Equal(t, "2024-03-01T12:00:00.120000000Z", text)
+
+	var stored time.Time
+	require.NoError(t, db.QueryRow(`SELECT updated_at FROM tasks WHERE id = 't'`).Scan(&stored))
+	assert.True(t, stored.Equal(at))
+
+	parsed, err := database.ParseTimestamp(text)
+	require.NoError(t, err)
+	assert.True(t, parsed.Equal(at))
+}
+
+func TestMigrate_NormalizesLegacyTimestamps(t *testing.T) {
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "tasks.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// SQLite's CURRENT_TIMESTAMP text, and go-sqlite3's own format for a time
+	// in another zone
+	_, err = db.Exec(`INSERT INTO tasks (id, title, created_at, updated_at, due_date)
+        VALUES ('legacy', 'Legacy', '2024-01-01 10:00:00', '2024-01-01 12:00:00.5+02:00', NULL)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`DELETE FROM schema_migrations WHERE version >= 22`)
+	require.NoError(t, err)
+
+	require.NoError(t, database.Migrate(db))
+
+	var created, updated string
+	var dueDate *string
+	require.NoError(t, db.QueryRow(`SELECT created_at || '', updated_at || '', due_date FROM tasks WHERE id = 'legacy'`).
+		Scan(&created, &updated, &dueDate))
+	assert.Equal(t, "2024-01-01T10:00:00.000000000Z", created)
+	assert.Equal(t, "2024-01-01T10:00:00.500000000Z", updated)
+	assert.Nil(t, dueDate)
+}
+
+func TestDatabase_InMemoryIsolation(t *testing.T) {
+	t.Run("each :memory: database is its own", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			title := fmt.Sprintf("Only in database %d", i)
+			t.Run(title, func(t *testing.T) {
+				t.Parallel()
+				taskService, _, _, cleanup := setupTestServices()
+				defer cleanup()
+
+				for j := 0; j < 20; j++ {
+					_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("%s #%d", title, j)})
+					require.NoError(t, err)
+				}
+
+				tasks, err := taskService.GetAllTasks()
+				require.NoError(t, err)
+				assert.Len(t, tasks, 20)
+				for _, task := range tasks {
+					assert.True(t, strings.HasPrefix(task.Title, title), task.Title)
+				}
+			})
+		}
+	})
+
+	t.Run("named databases are shared by name", func(t *testing.T) {
+		first, err := database.NewSQLiteDBNamed("isolation-test")
+		require.NoError(t, err)
+		defer first.Close()
+		second, err := database.NewSQLiteDBNamed("isolation-test")
+		require.NoError(t, err)
+		defer second.Close()
+		other, err := database.NewSQLiteDBNamed("isolation-test-other")
+		require.NoError(t, err)
+		defer other.Close()
+
+		_, err = first.Exec(`INSERT INTO tags (name) VALUES ('shared')`)
+		require.NoError(t, err)
+
+		var count int
+		require.NoError(t, second.QueryRow(`SELECT COUNT(*) FROM tags WHERE name = 'shared'`).Scan(&count))
+		assert.Equal(t, 1, count)
+		require.NoError(t, other.QueryRow(`SELECT COUNT(*) FROM tags WHERE name = 'shared'`).Scan(&count))
+		assert.Equal(t, 0, count)
+	})
+}
+
+// spyReader records the queries sent through it before passing them on.
+type spyReader struct {
+	database.Querier
+
+	mu      sync.Mutex
+	queries []string
+}
+
+func (r *spyReader) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	r.record(query)
+	return r.Querier.QueryContext(ctx, query, args...)
+}
+
+func (r *spyReader) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	r.record(query)
+	return r.Querier.QueryRowContext(ctx, query, args...)
+}
+
+func (r *spyReader) record(query string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = append(r.queries, query)
+}
+
+func (r *spyReader) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.queries)
+}
+
+func TestDatabase_ReadsUseReader(t *testing.T) {
+	taskService, _, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	spy := &spyReader{Querier: db.Writer()}
+	db.SetReader(spy)
+
+	// Mutations go to the primary
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Replicated"})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(task.ID))
+	assert.Zero(t, spy.count())
+
+	task, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Read back"})
+	require.NoError(t, err)
+
+	reads := []struct {
+		name string
+		read func() error
+	}{
+		{"GetAllTasks", func() error { _, err := taskService.GetAllTasks(); return err }},
+		{"ListTasks", func() error { _, err := taskService.ListTasks(&models.TaskFilter{Sort: "title"}); return err }},
+		{"GetTaskByID", func() error { _, err := taskService.GetTaskByID(task.ID); return err }},
+		{"GetTaskPage", func() error { _, err := taskService.GetTaskPage("", 10); return err }},
+		{"GetStats", func() error { _, err := taskService.GetStats(); return err }},
+	}
+	for _, read := range reads {
+		before := spy.count()
+		require.NoError(t, read.read(), read.name)
+		assert.Greater(t, spy.count(), before, "%s should query the reader", read.name)
+	}
+
+	db.SetReader(nil)
+	before := spy.count()
+	_, err = taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, before, spy.count(), "reads return to the primary")
+}
+
+func TestDatabase_OpenReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db, err := database.NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.OpenReader(path, database.PoolConfig{}))
+	assert.NotEqual(t, db.Writer(), db.Reader())
+
+	syncService := services.NewSyncService(db, &config.Config{SyncBatchSize: 5, MaxRetries: 3})
+	taskService := services.NewTaskService(db, syncService)
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Seen by the reader"})
+	require.NoError(t, err)
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Seen by the reader", stored.Title)
+
+	// The reader refuses writes
+	rows, err := db.Reader().QueryContext(context.Background(), `DELETE FROM tasks RETURNING id`)
+	if err == nil {
+		for rows.Next() {
+		}
+		err = rows.Err()
+		rows.Close()
+	}
+	assert.Error(t, err)
+	_, err = taskService.GetTaskByID(task.ID)
+	assert.NoError(t, err)
+
+	assert.Error(t, db.OpenReader(":memory:", database.PoolConfig{}))
+}
@@ -2,22 +2,39 @@ package tests
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/config"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/handlers"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/middleware"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// testMaxBodyBytes caps request bodies in the test router.
+const testMaxBodyBytes = 64 << 10
+
+// testRequestTimeout bounds each request in the test router.
+const testRequestTimeout = 5 * time.Second
+
 func setupTestApp() (*gin.Engine, func()) {
 	// Create temporary database
 	cfg := &config.Config{
@@ -35,21 +52,75 @@ func setupTestApp() (*gin.Engine, func()) {
 	taskService := services.NewTaskService(db, syncService)
 	taskHandler := handlers.NewTaskHandler(taskService)
 	syncHandler := handlers.NewSyncHandler(syncService)
+	healthHandler := handlers.NewHealthHandler(db, syncService)
+	adminHandler := handlers.NewAdminHandler(db)
 
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.Gzip())
+	router.Use(middleware.Recovery(log.New(io.Discard, "", 0)))
 
 	api := router.Group("/api")
+	api.Use(middleware.UserContext(testUserKeys))
+	api.Use(middleware.BodyLimit(testMaxBodyBytes))
+	api.Use(middleware.Timeout(testRequestTimeout))
+	api.Use(middleware.ResponseTimeFormat())
 	{
 		api.GET("/tasks", taskHandler.GetTasks)
+		api.GET("/tasks/stats", taskHandler.GetTaskStats)
+		api.GET("/tasks/export", taskHandler.ExportTasks)
+		api.GET("/tasks/changes", taskHandler.GetChanges)
+		api.GET("/tasks/by-server-id/:server_id", taskHandler.GetTaskByServerID)
 		api.GET("/tasks/:id", taskHandler.GetTask)
 		api.POST("/tasks", taskHandler.CreateTask)
+		api.POST("/tasks/import", taskHandler.ImportTasks)
+		api.POST("/tasks/bulk-complete", taskHandler.BulkComplete)
+		api.POST("/tasks/bulk-delete", taskHandler.BulkDelete)
+		api.POST("/tasks/batch", taskHandler.BatchCreateTasks)
 		api.PUT("/tasks/:id", taskHandler.UpdateTask)
+		api.PATCH("/tasks/:id", taskHandler.PatchTask)
 		api.DELETE("/tasks/:id", taskHandler.DeleteTask)
+		api.GET("/tasks/:id/sync-history", taskHandler.GetSyncHistory)
+		api.POST("/tasks/:id/resync", taskHandler.ResyncTask)
+		api.POST("/tasks/:id/restore", taskHandler.RestoreTask)
+		api.POST("/tasks/:id/archive", taskHandler.ArchiveTask)
+		api.POST("/tasks/:id/unarchive", taskHandler.UnarchiveTask)
+		api.GET("/tasks/:id/dependencies", taskHandler.GetDependencies)
+		api.POST("/tasks/:id/dependencies", taskHandler.AddDependency)
+		api.DELETE("/tasks/:id/dependencies/:depends_on_id", taskHandler.RemoveDependency)
+		api.GET("/tasks/:id/comments", taskHandler.GetComments)
+		api.POST("/tasks/:id/comments", taskHandler.AddComment)
+		api.GET("/activity", taskHandler.GetActivity)
+		api.GET("/sync/queue", syncHandler.GetSyncQueue)
+		api.GET("/sync/queue/:id", syncHandler.GetQueueItem)
 		api.POST("/sync/trigger", syncHandler.TriggerSync)
 		api.GET("/sync/status", syncHandler.GetSyncStatus)
+		api.GET("/sync/plan", syncHandler.GetSyncPlan)
+		api.GET("/sync/conflicts", syncHandler.GetConflicts)
+		api.POST("/sync/conflicts/:id/resolve", syncHandler.ResolveConflict)
+		api.GET("/sync/dead-letter", syncHandler.GetDeadLetters)
+		api.GET("/sync/tasks", syncHandler.GetSyncTasks)
+		api.GET("/sync/runs", syncHandler.GetSyncRuns)
+		api.GET("/sync/attempts", syncHandler.GetSyncAttempts)
+	}
+
+	operator := api.Group("", middleware.AdminAuth(testAdminKey))
+	{
+		operator.POST("/tasks/purge", taskHandler.PurgeDeletedTasks)
+		operator.POST("/sync/reset", syncHandler.ResetQueue)
+		operator.POST("/sync/pause", syncHandler.PauseSync)
+		operator.POST("/sync/resume", syncHandler.ResumeSync)
+		operator.POST("/sync/pull", syncHandler.PullSync)
+		operator.POST("/admin/maintenance", adminHandler.RunMaintenance)
+		operator.POST("/admin/backup", adminHandler.RunBackup)
 	}
 
+	router.GET("/health", healthHandler.Health)
+	router.GET("/health/live", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
+
 	cleanup := func() {
 		db.Close()
 	}
@@ -78,9 +149,7 @@ func TestCreateTask(t *testing.T) {
 	var response map[string]interface{}
 	json.Unmarshal(w.Body.Bytes(), &response)
 
-	assert.Contains(t, response, "task")
-	taskData := response["task"].(map[string]interface{})
-	assert.Equal(t, "Test Task", taskData["title"])
+	assert.Equal(t, "Test Task", response["title"])
 }
 
 func TestGetTasks(t *testing.T) {
@@ -104,11 +173,9 @@ func TestGetTasks(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
+	var tasks []interface{}
+	json.Unmarshal(w.Body.Bytes(), &tasks)
 
-	assert.Contains(t, response, "tasks")
-	tasks := response["tasks"].([]interface{})
 	assert.Len(t, tasks, 1)
 }
 
@@ -128,7 +195,2487 @@ func TestSyncStatus(t *testing.T) {
 	assert.Contains(t, response, "sync_status")
 }
 
-func TestMain(m *testing.M) {
-	code := m.Run()
-	os.Exit(code)
+func TestTaskHandlers_NotFound(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.UpdateTaskRequest{Title: stringPtr("Updated")})
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/api/tasks/non-existent-id", nil),
+		httptest.NewRequest("PUT", "/api/tasks/non-existent-id", bytes.NewBuffer(body)),
+		httptest.NewRequest("DELETE", "/api/tasks/non-existent-id", nil),
+		httptest.NewRequest("POST", "/api/tasks/non-existent-id/resync", nil),
+	}
+
+	for _, req := range requests {
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, "%s %s", req.Method, req.URL)
+
+		apiErr := decodeAPIError(t, w.Body.Bytes())
+		assert.Equal(t, models.ErrorCodeTaskNotFound, apiErr.Code, "%s %s", req.Method, req.URL)
+		assert.Equal(t, "task not found", apiErr.Message)
+		assert.NotEmpty(t, apiErr.RequestID)
+	}
+}
+
+func TestTaskHandlers_DatabaseFailure(t *testing.T) {
+	router, cleanup := setupTestApp()
+
+	// Close the database so every query fails with a real error
+	cleanup()
+
+	body, _ := json.Marshal(models.UpdateTaskRequest{Title: stringPtr("Updated")})
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/api/tasks/some-id", nil),
+		httptest.NewRequest("PUT", "/api/tasks/some-id", bytes.NewBuffer(body)),
+		httptest.NewRequest("DELETE", "/api/tasks/some-id", nil),
+	}
+
+	for _, req := range requests {
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code, "%s %s", req.Method, req.URL)
+	}
+}
+
+func TestCreateTask_TitleValidation(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	cases := []struct {
+		title      string
+		wantStatus int
+		wantTitle  string
+	}{
+		{title: "   \t\n ", wantStatus: http.StatusBadRequest},
+		{title: strings.Repeat("a", models.MaxTitleLength+1), wantStatus: http.StatusBadRequest},
+		{title: strings.Repeat("a", models.MaxTitleLength), wantStatus: http.StatusCreated, wantTitle: strings.Repeat("a", models.MaxTitleLength)},
+		{title: "  Needs trimming  ", wantStatus: http.StatusCreated, wantTitle: "Needs trimming"},
+	}
+
+	for _, tc := range cases {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: tc.title})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.wantStatus, w.Code)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if tc.wantStatus == http.StatusCreated {
+			assert.Equal(t, tc.wantTitle, response["title"])
+		} else {
+			assert.NotEmpty(t, response["error"])
+		}
+	}
+}
+
+func TestUpdateTask_TitleValidation(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Original"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	url := "/api/tasks/" + created["id"].(string)
+
+	for _, title := range []string{"   ", strings.Repeat("b", models.MaxTitleLength+1)} {
+		body, _ = json.Marshal(models.UpdateTaskRequest{Title: stringPtr(title)})
+		req, _ = http.NewRequest("PUT", url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+
+	body, _ = json.Marshal(models.UpdateTaskRequest{Title: stringPtr("  Trimmed  ")})
+	req, _ = http.NewRequest("PUT", url, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.Equal(t, "Trimmed", updated["title"])
+}
+
+func TestGetTasks_UpdatedWindowValidation(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	badURLs := []string{
+		"/api/tasks?updated_after=yesterday",
+		"/api/tasks?updated_before=2024-01-10",
+		"/api/tasks?updated_after=2024-01-10T12:00:00Z&updated_before=2024-01-10T10:00:00Z",
+		"/api/tasks?updated_after=2024-01-10T10:00:00Z&updated_before=2024-01-10T10:00:00Z",
+	}
+	for _, url := range badURLs {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/tasks?updated_after=2024-01-10T10:00:00Z&updated_before=2024-01-10T16:00:00%2B05:30", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetTasks_FilterByTag(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	for _, task := range []models.CreateTaskRequest{
+		{Title: "Work task", Tags: []string{"work"}},
+		{Title: "Home task", Tags: []string{"home"}},
+	} {
+		body, _ := json.Marshal(task)
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/tasks?tag=work", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tasks []map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &tasks)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "Work task", tasks[0]["title"])
+	assert.Equal(t, []interface{}{"work"}, tasks[0]["tags"])
+
+	// Blank tags are rejected
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Bad tags", Tags: []string{"  "}})
+	req, _ = http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetTasks_Sort(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	priorities := map[string]int{"banana": 2, "Cherry": 0, "apple": 1}
+	for _, title := range []string{"banana", "Cherry", "apple"} {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: title, Priority: priorities[title]})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	titles := func(url string) []string {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, url)
+
+		var tasks []models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		var out []string
+		for _, task := range tasks {
+			out = append(out, task.Title)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"apple", "banana", "Cherry"}, titles("/api/tasks?sort=title:asc"))
+	assert.Equal(t, []string{"apple", "banana", "Cherry"}, titles("/api/tasks?sort=title"))
+	assert.Equal(t, []string{"Cherry", "banana", "apple"}, titles("/api/tasks?sort=title:desc"))
+	assert.Equal(t, []string{"banana", "Cherry", "apple"}, titles("/api/tasks?sort=created_at:asc"))
+	assert.Equal(t, []string{"banana", "apple", "Cherry"}, titles("/api/tasks?sort=priority:desc"))
+	assert.Equal(t, []string{"Cherry", "apple", "banana"}, titles("/api/tasks?sort=priority"))
+
+	for _, sort := range []string{"due_date", "title:sideways", "title;DROP TABLE tasks", "description:asc"} {
+		req, _ := http.NewRequest("GET", "/api/tasks?sort="+url.QueryEscape(sort), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, sort)
+		assert.Equal(t, models.ErrorCodeValidationFailed, decodeAPIError(t, w.Body.Bytes()).Code, sort)
+	}
+
+	// Keyset pages have a fixed order
+	req, _ := http.NewRequest("GET", "/api/tasks?limit=2&sort=title", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetTasks_CursorPagination(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: "Paged task"})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	seen := make(map[string]bool)
+	url := "/api/tasks?limit=2"
+	for {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var page struct {
+			Tasks      []models.Task `json:"tasks"`
+			NextCursor string        `json:"next_cursor"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		for _, task := range page.Tasks {
+			assert.False(t, seen[task.ID])
+			seen[task.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		url = "/api/tasks?limit=2&cursor=" + page.NextCursor
+	}
+	assert.Len(t, seen, 5)
+
+	for _, url := range []string{"/api/tasks?cursor=garbage", "/api/tasks?limit=0", "/api/tasks?cursor=&tag=work"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestGetTasks_PaginationHeaders(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: fmt.Sprintf("Paged task %d", i)})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	linkPattern := regexp.MustCompile(`<([^>]+)>; rel="(\w+)"`)
+	fetch := func(url string) (ids []string, links map[string]string) {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "5", w.Header().Get("X-Total-Count"))
+		assert.Equal(t, "2", w.Header().Get("X-Page-Limit"))
+
+		var page struct {
+			Tasks []models.Task `json:"tasks"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+
+		links = make(map[string]string)
+		for _, match := range linkPattern.FindAllStringSubmatch(w.Header().Get("Link"), -1) {
+			links[match[2]] = match[1]
+		}
+		return ids, links
+	}
+
+	first, links := fetch("/api/tasks?limit=2")
+	assert.Len(t, first, 2)
+	assert.NotContains(t, links, "prev")
+	require.Contains(t, links, "next")
+
+	second, links := fetch(links["next"])
+	assert.Len(t, second, 2)
+	require.Contains(t, links, "next")
+	assert.Equal(t, "/api/tasks?limit=2", links["prev"])
+
+	last, links := fetch(links["next"])
+	assert.Len(t, last, 1)
+	assert.NotContains(t, links, "next")
+	require.Contains(t, links, "prev")
+
+	// Following prev from the last page returns the second page
+	back, _ := fetch(links["prev"])
+	assert.Equal(t, second, back)
+
+	// A page fetched by cursor alone still links with the limit and keeps the
+	// request's other parameters, so prev to the first page stays paginated
+	req, _ := http.NewRequest("GET", "/api/tasks?limit=4", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var page struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+
+	req, _ = http.NewRequest("GET", "/api/tasks?fields=id,title&cursor="+url.QueryEscape(page.NextCursor), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	match := linkPattern.FindStringSubmatch(w.Header().Get("Link"))
+	require.NotNil(t, match)
+	assert.Equal(t, "prev", match[2])
+	prev, err := url.Parse(match[1])
+	require.NoError(t, err)
+	assert.Equal(t, "50", prev.Query().Get("limit"))
+	assert.Equal(t, "id,title", prev.Query().Get("fields"))
+	assert.False(t, prev.Query().Has("cursor"))
+}
+
+func TestTriggerSync_Overrides(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: "Queued task"})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	untouched := func() int {
+		req, _ := http.NewRequest("GET", "/api/sync/queue", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			SyncQueue []models.SyncQueueItem `json:"sync_queue"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		count := 0
+		for _, item := range response.SyncQueue {
+			if item.RetryCount == 0 {
+				count++
+			}
+		}
+		return count
+	}
+
+	// The override processes a single item, whether or not it succeeds
+	req, _ := http.NewRequest("POST", "/api/sync/trigger", strings.NewReader(`{"batch_size": 1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, untouched())
+
+	// Without a body the configured batch size covers the rest
+	req, _ = http.NewRequest("POST", "/api/sync/trigger", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 0, untouched())
+
+	for _, body := range []string{`{"batch_size": 0}`, `{"max_retries": -1}`, `{"batch_size": 100000}`, `{"max_retries": 1000}`, `{"task_ids": [""]}`, `not json`} {
+		req, _ := http.NewRequest("POST", "/api/sync/trigger", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestTriggerSync_TaskIDs(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var ids []string
+	for _, title := range []string{"Targeted", "Left queued"} {
+		w := send("POST", "/api/tasks", `{"title": "`+title+`"}`)
+		require.Equal(t, http.StatusCreated, w.Code)
+		var task models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+		ids = append(ids, task.ID)
+	}
+
+	w := send("POST", "/api/sync/trigger", `{"task_ids": ["`+ids[0]+`"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = send("GET", "/api/sync/queue", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		SyncQueue []models.SyncQueueItem `json:"sync_queue"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	for _, item := range response.SyncQueue {
+		if item.TaskID == ids[1] {
+			assert.Zero(t, item.RetryCount, "untargeted items are not attempted")
+		}
+	}
+	assert.NotEmpty(t, response.SyncQueue)
+
+	w = send("POST", "/api/sync/trigger", `{"task_ids": ["`+ids[1]+`", "missing"]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, models.ErrorCodeTaskNotFound, decodeAPIError(t, w.Body.Bytes()).Code)
+
+	// Another user's task is reported missing and left alone
+	req, _ := http.NewRequest("POST", "/api/sync/trigger", strings.NewReader(`{"task_ids": ["`+ids[1]+`"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	asUser(req, "bob")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, decodeAPIError(t, w.Body.Bytes()).Message, ids[1])
+
+	w = send("GET", "/api/sync/queue", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	stillQueued := false
+	for _, item := range response.SyncQueue {
+		if item.TaskID == ids[1] {
+			stillQueued = true
+			assert.Zero(t, item.RetryCount, "bob's request didn't push the task")
+		}
+	}
+	assert.True(t, stillQueued)
+}
+
+func TestTriggerSync_DetachedFromRequest(t *testing.T) {
+	db, err := database.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, &config.Config{SyncBatchSize: 10, MaxRetries: 3})
+	taskService := services.NewTaskService(db, syncService)
+	fake := &fakeSyncClient{delay: 100 * time.Millisecond}
+	syncService.SetClient(fake)
+	syncHandler := handlers.NewSyncHandler(syncService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Timeout(20 * time.Millisecond))
+	router.POST("/api/sync/trigger", syncHandler.TriggerSync)
+
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Slow to sync"})
+	require.NoError(t, err)
+
+	// The request times out, but the pass it started still finishes
+	req, _ := http.NewRequest("POST", "/api/sync/trigger", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, items, "the item synced after the request gave up")
+
+	// The pass's own timeout still bounds it
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Never answers"})
+	require.NoError(t, err)
+	fake.delay = 0
+	fake.blocking = true
+	syncHandler.SetPassTimeout(20 * time.Millisecond)
+	router = gin.New()
+	router.POST("/api/sync/trigger", syncHandler.TriggerSync)
+
+	req, _ = http.NewRequest("POST", "/api/sync/trigger", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	items, err = syncService.ListSyncQueue(&models.SyncQueueFilter{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, 1, items[0].RetryCount, "the timed-out item is retried later")
+}
+
+func TestGetQueueItem(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Queued task"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	asUser(req, "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+
+	req, _ = http.NewRequest("GET", "/api/sync/queue", nil)
+	asUser(req, "alice")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var queue struct {
+		SyncQueue []models.SyncQueueItem `json:"sync_queue"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queue))
+	require.Len(t, queue.SyncQueue, 1)
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/api/sync/queue/%d", queue.SyncQueue[0].ID), nil)
+	asUser(req, "alice")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var item struct {
+		ID           int         `json:"id"`
+		TaskID       string      `json:"task_id"`
+		RetryCount   int         `json:"retry_count"`
+		LastAttempt  *time.Time  `json:"last_attempt"`
+		ErrorMessage *string     `json:"error_message"`
+		TaskData     models.Task `json:"task_data"`
+		DeadLettered bool        `json:"dead_lettered"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &item))
+	assert.Equal(t, queue.SyncQueue[0].ID, item.ID)
+	assert.Equal(t, created.ID, item.TaskID)
+	assert.Zero(t, item.RetryCount)
+	assert.Nil(t, item.LastAttempt)
+	assert.Nil(t, item.ErrorMessage)
+	assert.Equal(t, "Queued task", item.TaskData.Title)
+	assert.False(t, item.DeadLettered)
+
+	// Another user's item looks the same as one that doesn't exist
+	for _, lookup := range []struct {
+		id   int
+		user string
+	}{
+		{queue.SyncQueue[0].ID + 100, "alice"},
+		{queue.SyncQueue[0].ID, "bob"},
+		{queue.SyncQueue[0].ID, ""},
+	} {
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/api/sync/queue/%d", lookup.id), nil)
+		asUser(req, lookup.user)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, lookup.user)
+		assert.Equal(t, models.ErrorCodeQueueItemNotFound, decodeAPIError(t, w.Body.Bytes()).Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/sync/queue/abc", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetSyncQueue_Filters(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Queued task"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	req, _ = http.NewRequest("DELETE", "/api/tasks/"+created.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	cases := map[string]int{
+		"/api/sync/queue":                       2,
+		"/api/sync/queue?operation_type=delete": 1,
+		"/api/sync/queue?operation_type=update": 0,
+		"/api/sync/queue?min_retries=1":         0,
+		"/api/sync/queue?limit=1":               1,
+	}
+	for url, expected := range cases {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, url)
+
+		var response struct {
+			SyncQueue []models.SyncQueueItem `json:"sync_queue"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Len(t, response.SyncQueue, expected, url)
+	}
+
+	for _, url := range []string{"/api/sync/queue?operation_type=upsert", "/api/sync/queue?min_retries=-1", "/api/sync/queue?limit=0"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+
+	// The error lists every operation type the filter accepts
+	req, _ = http.NewRequest("GET", "/api/sync/queue?operation_type=upsert", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Contains(t, decodeAPIError(t, w.Body.Bytes()).Message, "create, update, delete, restore, archive")
+}
+
+func TestGetSyncQueue_SummaryAndGzip(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Queued task"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/sync/queue?fields=summary", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	var response struct {
+		SyncQueue []map[string]interface{} `json:"sync_queue"`
+	}
+	require.NoError(t, json.NewDecoder(reader).Decode(&response))
+	require.Len(t, response.SyncQueue, 1)
+
+	item := response.SyncQueue[0]
+	assert.NotContains(t, item, "task_data")
+	for _, field := range []string{"id", "task_id", "operation_type", "retry_count", "created_at"} {
+		assert.Contains(t, item, field)
+	}
+
+	// Without Accept-Encoding the full listing comes back uncompressed
+	req, _ = http.NewRequest("GET", "/api/sync/queue", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Body.String(), "task_data")
+
+	req, _ = http.NewRequest("GET", "/api/sync/queue?fields=everything", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetSyncRuns(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Queued task"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/sync/trigger", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/sync/runs?limit=5", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		SyncRuns []models.SyncRun `json:"sync_runs"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.SyncRuns, 1)
+	run := response.SyncRuns[0]
+	assert.Equal(t, 1, run.Processed)
+	assert.Equal(t, 1, run.Succeeded+run.Failed)
+
+	req, _ = http.NewRequest("GET", "/api/sync/runs?limit=0", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetDeadLetters(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Queued task"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/sync/dead-letter?limit=10&task_id=missing", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []interface{}{}, response["dead_letters"])
+	assert.Equal(t, float64(0), response["total"])
+	assert.Equal(t, float64(10), response["limit"])
+
+	req, _ = http.NewRequest("GET", "/api/sync/dead-letter?offset=-1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetSyncTasks(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Waiting to sync"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	counts := map[string]int{"pending": 1, "synced": 0, "error": 0}
+	for status, count := range counts {
+		req, _ = http.NewRequest("GET", "/api/sync/tasks?status="+status, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, status)
+
+		var response struct {
+			Tasks  []map[string]interface{} `json:"tasks"`
+			Status string                   `json:"status"`
+			Total  int                      `json:"total"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, status, response.Status)
+		assert.Equal(t, count, response.Total, status)
+		assert.Len(t, response.Tasks, count, status)
+	}
+
+	for _, url := range []string{"/api/sync/tasks", "/api/sync/tasks?status=stuck", "/api/sync/tasks?status=error&limit=0"} {
+		req, _ = http.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestResetSyncQueue(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Queued task"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// Only an operator may empty everyone's queue
+	req, _ = http.NewRequest("POST", "/api/sync/reset", nil)
+	asUser(req, "alice")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, models.ErrorCodeForbidden, decodeAPIError(t, w.Body.Bytes()).Code)
+
+	req, _ = http.NewRequest("POST", "/api/sync/reset?reset_errors=maybe", nil)
+	asAdmin(req)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/sync/reset?reset_errors=true", nil)
+	asAdmin(req)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/sync/queue", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		SyncQueue []models.SyncQueueItem `json:"sync_queue"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Empty(t, response.SyncQueue)
+}
+
+func TestBulkCompleteTasks(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Bulk"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := created["id"].(string)
+
+	body, _ = json.Marshal(models.BulkCompleteRequest{IDs: []string{id, "missing"}, Completed: boolPtr(true)})
+	req, _ = http.NewRequest("POST", "/api/tasks/bulk-complete", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, float64(1), response["updated"])
+	assert.Equal(t, []interface{}{"missing"}, response["not_found"])
+
+	// completed is required so a missing flag can't silently mean false
+	req, _ = http.NewRequest("POST", "/api/tasks/bulk-complete", bytes.NewBufferString(`{"ids": ["`+id+`"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"completed"`)
+}
+
+func TestBulkDeleteTasks(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	var ids []string
+	for _, title := range []string{"Delete me", "Delete me too"} {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: title})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var created map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		ids = append(ids, created["id"].(string))
+	}
+
+	body, _ := json.Marshal(models.BulkDeleteRequest{IDs: []string{ids[0], "missing", ids[1]}})
+	req, _ := http.NewRequest("POST", "/api/tasks/bulk-delete", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, float64(2), response["deleted"])
+	assert.Equal(t, []interface{}{"missing"}, response["not_found"])
+
+	for _, id := range ids {
+		req, _ = http.NewRequest("GET", "/api/tasks/"+id, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/tasks/bulk-delete", bytes.NewBufferString(`{"ids": []}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetTasks_FieldSelection(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Light"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := created["id"].(string)
+
+	req, _ = http.NewRequest("GET", "/api/tasks/"+id+"?fields=id,title,completed", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+	assert.Equal(t, map[string]interface{}{"id": id, "title": "Light", "completed": false}, task)
+
+	for _, url := range []string{"/api/tasks?fields=id,updated_at", "/api/tasks?fields=id,updated_at&limit=10"} {
+		req, _ = http.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, url)
+
+		var tasks []map[string]interface{}
+		if strings.Contains(url, "limit") {
+			var page struct {
+				Tasks []map[string]interface{} `json:"tasks"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+			tasks = page.Tasks
+		} else {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		}
+		require.Len(t, tasks, 1, url)
+		assert.Len(t, tasks[0], 2, url)
+		assert.Contains(t, tasks[0], "updated_at", url)
+		assert.NotContains(t, tasks[0], "title", url)
+	}
+
+	for _, url := range []string{"/api/tasks?fields=id,password", "/api/tasks/" + id + "?fields=bogus"} {
+		req, _ = http.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestGetTaskByServerID_NotFound(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	req, _ := http.NewRequest("GET", "/api/tasks/by-server-id/srv_missing", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	apiErr := decodeAPIError(t, w.Body.Bytes())
+	assert.Equal(t, models.ErrorCodeTaskNotFound, apiErr.Code)
+	assert.Equal(t, "task not found", apiErr.Message)
+}
+
+func TestGetTask_TimeFormat(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Timestamps"})
+	req, _ := http.NewRequest("POST", "/api/tasks?time_format=epoch_ms", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.IsType(t, float64(0), created["created_at"])
+	id := created["id"].(string)
+
+	req, _ = http.NewRequest("GET", "/api/tasks/"+id, nil)
+	req.Header.Set("X-Time-Format", "epoch_ms")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var fetched map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	assert.IsType(t, float64(0), fetched["updated_at"])
+
+	req, _ = http.NewRequest("GET", "/api/tasks?time_format=unix", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTaskDependencies(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	create := func(title string) string {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: title})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var created map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		return created["id"].(string)
+	}
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	blocker := create("Blocker")
+	blocked := create("Blocked")
+
+	w := send("POST", "/api/tasks/"+blocked+"/dependencies", `{"depends_on_id": "`+blocker+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = send("POST", "/api/tasks/"+blocker+"/dependencies", `{"depends_on_id": "`+blocked+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = send("GET", "/api/tasks/"+blocked+"/dependencies", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Dependencies []models.Task `json:"dependencies"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Dependencies, 1)
+	assert.Equal(t, blocker, response.Dependencies[0].ID)
+
+	w = send("PUT", "/api/tasks/"+blocked, `{"completed": true}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = send("PUT", "/api/tasks/"+blocked+"?force=true", `{"completed": true}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = send("DELETE", "/api/tasks/"+blocked+"/dependencies/"+blocker, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = send("DELETE", "/api/tasks/"+blocked+"/dependencies/"+blocker, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTaskComments(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		asUser(req, "alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/api/tasks", `{"title": "Discussed"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var task models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+
+	w = send("POST", "/api/tasks/"+task.ID+"/comments", `{"body": "  First!  "}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var comment models.TaskComment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &comment))
+	assert.NotEmpty(t, comment.ID)
+	assert.Equal(t, task.ID, comment.TaskID)
+	assert.Equal(t, "First!", comment.Body)
+	assert.Equal(t, "alice", comment.Author)
+
+	w = send("POST", "/api/tasks/"+task.ID+"/comments", `{"body": "   "}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send("POST", "/api/tasks/missing/comments", `{"body": "Hello"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = send("GET", "/api/tasks/"+task.ID+"/comments", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Comments []models.TaskComment `json:"comments"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Comments, 1)
+	assert.Equal(t, comment.ID, response.Comments[0].ID)
+
+	w = send("DELETE", "/api/tasks/"+task.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	w = send("GET", "/api/tasks/"+task.ID+"/comments", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteTask_Hard(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	create := func(title string) string {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: title})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var created map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		return created["id"].(string)
+	}
+	soft := create("Soft")
+	hard := create("Hard")
+
+	for _, url := range []string{"/api/tasks/" + soft, "/api/tasks/" + hard + "?hard=true"} {
+		req, _ := http.NewRequest("DELETE", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, url)
+	}
+
+	// The export includes soft-deleted tasks, so only the hard delete is missing
+	req, _ := http.NewRequest("GET", "/api/tasks/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), soft)
+	assert.NotContains(t, w.Body.String(), hard)
+
+	req, _ = http.NewRequest("DELETE", "/api/tasks/"+soft+"?hard=maybe", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteTask_Twice(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Delete me twice"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := created["id"].(string)
+
+	for i := 0; i < 2; i++ {
+		req, _ = http.NewRequest("DELETE", "/api/tasks/"+id, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, "delete %d", i+1)
+	}
+
+	// Another user's deleted task still looks like it never existed
+	req, _ = http.NewRequest("DELETE", "/api/tasks/"+id, nil)
+	asUser(req, "someone-else")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest("DELETE", "/api/tasks/never-existed", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTaskMutations_IncludeSync(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var created struct {
+		Task     models.Task          `json:"task"`
+		SyncItem models.SyncQueueItem `json:"sync_item"`
+	}
+	w := send("POST", "/api/tasks?include_sync=true", `{"title": "Queued"}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := created.Task.ID
+	assert.Equal(t, "Queued", created.Task.Title)
+	assert.Equal(t, models.OperationTypeCreate, created.SyncItem.OperationType)
+	assert.Equal(t, id, created.SyncItem.TaskID)
+	assert.Positive(t, created.SyncItem.ID)
+
+	var updated struct {
+		Task     models.Task          `json:"task"`
+		SyncItem models.SyncQueueItem `json:"sync_item"`
+	}
+	w = send("PUT", "/api/tasks/"+id+"?include_sync=true", `{"completed": true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	assert.True(t, updated.Task.Completed)
+	assert.Equal(t, models.OperationTypeUpdate, updated.SyncItem.OperationType)
+	assert.Greater(t, updated.SyncItem.ID, created.SyncItem.ID)
+
+	// Without the flag the body is the task itself
+	w = send("PUT", "/api/tasks/"+id, `{"title": "Plain"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var plain map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &plain))
+	assert.Equal(t, id, plain["id"])
+	assert.NotContains(t, plain, "sync_item")
+
+	var deleted struct {
+		Message  string                `json:"message"`
+		SyncItem *models.SyncQueueItem `json:"sync_item"`
+	}
+	w = send("DELETE", "/api/tasks/"+id+"?include_sync=true", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deleted))
+	require.NotNil(t, deleted.SyncItem)
+	assert.Equal(t, models.OperationTypeDelete, deleted.SyncItem.OperationType)
+	assert.Equal(t, id, deleted.SyncItem.TaskID)
+
+	// Deleting again queues nothing
+	w = send("DELETE", "/api/tasks/"+id+"?include_sync=true", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	deleted.SyncItem = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &deleted))
+	assert.Nil(t, deleted.SyncItem)
+
+	w = send("POST", "/api/tasks?include_sync=maybe", `{"title": "Bad flag"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBatchCreateTasks(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	send := func(url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	countTasks := func() int {
+		req, _ := http.NewRequest("GET", "/api/tasks", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var tasks []map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		return len(tasks)
+	}
+
+	const batch = `{"tasks": [{"title": "First"}, {"title": "   "}, {"title": "Third"}]}`
+
+	// Atomic: the invalid entry fails the whole batch
+	w := send("/api/tasks/batch", batch)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	apiErr := decodeAPIError(t, w.Body.Bytes())
+	assert.Equal(t, models.ErrorCodeValidationFailed, apiErr.Code)
+	assert.Equal(t, "tasks[1]", apiErr.Field)
+	assert.Zero(t, countTasks())
+
+	// Partial: the valid entries are created and each entry gets a result
+	w = send("/api/tasks/batch?partial=true", batch)
+	require.Equal(t, http.StatusMultiStatus, w.Code)
+	var resp struct {
+		Results []struct {
+			Index int              `json:"index"`
+			Task  *models.Task     `json:"task"`
+			Error *models.APIError `json:"error"`
+		} `json:"results"`
+		Created int `json:"created"`
+		Failed  int `json:"failed"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Created)
+	assert.Equal(t, 1, resp.Failed)
+	require.Len(t, resp.Results, 3)
+	for i, result := range resp.Results {
+		assert.Equal(t, i, result.Index)
+	}
+	require.NotNil(t, resp.Results[0].Task)
+	assert.Equal(t, "First", resp.Results[0].Task.Title)
+	assert.Nil(t, resp.Results[0].Error)
+	require.NotNil(t, resp.Results[1].Error)
+	assert.Nil(t, resp.Results[1].Task)
+	assert.Equal(t, models.ErrorCodeValidationFailed, resp.Results[1].Error.Code)
+	assert.Equal(t, "tasks[1]", resp.Results[1].Error.Field)
+	require.NotNil(t, resp.Results[2].Task)
+	assert.Equal(t, "Third", resp.Results[2].Task.Title)
+	assert.Equal(t, 2, countTasks())
+
+	// A fully valid batch is created either way
+	w = send("/api/tasks/batch", `{"tasks": [{"title": "Fourth"}, {"title": "Fifth"}]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, 4, countTasks())
+
+	w = send("/api/tasks/batch", `{"tasks": []}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = send("/api/tasks/batch?partial=maybe", batch)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestArchiveTask(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Archive me"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := created["id"].(string)
+
+	listIDs := func(query string) []string {
+		req, _ := http.NewRequest("GET", "/api/tasks"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var tasks []map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		ids := []string{}
+		for _, task := range tasks {
+			ids = append(ids, task["id"].(string))
+		}
+		return ids
+	}
+
+	req, _ = http.NewRequest("POST", "/api/tasks/"+id+"/archive", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var archived map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &archived))
+	assert.Equal(t, true, archived["archived"])
+
+	assert.Empty(t, listIDs(""))
+	assert.Equal(t, []string{id}, listIDs("?include_archived=true"))
+
+	req, _ = http.NewRequest("GET", "/api/tasks?include_archived=yes-please", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/tasks/"+id+"/unarchive", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{id}, listIDs(""))
+
+	req, _ = http.NewRequest("POST", "/api/tasks/missing/archive", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetActivity(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	for _, title := range []string{"One", "Two", "Three"} {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: title})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	type page struct {
+		Activity []struct {
+			Event string      `json:"event"`
+			Task  models.Task `json:"task"`
+		} `json:"activity"`
+		NextCursor string `json:"next_cursor"`
+	}
+
+	req, _ := http.NewRequest("GET", "/api/activity?limit=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var first page
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	require.Len(t, first.Activity, 2)
+	assert.Equal(t, "task.created", first.Activity[0].Event)
+	assert.Equal(t, "Three", first.Activity[0].Task.Title)
+	assert.Equal(t, "Two", first.Activity[1].Task.Title)
+	require.NotEmpty(t, first.NextCursor)
+
+	req, _ = http.NewRequest("GET", "/api/activity?limit=2&cursor="+first.NextCursor, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var second page
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+	require.Len(t, second.Activity, 1)
+	assert.Equal(t, "One", second.Activity[0].Task.Title)
+	assert.Empty(t, second.NextCursor)
+
+	req, _ = http.NewRequest("GET", "/api/activity?cursor=not-a-cursor", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestTaskHandlers_UserScoping(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Alice's task"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	asUser(req, "alice")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var created models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "alice", created.UserID)
+
+	update, _ := json.Marshal(models.UpdateTaskRequest{Title: stringPtr("Hijacked")})
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/api/tasks/"+created.ID, nil),
+		httptest.NewRequest("PUT", "/api/tasks/"+created.ID, bytes.NewBuffer(update)),
+		httptest.NewRequest("DELETE", "/api/tasks/"+created.ID, nil),
+		httptest.NewRequest("GET", "/api/tasks/"+created.ID+"/sync-history", nil),
+	}
+	for _, req := range requests {
+		req.Header.Set("Content-Type", "application/json")
+		asUser(req, "bob")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code, "%s %s", req.Method, req.URL)
+	}
+
+	// Bob's listing is empty and Alice still sees her unchanged task
+	for user, expected := range map[string]int{"bob": 0, "alice": 1, "": 0} {
+		req, _ := http.NewRequest("GET", "/api/tasks", nil)
+		asUser(req, user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var tasks []models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tasks))
+		require.Len(t, tasks, expected, user)
+		if expected > 0 {
+			assert.Equal(t, "Alice's task", tasks[0].Title)
+		}
+	}
+}
+
+func TestSyncHandlers_UserScoping(t *testing.T) {
+	db, err := database.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, &config.Config{SyncBatchSize: 10, MaxRetries: 3})
+	taskService := services.NewTaskService(db, syncService)
+	syncHandler := handlers.NewSyncHandler(syncService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(middleware.UserContext(testUserKeys))
+	api.GET("/sync/queue", syncHandler.GetSyncQueue)
+	api.GET("/sync/conflicts", syncHandler.GetConflicts)
+	api.POST("/sync/conflicts/:id/resolve", syncHandler.ResolveConflict)
+	api.GET("/sync/dead-letter", syncHandler.GetDeadLetters)
+	api.GET("/sync/tasks", syncHandler.GetSyncTasks)
+	api.GET("/sync/attempts", syncHandler.GetSyncAttempts)
+	api.GET("/sync/plan", syncHandler.GetSyncPlan)
+
+	alice, err := taskService.ForUser("alice").CreateTask(&models.CreateTaskRequest{Title: "Alice's task"})
+	require.NoError(t, err)
+	bob, err := taskService.ForUser("bob").CreateTask(&models.CreateTaskRequest{Title: "Bob's task"})
+	require.NoError(t, err)
+
+	// Give Alice's task a dead letter, a sync attempt and a conflict held for review
+	_, err = db.Exec(`UPDATE sync_queue SET retry_count = 3 WHERE task_id = ?`, alice.ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO sync_attempts (task_id, operation_type, success) VALUES (?, 'create', 0)`, alice.ID)
+	require.NoError(t, err)
+	result, err := db.Exec(`INSERT INTO sync_conflicts (task_id, local_data, remote_data, winner, needs_review)
+        VALUES (?, '{}', '{}', 'local', 1)`, alice.ID)
+	require.NoError(t, err)
+	conflictID, err := result.LastInsertId()
+	require.NoError(t, err)
+
+	get := func(url, user string) map[string]json.RawMessage {
+		req, _ := http.NewRequest("GET", url, nil)
+		asUser(req, user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, url)
+		var response map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	count := func(raw json.RawMessage) int {
+		var items []json.RawMessage
+		require.NoError(t, json.Unmarshal(raw, &items))
+		return len(items)
+	}
+
+	// Each user's queue holds only their own create
+	for user, want := range map[string]int{"alice": 1, "bob": 1, "": 0} {
+		var queue []models.SyncQueueItem
+		require.NoError(t, json.Unmarshal(get("/api/sync/queue", user)["sync_queue"], &queue))
+		require.Len(t, queue, want, user)
+		for _, item := range queue {
+			assert.Equal(t, user, item.UserID)
+		}
+	}
+	for user, want := range map[string]int{"alice": 1, "bob": 0, "": 0} {
+		assert.Equal(t, want, count(get("/api/sync/conflicts", user)["conflicts"]), user)
+		assert.Equal(t, want, count(get("/api/sync/dead-letter", user)["dead_letters"]), user)
+		assert.Equal(t, want, count(get("/api/sync/attempts", user)["attempts"]), user)
+	}
+	assert.Equal(t, 1, count(get("/api/sync/tasks?status=pending", "bob")["tasks"]))
+
+	// Alice's only item is dead-lettered, so only Bob has anything to push
+	for user, want := range map[string]int{"alice": 0, "bob": 1, "": 0} {
+		var plan []services.SyncPlanItem
+		require.NoError(t, json.Unmarshal(get("/api/sync/plan", user)["sync_plan"], &plan))
+		require.Len(t, plan, want, user)
+		for _, item := range plan {
+			assert.Equal(t, bob.ID, item.TaskID)
+		}
+	}
+
+	// Bob can't settle Alice's conflict, but she can
+	resolve := func(user string) int {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("/api/sync/conflicts/%d/resolve", conflictID),
+			strings.NewReader(`{"winner": "local"}`))
+		req.Header.Set("Content-Type", "application/json")
+		asUser(req, user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusNotFound, resolve("bob"))
+	assert.Equal(t, http.StatusOK, resolve("alice"))
+}
+
+func TestCreateTask_DuplicateTitleConflict(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:        ":memory:",
+		SyncBatchSize:       10,
+		MaxRetries:          3,
+		EnforceUniqueTitles: true,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	taskHandler := handlers.NewTaskHandler(services.NewTaskService(db, services.NewSyncService(db, cfg)))
+	router := gin.New()
+	router.POST("/api/tasks", taskHandler.CreateTask)
+
+	for _, expected := range []int{http.StatusCreated, http.StatusConflict} {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: "Only once"})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Code)
+	}
+}
+
+func TestCreateTask_QueueFull(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:  ":memory:",
+		SyncBatchSize: 10,
+		MaxRetries:    3,
+		MaxQueueSize:  1,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	taskHandler := handlers.NewTaskHandler(services.NewTaskService(db, services.NewSyncService(db, cfg)))
+	router := gin.New()
+	router.POST("/api/tasks", taskHandler.CreateTask)
+
+	for _, expected := range []int{http.StatusCreated, http.StatusServiceUnavailable} {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: "Backpressure"})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, expected, w.Code)
+		if expected == http.StatusServiceUnavailable {
+			assert.Equal(t, "30", w.Header().Get("Retry-After"))
+			assert.Contains(t, w.Body.String(), "sync queue is full")
+		}
+	}
+}
+
+func TestGetTaskStats(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Counted"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/tasks/stats", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var stats map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, float64(1), stats["active"])
+	assert.Equal(t, float64(0), stats["completed"])
+	assert.Equal(t, float64(1), stats["pending_sync"])
+	assert.Equal(t, float64(0), stats["error_sync"])
+	assert.Equal(t, float64(0), stats["deleted"])
+}
+
+func TestExportTasks(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: "Exported"})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var task models.Task
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+		ids = append(ids, task.ID)
+	}
+
+	// Deleted tasks are part of the export
+	req, _ := http.NewRequest("DELETE", "/api/tasks/"+ids[1], nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/tasks/export", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Equal(t, 3, strings.Count(body, "\n"))
+
+	var exported []models.Task
+	decoder := json.NewDecoder(strings.NewReader(body))
+	for decoder.More() {
+		var task models.Task
+		require.NoError(t, decoder.Decode(&task))
+		exported = append(exported, task)
+	}
+	require.Len(t, exported, 3)
+	for i, task := range exported {
+		assert.Equal(t, ids[i], task.ID)
+	}
+	assert.True(t, exported[1].IsDeleted)
+}
+
+func TestImportTasks(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	task := models.NewTask("From backup", nil)
+	data, _ := json.Marshal(task)
+	body := string(data) + "\nnot json\n"
+
+	req, _ := http.NewRequest("POST", "/api/tasks/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Imported int                        `json:"imported"`
+		Updated  int                        `json:"updated"`
+		Errors   []services.ImportLineError `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Imported)
+	assert.Equal(t, 0, response.Updated)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, 2, response.Errors[0].Line)
+
+	req, _ = http.NewRequest("GET", "/api/tasks/"+task.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPurgeDeletedTasks_RequiresAge(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	for _, url := range []string{"/api/tasks/purge", "/api/tasks/purge?older_than_days=0", "/api/tasks/purge?older_than_days=abc"} {
+		req, _ := http.NewRequest("POST", url, nil)
+		asAdmin(req)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+
+	req, _ := http.NewRequest("POST", "/api/tasks/purge?older_than_days=30", nil)
+	asAdmin(req)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, float64(0), response["purged"])
+}
+
+func TestGetConflicts_Pagination(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	req, _ := http.NewRequest("GET", "/api/sync/conflicts?limit=5&offset=0", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []interface{}{}, response["conflicts"])
+	assert.Equal(t, float64(0), response["total"])
+	assert.Equal(t, float64(5), response["limit"])
+
+	for _, url := range []string{"/api/sync/conflicts?limit=0", "/api/sync/conflicts?offset=-1"} {
+		req, _ = http.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestResolveConflict_Errors(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	cases := []struct {
+		url  string
+		body string
+		want int
+	}{
+		{url: "/api/sync/conflicts/abc/resolve", body: `{"winner":"local"}`, want: http.StatusBadRequest},
+		{url: "/api/sync/conflicts/1/resolve", body: `{}`, want: http.StatusBadRequest},
+		{url: "/api/sync/conflicts/1/resolve", body: `{"winner":"both"}`, want: http.StatusBadRequest},
+		{url: "/api/sync/conflicts/1/resolve", body: `{"winner":"remote"}`, want: http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest("POST", tc.url, bytes.NewBufferString(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.want, w.Code, tc.body)
+	}
+}
+
+func TestHealthReady(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Pending"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest("GET", "/health/ready", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "ok", response["status"])
+	assert.Equal(t, float64(1), response["pending_sync_count"])
+}
+
+func TestHealth_DatabaseClosed(t *testing.T) {
+	router, cleanup := setupTestApp()
+	cleanup()
+
+	for _, url := range []string{"/health", "/health/ready"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, url)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "unavailable", response["status"])
+	}
+
+	// Liveness does not depend on the database
+	req, _ := http.NewRequest("GET", "/health/live", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRunMaintenance(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	req, _ := http.NewRequest("POST", "/api/admin/maintenance", nil)
+	asAdmin(req)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Contains(t, response, "maintenance")
+}
+
+func TestRunBackup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dir := t.TempDir()
+	db, err := database.NewSQLiteDB(filepath.Join(dir, "tasks.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`INSERT INTO tasks (id, title) VALUES ('backed-up', 'Backed up')`)
+	require.NoError(t, err)
+
+	adminHandler := handlers.NewAdminHandler(db)
+	adminHandler.SetBackup(filepath.Join(dir, "backups"), 5)
+	router := gin.New()
+	router.POST("/api/admin/backup", adminHandler.RunBackup)
+
+	req, _ := http.NewRequest("POST", "/api/admin/backup", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Path string `json:"path"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.FileExists(t, response.Path)
+
+	backup, err := database.NewSQLiteDB(response.Path)
+	require.NoError(t, err)
+	defer backup.Close()
+
+	var title string
+	require.NoError(t, backup.QueryRow(`SELECT title FROM tasks WHERE id = 'backed-up'`).Scan(&title))
+	assert.Equal(t, "Backed up", title)
+
+	// The test app's in-memory database can't be backed up
+	memoryRouter, cleanup := setupTestApp()
+	defer cleanup()
+
+	req, _ = http.NewRequest("POST", "/api/admin/backup", nil)
+	asAdmin(req)
+	w = httptest.NewRecorder()
+	memoryRouter.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPauseAndResumeSync(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	req, _ := http.NewRequest("POST", "/api/sync/pause", nil)
+	asAdmin(req)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/sync/trigger", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/sync/status", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		SyncStatus services.SyncStatus `json:"sync_status"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.SyncStatus.Paused)
+
+	req, _ = http.NewRequest("POST", "/api/sync/resume", nil)
+	asAdmin(req)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/sync/trigger", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetSyncAttempts(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Queued task"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/sync/trigger", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req, _ = http.NewRequest("GET", "/api/sync/attempts?limit=5", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Attempts   []models.SyncAttempt `json:"attempts"`
+		NextCursor string               `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Attempts, 1)
+	assert.Empty(t, response.NextCursor)
+
+	for _, url := range []string{"/api/sync/attempts?result=maybe", "/api/sync/attempts?cursor=bogus", "/api/sync/attempts?limit=0"} {
+		req, _ = http.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, url)
+	}
+}
+
+func TestGetSyncHistory(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "History"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	req, _ = http.NewRequest("GET", "/api/tasks/"+created["id"].(string)+"/sync-history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, []interface{}{}, response["sync_history"])
+
+	req, _ = http.NewRequest("GET", "/api/tasks/non-existent-id/sync-history", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUpdateTask_DescriptionNullClears(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Described", Description: stringPtr("Keep me")})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	url := "/api/tasks/" + created["id"].(string)
+
+	// Omitting description keeps it
+	req, _ = http.NewRequest("PUT", url, bytes.NewBufferString(`{"completed": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var updated map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.Equal(t, "Keep me", updated["description"])
+
+	// An explicit null clears it
+	req, _ = http.NewRequest("PUT", url, bytes.NewBufferString(`{"description": null}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	updated = nil
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.Contains(t, updated, "description")
+	assert.Nil(t, updated["description"])
+
+	req, _ = http.NewRequest("GET", url, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var fetched map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &fetched)
+	assert.Nil(t, fetched["description"])
+	assert.Equal(t, true, fetched["completed"])
+}
+
+func TestGetTaskChanges(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	getChanges := func(since string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest("GET", "/api/tasks/changes?since="+url.QueryEscape(since), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	w, first := getChanges("2000-01-01T00:00:00Z")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, first["tasks"])
+	since := first["server_time"].(string)
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Fresh"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	req, _ = http.NewRequest("DELETE", "/api/tasks/"+created["id"].(string), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w, changes := getChanges(since)
+	require.Equal(t, http.StatusOK, w.Code)
+	tasks := changes["tasks"].([]interface{})
+	require.Len(t, tasks, 1)
+	assert.Equal(t, created["id"], tasks[0].(map[string]interface{})["id"])
+	assert.Equal(t, true, tasks[0].(map[string]interface{})["is_deleted"])
+	assert.NotEqual(t, since, changes["server_time"])
+
+	for _, bad := range []string{"", "yesterday"} {
+		w, _ := getChanges(bad)
+		assert.Equal(t, http.StatusBadRequest, w.Code, bad)
+		assert.Equal(t, models.ErrorCodeValidationFailed, decodeAPIError(t, w.Body.Bytes()).Code)
+	}
+}
+
+func TestCreateTask_DescriptionOverflow(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	create := func(description string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.CreateTaskRequest{Title: "Long notes", Description: &description})
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	withDescriptionLimit(t, 5, models.DescriptionOverflowReject)
+	w := create("abcdef")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, models.ErrorCodeValidationFailed, decodeAPIError(t, w.Body.Bytes()).Code)
+
+	w = create("abcde")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("X-Description-Truncated"))
+
+	models.DescriptionOverflow = models.DescriptionOverflowTruncate
+	w = create("abcdef")
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Description-Truncated"))
+
+	var task map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+	assert.Equal(t, "abcd…", task["description"])
+}
+
+func TestCreateTask_OversizedBody(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	description := strings.Repeat("x", testMaxBodyBytes)
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Too big", Description: &description})
+
+	t.Run("declared length", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		apiErr := decodeAPIError(t, w.Body.Bytes())
+		assert.Equal(t, models.ErrorCodeBodyTooLarge, apiErr.Code)
+		assert.Equal(t, "request body too large", apiErr.Message)
+	})
+
+	t.Run("unknown length", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		apiErr := decodeAPIError(t, w.Body.Bytes())
+		assert.Equal(t, models.ErrorCodeBodyTooLarge, apiErr.Code)
+		assert.Equal(t, "request body too large", apiErr.Message)
+	})
+}
+
+func TestCreateTask_BindingErrors(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	cases := []struct {
+		name    string
+		body    string
+		message string
+		field   string
+	}{
+		{name: "missing title", body: `{"description": "no title"}`, message: "title is required", field: "title"},
+		{name: "wrong type", body: `{"title": 42}`, message: "title must be of type string", field: "title"},
+		{name: "malformed", body: `{"title": `, message: "request body is not valid JSON"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/tasks", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			apiErr := decodeAPIError(t, w.Body.Bytes())
+			assert.Equal(t, models.ErrorCodeValidationFailed, apiErr.Code)
+			assert.Equal(t, tc.message, apiErr.Message)
+			assert.Equal(t, tc.field, apiErr.Field)
+		})
+	}
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	os.Exit(code)
+}
+
+func TestUpdateTask_Metadata(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	oldLimit := models.MaxMetadataBytes
+	models.MaxMetadataBytes = 64
+	t.Cleanup(func() { models.MaxMetadataBytes = oldLimit })
+
+	send := func(method, url, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	w, created := send("POST", "/api/tasks", `{"title": "Annotated", "metadata": {"source": "jira", "points": 3}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, map[string]interface{}{"source": "jira", "points": float64(3)}, created["metadata"])
+	url := "/api/tasks/" + created["id"].(string)
+
+	t.Run("merge", func(t *testing.T) {
+		w, updated := send("PUT", url, `{"metadata": {"points": 5, "sprint": "12"}}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]interface{}{"source": "jira", "points": float64(5), "sprint": "12"}, updated["metadata"])
+
+		w, updated = send("PUT", url, `{"metadata": {"sprint": null}}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]interface{}{"source": "jira", "points": float64(5)}, updated["metadata"])
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		w, updated := send("PUT", url+"?replace_metadata=true", `{"metadata": {"owner": "ops"}}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]interface{}{"owner": "ops"}, updated["metadata"])
+
+		w, fetched := send("GET", url, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, map[string]interface{}{"owner": "ops"}, fetched["metadata"])
+
+		w, _ = send("PUT", url+"?replace_metadata=maybe", `{"metadata": {}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("oversized", func(t *testing.T) {
+		big := `{"notes": "` + strings.Repeat("x", 64) + `"}`
+
+		w, _ := send("POST", "/api/tasks", `{"title": "Too much", "metadata": `+big+`}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, models.ErrorCodeValidationFailed, decodeAPIError(t, w.Body.Bytes()).Code)
+
+		// Each half fits, but merged they don't
+		half := strings.Repeat("y", 25)
+		w, _ = send("PUT", url, `{"metadata": {"a": "`+half+`"}}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		w, _ = send("PUT", url, `{"metadata": {"b": "`+half+`"}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, models.ErrorCodeValidationFailed, decodeAPIError(t, w.Body.Bytes()).Code)
+
+		// Replacing instead of merging fits again
+		w, _ = send("PUT", url+"?replace_metadata=true", `{"metadata": {"b": "`+half+`"}}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		w, _ := send("POST", "/api/tasks", `{"title": "Listed", "metadata": ["a", "b"]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, models.ErrorCodeValidationFailed, decodeAPIError(t, w.Body.Bytes()).Code)
+	})
+}
+
+func TestPullSync_Errors(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	// Pulling merges into every user's tasks, so only an operator may do it
+	req, _ := http.NewRequest("POST", "/api/sync/pull", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req, _ = http.NewRequest("POST", "/api/sync/pull?since=yesterday", nil)
+	asAdmin(req)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, models.ErrorCodeValidationFailed, decodeAPIError(t, w.Body.Bytes()).Code)
+
+	// The built-in simulated server can't list its tasks
+	req, _ = http.NewRequest("POST", "/api/sync/pull", nil)
+	asAdmin(req)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Equal(t, models.ErrorCodePullUnsupported, decodeAPIError(t, w.Body.Bytes()).Code)
+}
+
+func TestUpdateTask_IfMatch(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Versioned"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	url := "/api/tasks/" + created["id"].(string)
+
+	req, _ = http.NewRequest("GET", url, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	update := func(title, ifMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", url, strings.NewReader(`{"title": "`+title+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("matching", func(t *testing.T) {
+		w := update("First edit", etag)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+		// The old tag no longer names the current version
+		w = update("Lost update", etag)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+		assert.Equal(t, models.ErrorCodePreconditionFailed, decodeAPIError(t, w.Body.Bytes()).Code)
+	})
+
+	t.Run("mismatched", func(t *testing.T) {
+		w := update("Stale", `"0000000000000000"`)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+		req, _ := http.NewRequest("GET", url, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var fetched map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &fetched)
+		assert.Equal(t, "First edit", fetched["title"])
+
+		// Any of several tags, or *, may match
+		current := w.Header().Get("ETag")
+		w = update("Listed", `"0000000000000000", `+current)
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = update("Wildcard", "*")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		// Optional by default
+		w := update("Unconditional", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestUpdateTask_IfMatchRequired(t *testing.T) {
+	db, err := database.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, &config.Config{SyncBatchSize: 10, MaxRetries: 3})
+	taskService := services.NewTaskService(db, syncService)
+	taskHandler := handlers.NewTaskHandler(taskService)
+	taskHandler.SetRequireIfMatch(true)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PUT("/api/tasks/:id", taskHandler.UpdateTask)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Guarded"})
+	require.NoError(t, err)
+
+	send := func(ifMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PUT", "/api/tasks/"+task.ID, strings.NewReader(`{"completed": true}`))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("")
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	assert.Equal(t, models.ErrorCodePreconditionRequired, decodeAPIError(t, w.Body.Bytes()).Code)
+
+	w = send(task.ETag())
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPatchTask_ToggleCompleted(t *testing.T) {
+	db, err := database.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, &config.Config{SyncBatchSize: 10, MaxRetries: 3})
+	taskService := services.NewTaskService(db, syncService)
+	taskHandler := handlers.NewTaskHandler(taskService)
+	taskHandler.SetRequireIfMatch(true)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.PATCH("/api/tasks/:id", taskHandler.PatchTask)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Toggled", Description: stringPtr("Kept")})
+	require.NoError(t, err)
+
+	patch := func(body, ifMatch string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("PATCH", "/api/tasks/"+task.ID, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// PATCH needs no If-Match even when PUT does, and leaves other fields alone
+	w := patch(`{"completed": true}`, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var patched models.Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &patched))
+	assert.True(t, patched.Completed)
+	assert.Equal(t, "Toggled", patched.Title)
+	require.NotNil(t, patched.Description)
+	assert.Equal(t, "Kept", *patched.Description)
+
+	w = patch(`{"completed": false}`, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Completed)
+
+	// A stale If-Match is still refused
+	w = patch(`{"completed": true}`, task.ETag())
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	req, _ := http.NewRequest("PATCH", "/api/tasks/missing", strings.NewReader(`{"completed": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
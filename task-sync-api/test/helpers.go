@@ -1,5 +1,15 @@
 package tests
 
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/stretchr/testify/require"
+)
+
 // Helper functions shared across test files
 func stringPtr(s string) *string {
 	return &s
@@ -8,3 +18,36 @@ func stringPtr(s string) *string {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+// decodeAPIError reads the error object out of a {"error": {...}} response body.
+func decodeAPIError(t *testing.T, body []byte) models.APIError {
+	t.Helper()
+	var response struct {
+		Error models.APIError `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response.Error
+}
+
+// testUserKeys are the user API keys the test router accepts, by user ID.
+var testUserKeys = map[string]string{
+	"alice":        "alice-key",
+	"bob":          "bob-key",
+	"someone-else": "someone-else-key",
+}
+
+// testAdminKey is the admin key the test router accepts for operator routes.
+const testAdminKey = "admin-key"
+
+// asUser authenticates req as user with its test API key. The empty user sends
+// no key and acts anonymously.
+func asUser(req *http.Request, user string) {
+	if user != "" {
+		req.Header.Set("X-API-Key", testUserKeys[user])
+	}
+}
+
+// asAdmin authenticates req for the test router's operator routes.
+func asAdmin(req *http.Request) {
+	req.Header.Set("X-Admin-Key", testAdminKey)
+}
@@ -0,0 +1,554 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/config"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/handlers"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/middleware"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	limiter := middleware.NewRateLimiter(1, 3)
+
+	// The burst is available immediately
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.Allow("10.0.0.1")
+		assert.True(t, allowed, "request %d should be allowed", i)
+	}
+
+	allowed, wait := limiter.Allow("10.0.0.1")
+	assert.False(t, allowed)
+	assert.Greater(t, wait, time.Duration(0))
+
+	// Other clients have their own bucket
+	allowed, _ = limiter.Allow("10.0.0.2")
+	assert.True(t, allowed)
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	api := router.Group("/api")
+	api.Use(middleware.NewRateLimiter(1, 5).Middleware())
+	api.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	})
+
+	var ok, limited int
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequest("GET", "/api/ping", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		switch w.Code {
+		case http.StatusOK:
+			ok++
+		case http.StatusTooManyRequests:
+			limited++
+			assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		}
+	}
+
+	assert.Equal(t, 5, ok)
+	assert.Equal(t, 5, limited)
+}
+
+func setupAuthRouter(apiKey string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	api := router.Group("/api")
+	api.Use(middleware.APIKeyAuth(apiKey))
+	api.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	})
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	return router
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := setupAuthRouter("secret")
+
+	cases := []struct {
+		name       string
+		key        string
+		wantStatus int
+	}{
+		{name: "valid key", key: "secret", wantStatus: http.StatusOK},
+		{name: "wrong key", key: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "missing key", key: "", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		req, _ := http.NewRequest("GET", "/api/ping", nil)
+		if tc.key != "" {
+			req.Header.Set("X-API-Key", tc.key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.wantStatus, w.Code, tc.name)
+	}
+
+	// Health stays open
+	req, _ := http.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAPIKeyAuth_Disabled(t *testing.T) {
+	router := setupAuthRouter("")
+
+	req, _ := http.NewRequest("GET", "/api/ping", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestUserContext(t *testing.T) {
+	userKeys := map[string]string{"alice": "alice-key", "bob": "bob-key"}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(middleware.APIKeyAuth("shared", userKeys["alice"], userKeys["bob"]))
+	api.Use(middleware.UserContext(userKeys))
+	api.GET("/whoami", func(c *gin.Context) {
+		c.String(http.StatusOK, middleware.UserID(c))
+	})
+
+	send := func(key, userHeader string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/whoami", nil)
+		req.Header.Set("X-API-Key", key)
+		if userHeader != "" {
+			req.Header.Set("X-User-ID", userHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A user's key identifies them; the shared key acts anonymously
+	w := send("bob-key", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bob", w.Body.String())
+	w = send("shared", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", w.Body.String())
+
+	// Claiming an identity by header is refused, even with a valid key
+	w = send("shared", "alice")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, models.ErrorCodeUnauthorized, decodeAPIError(t, w.Body.Bytes()).Code)
+
+	w = send("mallory-key", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAdminAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	send := func(adminKey, provided string) int {
+		router := gin.New()
+		router.POST("/api/sync/reset", middleware.AdminAuth(adminKey), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		req, _ := http.NewRequest("POST", "/api/sync/reset", nil)
+		if provided != "" {
+			req.Header.Set("X-Admin-Key", provided)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("root", "root"))
+	assert.Equal(t, http.StatusForbidden, send("root", "guess"))
+	assert.Equal(t, http.StatusForbidden, send("root", ""))
+	// Without a configured key operator actions are off, not open
+	assert.Equal(t, http.StatusForbidden, send("", ""))
+}
+
+func setupCORSRouter(origins []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.CORS(middleware.CORSOptions{
+		AllowedOrigins: origins,
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+	}))
+
+	api := router.Group("/api")
+	api.Use(middleware.APIKeyAuth("secret"))
+	api.GET("/tasks", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []string{})
+	})
+
+	return router
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	router := setupCORSRouter([]string{"https://app.example.com"})
+
+	req, _ := http.NewRequest("OPTIONS", "/api/tasks", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Preflight is answered before authentication
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
+
+	// Unknown origins are refused
+	req, _ = http.NewRequest("OPTIONS", "/api/tasks", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_CrossOriginGet(t *testing.T) {
+	router := setupCORSRouter([]string{"https://app.example.com"})
+
+	req, _ := http.NewRequest("GET", "/api/tasks", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("X-API-Key", "secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	req, _ = http.NewRequest("GET", "/api/tasks", nil)
+	req.Header.Set("Origin", "https://other.example.com")
+	req.Header.Set("X-API-Key", "secret")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_Wildcard(t *testing.T) {
+	router := setupCORSRouter([]string{"*"})
+
+	req, _ := http.NewRequest("GET", "/api/tasks", nil)
+	req.Header.Set("Origin", "https://anywhere.example.com")
+	req.Header.Set("X-API-Key", "secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.BodyLimit(16))
+	router.Any("/echo", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		c.String(http.StatusOK, string(body))
+	})
+
+	cases := []struct {
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{method: "POST", body: "small", wantStatus: http.StatusOK},
+		{method: "POST", body: strings.Repeat("a", 17), wantStatus: http.StatusRequestEntityTooLarge},
+		{method: "PUT", body: strings.Repeat("a", 17), wantStatus: http.StatusRequestEntityTooLarge},
+		{method: "DELETE", body: strings.Repeat("a", 17), wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		req, _ := http.NewRequest(tc.method, "/echo", strings.NewReader(tc.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.wantStatus, w.Code, "%s with %d bytes", tc.method, len(tc.body))
+	}
+}
+
+func TestRequestLogger_Levels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// serve sends body to path and returns the log output and response body
+	serve := func(level middleware.LogLevel, path, body string) (string, string) {
+		var out bytes.Buffer
+		router := gin.New()
+		router.Use(middleware.RequestLogger(level, log.New(&out, "", 0)))
+		router.POST("/ok", func(c *gin.Context) {
+			read, _ := io.ReadAll(c.Request.Body)
+			c.String(http.StatusOK, string(read))
+		})
+		router.POST("/fail", func(c *gin.Context) {
+			c.Status(http.StatusInternalServerError)
+		})
+
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return out.String(), w.Body.String()
+	}
+
+	// At warn only failures are logged
+	logged, _ := serve(middleware.LogLevelWarn, "/ok", "")
+	assert.Empty(t, logged)
+	logged, _ = serve(middleware.LogLevelWarn, "/fail", "")
+	assert.Contains(t, logged, "POST /fail 500")
+
+	logged, _ = serve(middleware.LogLevelInfo, "/ok", "")
+	assert.Contains(t, logged, "POST /ok 200")
+	logged, _ = serve(middleware.LogLevelError, "/ok", "")
+	assert.Empty(t, logged)
+
+	// Debug logs the body and still hands it to the handler
+	logged, response := serve(middleware.LogLevelDebug, "/ok", `{"title":"logged"}`)
+	assert.Equal(t, `{"title":"logged"}`, response)
+	assert.Contains(t, logged, `body={"title":"logged"}`)
+
+	_, err := middleware.ParseLogLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestGzip_AcceptEncoding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Gzip())
+	router.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	})
+	router.DELETE("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	cases := map[string]string{
+		"gzip":                "gzip",
+		"deflate, gzip;q=0.5": "gzip",
+		"gzip;q=0":            "",
+		"deflate":             "",
+		"":                    "",
+	}
+	for acceptEncoding, expected := range cases {
+		req, _ := http.NewRequest("GET", "/ping", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, acceptEncoding)
+		assert.Equal(t, expected, w.Header().Get("Content-Encoding"), acceptEncoding)
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"), acceptEncoding)
+	}
+
+	// Nothing is compressed when there is no body
+	req, _ := http.NewRequest("DELETE", "/empty", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestRecovery_RespondsWithJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Recovery(log.New(&out, "", 0)))
+	router.GET("/panic", func(c *gin.Context) {
+		panic("something broke")
+	})
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "req-123", w.Header().Get("X-Request-ID"))
+
+	apiErr := decodeAPIError(t, w.Body.Bytes())
+	assert.Equal(t, models.ErrorCodeInternal, apiErr.Code)
+	assert.Equal(t, "internal server error", apiErr.Message)
+	assert.Equal(t, "req-123", apiErr.RequestID)
+
+	assert.Contains(t, out.String(), "something broke")
+	assert.Contains(t, out.String(), "request_id=req-123")
+	assert.Contains(t, out.String(), "goroutine")
+
+	// Without an incoming ID one is generated
+	req, _ = http.NewRequest("GET", "/ok", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("X-Request-ID"))
+}
+
+func TestTimeout_RespondsWith503(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.Timeout(20 * time.Millisecond))
+	router.GET("/slow", func(c *gin.Context) {
+		// Stands in for a query that only gives up when its context does
+		<-c.Request.Context().Done()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": c.Request.Context().Err().Error()})
+	})
+	router.GET("/sleepy", func(c *gin.Context) {
+		time.Sleep(40 * time.Millisecond)
+		c.JSON(http.StatusOK, gin.H{"message": "too late"})
+	})
+	router.GET("/fast", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	for _, path := range []string{"/slow", "/sleepy"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, path)
+		apiErr := decodeAPIError(t, w.Body.Bytes())
+		assert.Equal(t, models.ErrorCodeRequestTimeout, apiErr.Code, path)
+		assert.Equal(t, "request timed out", apiErr.Message, path)
+	}
+
+	req, _ := http.NewRequest("GET", "/fast", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConcurrencyLimiter_ShedsWritesOverLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	api := router.Group("/api")
+	api.Use(middleware.NewConcurrencyLimiter(2, 0, 0).Middleware())
+	api.POST("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.JSON(http.StatusOK, gin.H{"message": "done"})
+	})
+	api.GET("/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	})
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", "/api/slow", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+	<-entered
+	<-entered
+
+	// Both write slots are taken, so further writes are shed
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("POST", "/api/slow", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "1", w.Header().Get("Retry-After"))
+		assert.Equal(t, models.ErrorCodeServerBusy, decodeAPIError(t, w.Body.Bytes()).Code)
+	}
+
+	// Reads have their own limit, unset here
+	req, _ := http.NewRequest("GET", "/api/ping", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+}
+
+func TestConcurrencyLimiter_QueuesWritesWithoutLockErrors(t *testing.T) {
+	db, err := database.NewSQLiteDB(":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, &config.Config{SyncBatchSize: 10, MaxRetries: 3})
+	taskHandler := handlers.NewTaskHandler(services.NewTaskService(db, syncService))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(middleware.NewConcurrencyLimiter(1, 0, 5*time.Second).Middleware())
+	api.Use(middleware.UserContext(nil))
+	api.POST("/tasks", taskHandler.CreateTask)
+
+	const writers = 20
+	var wg sync.WaitGroup
+	codes := make([]int, writers)
+	bodies := make([]string, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"title": "Concurrent %d"}`, i)
+			req, _ := http.NewRequest("POST", "/api/tasks", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes[i], bodies[i] = w.Code, w.Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	// Every write queued for its turn rather than failing on a locked database
+	for i, code := range codes {
+		assert.Equal(t, http.StatusCreated, code, bodies[i])
+	}
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM tasks`).Scan(&count))
+	assert.Equal(t, writers, count)
+}
@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+
+	daily, err := models.NextOccurrence(models.RecurrenceDaily, from)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC), daily)
+
+	weekly, err := models.NextOccurrence(models.RecurrenceWeekly, from)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.February, 7, 9, 0, 0, 0, time.UTC), weekly)
+
+	for _, rule := range []string{"", "hourly", "0 9 * * *"} {
+		_, err := models.NextOccurrence(rule, from)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestCreateTaskRequest_RecurrenceValidation(t *testing.T) {
+	valid := &models.CreateTaskRequest{Title: "Standup", RecurrenceRule: stringPtr(models.RecurrenceWeekly)}
+	assert.NoError(t, valid.Validate())
+
+	invalid := &models.CreateTaskRequest{Title: "Standup", RecurrenceRule: stringPtr("fortnightly")}
+	assert.Error(t, invalid.Validate())
+
+	update := &models.UpdateTaskRequest{RecurrenceRule: stringPtr("fortnightly")}
+	assert.Error(t, update.Validate())
+}
+
+func TestTaskRequest_PriorityValidation(t *testing.T) {
+	assert.NoError(t, (&models.CreateTaskRequest{Title: "Urgent", Priority: 3}).Validate())
+	assert.EqualError(t, (&models.CreateTaskRequest{Title: "Urgent", Priority: -1}).Validate(), "priority must not be negative")
+
+	negative := -1
+	assert.EqualError(t, (&models.UpdateTaskRequest{Priority: &negative}).Validate(), "priority must not be negative")
+}
+
+// withDescriptionLimit sets the description limit and policy for the rest of the test.
+func withDescriptionLimit(t *testing.T, limit int, policy models.DescriptionOverflowPolicy) {
+	oldLimit, oldPolicy := models.MaxDescriptionLength, models.DescriptionOverflow
+	models.MaxDescriptionLength, models.DescriptionOverflow = limit, policy
+	t.Cleanup(func() {
+		models.MaxDescriptionLength, models.DescriptionOverflow = oldLimit, oldPolicy
+	})
+}
+
+func TestCreateTaskRequest_DescriptionLimit(t *testing.T) {
+	atLimit := strings.Repeat("é", 10)
+	overLimit := strings.Repeat("é", 11)
+
+	t.Run("reject", func(t *testing.T) {
+		withDescriptionLimit(t, 10, models.DescriptionOverflowReject)
+
+		create := &models.CreateTaskRequest{Title: "Notes", Description: stringPtr(atLimit)}
+		require.NoError(t, create.Validate())
+		assert.Equal(t, atLimit, *create.Description)
+		assert.False(t, create.DescriptionTruncated)
+
+		create = &models.CreateTaskRequest{Title: "Notes", Description: stringPtr(overLimit)}
+		assert.EqualError(t, create.Validate(), "description must be at most 10 characters")
+
+		update := &models.UpdateTaskRequest{Description: stringPtr(overLimit)}
+		assert.Error(t, update.Validate())
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		withDescriptionLimit(t, 10, models.DescriptionOverflowTruncate)
+
+		create := &models.CreateTaskRequest{Title: "Notes", Description: stringPtr(atLimit)}
+		require.NoError(t, create.Validate())
+		assert.Equal(t, atLimit, *create.Description)
+		assert.False(t, create.DescriptionTruncated)
+
+		create = &models.CreateTaskRequest{Title: "Notes", Description: stringPtr(overLimit)}
+		require.NoError(t, create.Validate())
+		assert.Equal(t, strings.Repeat("é", 9)+"…", *create.Description)
+		assert.True(t, create.DescriptionTruncated)
+
+		update := &models.UpdateTaskRequest{Description: stringPtr(overLimit + "more")}
+		require.NoError(t, update.Validate())
+		assert.Equal(t, strings.Repeat("é", 9)+"…", *update.Description)
+		assert.True(t, update.DescriptionTruncated)
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		withDescriptionLimit(t, 0, models.DescriptionOverflowReject)
+
+		create := &models.CreateTaskRequest{Title: "Notes", Description: stringPtr(strings.Repeat("x", 100000))}
+		assert.NoError(t, create.Validate())
+	})
+}
+
+func TestTask_Validate(t *testing.T) {
+	assert.NoError(t, models.NewTask("Valid", nil).Validate())
+
+	cases := map[string]func(*models.Task){
+		"missing id":      func(task *models.Task) { task.ID = "" },
+		"empty title":     func(task *models.Task) { task.Title = "  " },
+		"bad sync status": func(task *models.Task) { task.SyncStatus = "lost" },
+		"no sync status":  func(task *models.Task) { task.SyncStatus = "" },
+		"zero created_at": func(task *models.Task) { task.CreatedAt = time.Time{} },
+		"zero updated_at": func(task *models.Task) { task.UpdatedAt = time.Time{} },
+		"bad recurrence":  func(task *models.Task) { task.RecurrenceRule = stringPtr("hourly") },
+	}
+
+	for name, corrupt := range cases {
+		t.Run(name, func(t *testing.T) {
+			task := models.NewTask("Valid", nil)
+			corrupt(task)
+			assert.Error(t, task.Validate())
+		})
+	}
+}
+
+func TestSyncQueueItem_GetTaskDataValidates(t *testing.T) {
+	item, err := models.NewSyncQueueItem("task-1", models.OperationTypeCreate, models.NewTask("Queued", nil))
+	require.NoError(t, err)
+	_, err = item.GetTaskData()
+	assert.NoError(t, err)
+
+	item.TaskData = `{"id": "task-1", "title": "Queued", "sync_status": "bogus"}`
+	_, err = item.GetTaskData()
+	assert.ErrorContains(t, err, "sync_status")
+}
+
+func TestSyncQueueItem_Compression(t *testing.T) {
+	old := models.TaskDataCompressThreshold
+	models.TaskDataCompressThreshold = 512
+	t.Cleanup(func() { models.TaskDataCompressThreshold = old })
+
+	small := models.NewTask("Small", nil)
+	large := models.NewTask("Large", stringPtr(strings.Repeat("a long description ", 200)))
+	large.Tags = []string{"work", "home"}
+
+	item, err := models.NewSyncQueueItem(small.ID, models.OperationTypeCreate, small)
+	require.NoError(t, err)
+	assert.False(t, item.Compressed)
+	decoded, err := item.GetTaskData()
+	require.NoError(t, err)
+	assert.Equal(t, small.Title, decoded.Title)
+
+	item, err = models.NewSyncQueueItem(large.ID, models.OperationTypeUpdate, large)
+	require.NoError(t, err)
+	assert.True(t, item.Compressed)
+	uncompressed, err := json.Marshal(large)
+	require.NoError(t, err)
+	assert.Less(t, len(item.TaskData), len(uncompressed))
+
+	decoded, err = item.GetTaskData()
+	require.NoError(t, err)
+	assert.Equal(t, large.ID, decoded.ID)
+	assert.Equal(t, *large.Description, *decoded.Description)
+	assert.Equal(t, large.Tags, decoded.Tags)
+
+	// The same payload hashes alike compressed or not
+	models.TaskDataCompressThreshold = 0
+	plain, err := models.NewSyncQueueItem(large.ID, models.OperationTypeUpdate, large)
+	require.NoError(t, err)
+	assert.False(t, plain.Compressed)
+	assert.Equal(t, plain.ContentHash, item.ContentHash)
+
+	item.TaskData = "not gzip"
+	_, err = item.GetTaskData()
+	assert.ErrorContains(t, err, "decompress")
+}
+
+func TestTask_TimeFormat(t *testing.T) {
+	fixed := time.Date(2024, time.March, 10, 15, 30, 0, 0, time.UTC)
+	task := models.NewTask("Formatted", nil)
+	task.CreatedAt = fixed
+	task.UpdatedAt = fixed
+	task.DueDate = &fixed
+
+	decode := func(marshaler json.Marshaler) map[string]interface{} {
+		data, err := marshaler.MarshalJSON()
+		require.NoError(t, err)
+		var fields map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &fields))
+		return fields
+	}
+
+	fields := decode(task)
+	assert.Equal(t, "2024-03-10T15:30:00Z", fields["created_at"])
+	assert.Equal(t, "2024-03-10T15:30:00Z", fields["due_date"])
+	assert.Nil(t, fields["last_synced_at"])
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	models.ResponseTimeZone = newYork
+	defer func() { models.ResponseTimeZone = nil }()
+
+	fields = decode(task)
+	assert.Equal(t, "2024-03-10T11:30:00-04:00", fields["created_at"])
+	assert.Equal(t, "2024-03-10T11:30:00-04:00", fields["updated_at"])
+
+	fields = decode(task.WithTimeFormat(models.TimeFormatEpochMillis))
+	assert.Equal(t, float64(fixed.UnixMilli()), fields["created_at"])
+	assert.Equal(t, float64(fixed.UnixMilli()), fields["due_date"])
+
+	_, err = models.ParseTimeFormat("unix")
+	assert.Error(t, err)
+}
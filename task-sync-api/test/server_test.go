@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/config"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/server"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSelfSignedCert writes a certificate for 127.0.0.1 and its key to dir and
+// returns their paths along with the certificate itself.
+func writeSelfSignedCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "task-sync-api test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile, cert
+}
+
+func TestServe_TLS(t *testing.T) {
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go server.Serve(context.Background(), ln, router, certFile, keyFile, 0)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots},
+			ForceAttemptHTTP2: true,
+		},
+	}
+
+	resp, err := client.Get("https://" + ln.Addr().String() + "/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+}
+
+func TestServe_GracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ctx, ln, handler, "", "", 5*time.Second) }()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := client.Get("http://" + ln.Addr().String() + "/")
+		if err == nil {
+			resp.Body.Close()
+		}
+		responses <- resp
+	}()
+
+	<-started
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned before the in-flight request finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	resp := <-responses
+	require.NotNil(t, resp, "the in-flight request completes")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.NoError(t, <-served, "a clean shutdown returns nil")
+
+	_, err = client.Get("http://" + ln.Addr().String() + "/")
+	assert.Error(t, err, "no new connections after shutdown")
+}
+
+func TestConfig_ValidateTLS(t *testing.T) {
+	assert.NoError(t, (&config.Config{}).ValidateTLS())
+	assert.NoError(t, (&config.Config{TLSCertFile: "cert.pem", TLSKeyFile: "key.pem"}).ValidateTLS())
+	assert.Error(t, (&config.Config{TLSCertFile: "cert.pem"}).ValidateTLS())
+	assert.Error(t, (&config.Config{TLSKeyFile: "key.pem"}).ValidateTLS())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Setenv("SYNC_BATCH_SIZE", "")
+	t.Setenv("MAX_RETRIES", "")
+	require.NoError(t, config.Load().Validate(), "defaults are valid")
+
+	t.Setenv("SYNC_BATCH_SIZE", "25")
+	t.Setenv("SYNC_PRIORITIES", "delete=2,create=1")
+	t.Setenv("LOG_LEVEL", "WARN")
+	t.Setenv("DEFAULT_TASK_SORT", "title:desc")
+	t.Setenv("RESPONSE_TIME_ZONE", "Asia/Kolkata")
+	cfg := config.Load()
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, 25, cfg.SyncBatchSize)
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		error string
+	}{
+		{"unparseable int", map[string]string{"SYNC_BATCH_SIZE": "abc"}, `SYNC_BATCH_SIZE="abc" is not an integer`},
+		{"negative batch size", map[string]string{"SYNC_BATCH_SIZE": "-1"}, "SYNC_BATCH_SIZE must be positive"},
+		{"zero max retries", map[string]string{"MAX_RETRIES": "0"}, "MAX_RETRIES must be positive"},
+		{"unparseable bool", map[string]string{"HARD_DELETE": "sometimes"}, `HARD_DELETE="sometimes" is not a boolean`},
+		{"unparseable duration", map[string]string{"SYNC_ITEM_TIMEOUT": "30"}, `SYNC_ITEM_TIMEOUT="30" is not a duration`},
+		{"malformed priorities", map[string]string{"SYNC_PRIORITIES": "delete"}, "SYNC_PRIORITIES"},
+		{"non-positive operation retries", map[string]string{"MAX_RETRIES_BY_OPERATION": "delete=10,create=0"}, "MAX_RETRIES_BY_OPERATION must be positive for create"},
+		{"jitter out of range", map[string]string{"RETRY_JITTER_PERCENT": "150"}, "RETRY_JITTER_PERCENT must be between 0 and 100"},
+		{"bad port", map[string]string{"PORT": "http"}, "PORT must be a number"},
+		{"half TLS", map[string]string{"TLS_CERT_FILE": "cert.pem"}, "TLS_CERT_FILE and TLS_KEY_FILE"},
+		{"malformed user keys", map[string]string{"USER_API_KEYS": "alice"}, "USER_API_KEYS is not a list of name=value pairs"},
+		{"shared user key", map[string]string{"USER_API_KEYS": "alice=k1,bob=k1"}, "USER_API_KEYS gives alice and bob the same key"},
+		{"user key reuses admin key", map[string]string{"ADMIN_API_KEY": "k1", "USER_API_KEYS": "alice=k1"}, "USER_API_KEYS must not reuse ADMIN_API_KEY for alice"},
+		{"user key reuses API key", map[string]string{"API_KEY": "k1", "USER_API_KEYS": "alice=k1"}, "USER_API_KEYS must not reuse API_KEY for alice"},
+		{"unknown conflict strategy", map[string]string{"CONFLICT_STRATEGY": "newest"}, "CONFLICT_STRATEGY must be one of"},
+		{"unknown retry strategy", map[string]string{"RETRY_STRATEGY": "random"}, "RETRY_STRATEGY must be one of"},
+		{"unknown log level", map[string]string{"LOG_LEVEL": "verbose"}, "LOG_LEVEL must be debug, info, warn or error"},
+		{"unknown overflow policy", map[string]string{"DESCRIPTION_OVERFLOW_POLICY": "drop"}, "DESCRIPTION_OVERFLOW_POLICY must be reject or truncate"},
+		{"unknown sort field", map[string]string{"DEFAULT_TASK_SORT": "due_date"}, "DEFAULT_TASK_SORT must be"},
+		{"unknown sort direction", map[string]string{"DEFAULT_TASK_SORT": "title:up"}, "DEFAULT_TASK_SORT must be"},
+		{"unknown time zone", map[string]string{"RESPONSE_TIME_ZONE": "Mars/Olympus"}, "RESPONSE_TIME_ZONE must be an IANA time zone name"},
+		{"unknown priority operation", map[string]string{"SYNC_PRIORITIES": "delete=2,remove=1"}, `SYNC_PRIORITIES has unknown operation type "remove"`},
+		{"unknown retry operation", map[string]string{"MAX_RETRIES_BY_OPERATION": "erase=3"}, `MAX_RETRIES_BY_OPERATION has unknown operation type "erase"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			err := config.Load().Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.error)
+		})
+	}
+
+	// An empty PORT can only come from the struct, since an empty variable means unset
+	err := (&config.Config{Port: "", SyncBatchSize: -5, MaxRetries: 0}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "PORT must not be empty")
+	assert.Contains(t, err.Error(), "SYNC_BATCH_SIZE must be positive, got -5")
+	assert.Contains(t, err.Error(), "MAX_RETRIES must be positive, got 0")
+}
@@ -1,7 +1,13 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -10,6 +16,7 @@ import (
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
 	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/syncclient"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -135,8 +142,7 @@ func TestTaskService_GetTaskByID(t *testing.T) {
 
 	// Try to get non-existent task
 	_, err = taskService.GetTaskByID("non-existent-id")
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "task not found")
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
 }
 
 func TestTaskService_UpdateTask(t *testing.T) {
@@ -148,7 +154,6 @@ func TestTaskService_UpdateTask(t *testing.T) {
 	require.NoError(t, err)
 
 	originalUpdatedAt := task.UpdatedAt
-	time.Sleep(1 * time.Millisecond) // Ensure different timestamp
 
 	// Update the task
 	updateReq := &models.UpdateTaskRequest{
@@ -165,14 +170,94 @@ func TestTaskService_UpdateTask(t *testing.T) {
 	assert.Equal(t, models.SyncStatusPending, updatedTask.SyncStatus)
 	assert.True(t, updatedTask.UpdatedAt.After(originalUpdatedAt))
 
+	// Priority is stored and left alone by updates that don't mention it
+	priority := 2
+	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Priority: &priority})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Title: stringPtr("Retitled")})
+	require.NoError(t, err)
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Priority)
+
 	// Try to update non-existent task
 	_, err = taskService.UpdateTask("non-existent-id", updateReq)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "task not found")
 }
 
+func TestTaskService_SubSecondOrdering(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	// Updates microseconds apart, written from different zones, still sort in
+	// time order
+	base := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	zones := []*time.Location{time.UTC, time.FixedZone("UTC-7", -7*60*60), time.FixedZone("UTC+9", 9*60*60)}
+	var ids []string
+	for i := 0; i < 6; i++ {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Task %d", i)})
+		require.NoError(t, err)
+		at := base.Add(time.Duration(i) * 10 * time.Microsecond).In(zones[i%len(zones)])
+		_, err = db.Exec("UPDATE tasks SET updated_at = ?, last_synced_at = ? WHERE id = ?", at, at, task.ID)
+		require.NoError(t, err)
+		ids = append(ids, task.ID)
+	}
+
+	tasks, _, err := taskService.GetTasksAfter("", 10)
+	require.NoError(t, err)
+	require.Len(t, tasks, 6)
+	for i, task := range tasks {
+		assert.Equal(t, ids[len(ids)-1-i], task.ID)
+	}
+
+	// The newest last_synced_at comes back through MAX() as text
+	status, err := syncService.GetSyncStatus()
+	require.NoError(t, err)
+	assert.True(t, status.LastSync.Equal(base.Add(50*time.Microsecond)), "last sync %s", status.LastSync)
+
+	// Back-to-back writes with no pause between them keep their order too
+	first, err := taskService.UpdateTask(ids[0], &models.UpdateTaskRequest{Title: stringPtr("First")})
+	require.NoError(t, err)
+	second, err := taskService.UpdateTask(ids[1], &models.UpdateTaskRequest{Title: stringPtr("Second")})
+	require.NoError(t, err)
+	tasks, _, err = taskService.GetTasksAfter("", 2)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, second.ID, tasks[0].ID)
+	assert.Equal(t, first.ID, tasks[1].ID)
+	assert.True(t, tasks[0].UpdatedAt.Equal(second.UpdatedAt))
+}
+
+func TestTaskService_UpdateTask_ClearDescription(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Task", Description: stringPtr("Old")})
+	require.NoError(t, err)
+
+	var req models.UpdateTaskRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"description": null}`), &req))
+	assert.True(t, req.ClearDescription)
+
+	updated, err := taskService.UpdateTask(task.ID, &req)
+	require.NoError(t, err)
+	assert.Nil(t, updated.Description)
+
+	// The queued update carries the cleared value
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Contains(t, items[1].TaskData, `"description":null`)
+
+	// Absent description is not treated as a clear
+	req = models.UpdateTaskRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"title": "Renamed"}`), &req))
+	assert.False(t, req.ClearDescription)
+}
+
 func TestTaskService_DeleteTask(t *testing.T) {
-	taskService, _, _, cleanup := setupTestServices()
+	taskService, _, db, cleanup := setupTestServices()
 	defer cleanup()
 
 	// Create a task
@@ -193,6 +278,14 @@ func TestTaskService_DeleteTask(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "task not found")
 
+	// Deleting it again succeeds and queues nothing new
+	var queued int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sync_queue WHERE task_id = ?`, task.ID).Scan(&queued))
+	require.NoError(t, taskService.DeleteTask(task.ID))
+	var requeued int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sync_queue WHERE task_id = ?`, task.ID).Scan(&requeued))
+	assert.Equal(t, queued, requeued)
+
 	// Try to delete non-existent task
 	err = taskService.DeleteTask("non-existent-id")
 	assert.Error(t, err)
@@ -217,10 +310,86 @@ func TestSyncService_AddToQueue(t *testing.T) {
 	assert.GreaterOrEqual(t, count, 1, "Task creation should add item to sync queue")
 }
 
+func TestSyncService_CompressedTaskData(t *testing.T) {
+	old := models.TaskDataCompressThreshold
+	models.TaskDataCompressThreshold = 1024
+	defer func() { models.TaskDataCompressThreshold = old }()
+
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	small, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Small"})
+	require.NoError(t, err)
+	large, err := taskService.CreateTask(&models.CreateTaskRequest{
+		Title:       "Large",
+		Description: stringPtr(strings.Repeat("lots of detail ", 150)),
+	})
+	require.NoError(t, err)
+
+	var compressed bool
+	var stored string
+	require.NoError(t, db.QueryRow(`SELECT compressed, task_data FROM sync_queue WHERE task_id = ?`, large.ID).
+		Scan(&compressed, &stored))
+	assert.True(t, compressed)
+	assert.NotContains(t, stored, "lots of detail")
+	require.NoError(t, db.QueryRow(`SELECT compressed FROM sync_queue WHERE task_id = ?`, small.ID).Scan(&compressed))
+	assert.False(t, compressed)
+
+	// Both reach the server as the original task
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.ElementsMatch(t, []string{"create:" + small.ID, "create:" + large.ID}, fake.calls)
+
+	synced, err := taskService.GetTaskByID(large.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusSynced, synced.SyncStatus)
+	assert.Equal(t, *large.Description, *synced.Description)
+}
+
+func TestSyncService_AddToQueueDeduplicates(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Queued once"})
+	require.NoError(t, err)
+
+	updates := func() (count, retries int) {
+		err := db.QueryRow(`SELECT COUNT(*), COALESCE(MAX(retry_count), 0) FROM sync_queue
+            WHERE task_id = ? AND operation_type = 'update'`, task.ID).Scan(&count, &retries)
+		require.NoError(t, err)
+		return count, retries
+	}
+
+	// Re-adding the same operation, as after a crash, keeps one row
+	require.NoError(t, syncService.AddToQueue(task.ID, models.OperationTypeUpdate, task))
+	require.NoError(t, syncService.AddToQueue(task.ID, models.OperationTypeUpdate, task))
+	count, _ := updates()
+	assert.Equal(t, 1, count)
+
+	// A dead-lettered duplicate gets its retries back instead
+	_, err = db.Exec("UPDATE sync_queue SET retry_count = 3 WHERE task_id = ? AND operation_type = 'update'", task.ID)
+	require.NoError(t, err)
+	require.NoError(t, syncService.AddToQueue(task.ID, models.OperationTypeUpdate, task))
+	count, retries := updates()
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 0, retries)
+
+	// A different payload is a new operation
+	changed := *task
+	changed.Title = "Changed"
+	require.NoError(t, syncService.AddToQueue(task.ID, models.OperationTypeUpdate, &changed))
+	count, _ = updates()
+	assert.Equal(t, 2, count)
+}
+
 func TestSyncService_ProcessSyncQueue(t *testing.T) {
 	taskService, syncService, db, cleanup := setupTestServices()
 	defer cleanup()
 
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
 	// Create some tasks to generate queue items
 	task1, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Task 1"})
 	require.NoError(t, err)
@@ -237,26 +406,119 @@ func TestSyncService_ProcessSyncQueue(t *testing.T) {
 	// Process sync queue
 	err = syncService.ProcessSyncQueue()
 	require.NoError(t, err)
+	assert.Equal(t, []string{"create:" + task1.ID, "create:" + task2.ID}, fake.calls)
 
-	// Give it a moment to process (since sync includes simulated delay)
-	time.Sleep(100 * time.Millisecond)
-
-	// Check that some items might have been processed (depending on simulated failures)
-	// Note: Due to simulated failures, not all items may be processed in one run
 	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue").Scan(&queueCount)
 	require.NoError(t, err)
+	assert.Equal(t, 0, queueCount)
 
-	// Queue count should be same or less (some items may have been processed successfully)
-	assert.LessOrEqual(t, queueCount, 2)
+	// Both tasks are now synced
+	for _, id := range []string{task1.ID, task2.ID} {
+		retrieved, err := taskService.GetTaskByID(id)
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusSynced, retrieved.SyncStatus)
+	}
+}
+
+func TestSyncService_MaxRetriesByOperation(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:          ":memory:",
+		SyncBatchSize:         10,
+		MaxRetries:            2,
+		MaxRetriesByOperation: map[string]int{"delete": 5},
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
+	doomed, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Deleted later"})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	// Both operations fail on every attempt
+	fake.err = errors.New("server down")
+	fake.calls = nil
+	require.NoError(t, taskService.DeleteTask(doomed.ID))
+	created, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Never created"})
+	require.NoError(t, err)
+
+	for i := 0; i < 8; i++ {
+		require.NoError(t, syncService.ProcessSyncQueue())
+	}
+
+	attempts := map[string]int{}
+	for _, call := range fake.calls {
+		attempts[call]++
+	}
+	assert.Equal(t, 2, attempts["create:"+created.ID])
+	assert.Equal(t, 5, attempts["delete:"+doomed.ID])
+
+	status, err := syncService.GetSyncStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.PendingCount)
+	assert.Equal(t, 2, status.DeadLetterCount)
+
+	deadLetters, total, err := syncService.GetDeadLetters("", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	for _, item := range deadLetters {
+		if item.OperationType == models.OperationTypeDelete {
+			assert.Equal(t, 5, item.RetryCount)
+		} else {
+			assert.Equal(t, 2, item.RetryCount)
+		}
+	}
+}
+
+func TestSyncService_ProcessSyncQueue_Priority(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:   ":memory:",
+		SyncBatchSize:  10,
+		MaxRetries:     3,
+		SyncPriorities: map[string]int{"delete": 2, "update": 1},
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
 
-	// Verify tasks exist and check their sync status
-	retrievedTask1, err := taskService.GetTaskByID(task1.ID)
+	first, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "First"})
 	require.NoError(t, err)
-	assert.NotNil(t, retrievedTask1)
+	second, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Second"})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(first.ID, &models.UpdateTaskRequest{Title: stringPtr("First, edited")})
+	require.NoError(t, err)
+	third, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Third"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(second.ID))
 
-	retrievedTask2, err := taskService.GetTaskByID(task2.ID)
+	plan, err := syncService.DryRunSync()
 	require.NoError(t, err)
-	assert.NotNil(t, retrievedTask2)
+	var order []string
+	for _, item := range plan {
+		order = append(order, string(item.OperationType)+":"+item.TaskID)
+	}
+	// The deleted task goes first, then the updated one, then the rest; each
+	// task's own operations stay in the order they were queued
+	assert.Equal(t, []string{
+		"create:" + second.ID,
+		"delete:" + second.ID,
+		"create:" + first.ID,
+		"update:" + first.ID,
+		"create:" + third.ID,
+	}, order)
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, order, fake.calls)
 }
 
 func TestSyncService_GetSyncStatus(t *testing.T) {
@@ -281,147 +543,3466 @@ func TestSyncService_GetSyncStatus(t *testing.T) {
 	assert.False(t, status.InProgress)
 }
 
+func TestSyncService_GetSyncStatusForUser(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	alice, err := taskService.ForUser("alice").CreateTask(&models.CreateTaskRequest{Title: "Alice's task"})
+	require.NoError(t, err)
+	for _, title := range []string{"Bob's first", "Bob's second"} {
+		_, err := taskService.ForUser("bob").CreateTask(&models.CreateTaskRequest{Title: title})
+		require.NoError(t, err)
+	}
+
+	// Alice's item is dead-lettered and her task marked as failed
+	_, err = db.Exec(`UPDATE sync_queue SET retry_count = 3 WHERE task_id = ?`, alice.ID)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE tasks SET sync_status = 'error' WHERE id = ?`, alice.ID)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		user                     string
+		pending, errors, letters int
+	}{
+		{"alice", 0, 1, 1},
+		{"bob", 2, 0, 0},
+		{"someone-else", 0, 0, 0},
+	} {
+		status, err := syncService.ForUser(tc.user).GetSyncStatus()
+		require.NoError(t, err)
+		assert.Equal(t, tc.pending, status.PendingCount, tc.user)
+		assert.Equal(t, tc.errors, status.ErrorCount, tc.user)
+		assert.Equal(t, tc.letters, status.DeadLetterCount, tc.user)
+		assert.Equal(t, tc.pending > 0, status.OldestPendingAge > 0, tc.user)
+	}
+
+	// The unscoped service counts everyone's
+	status, err := syncService.GetSyncStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.PendingCount)
+	assert.Equal(t, 1, status.ErrorCount)
+	assert.Equal(t, 1, status.DeadLetterCount)
+}
+
 func TestSyncService_RetryLogic(t *testing.T) {
 	taskService, syncService, db, cleanup := setupTestServices()
 	defer cleanup()
 
-	// Create a task first so it exists in the tasks table
+	// The server fails twice, then accepts the push
+	serverErr := errors.New("server returned 500")
+	fake := &fakeSyncClient{script: []error{serverErr, serverErr, nil}}
+	syncService.SetClient(fake)
+
 	task, err := taskService.CreateTask(&models.CreateTaskRequest{
 		Title: "Test Task for Retry",
 	})
 	require.NoError(t, err)
 
-	// Process sync queue multiple times to test retry logic
-	for i := 0; i < 3; i++ {
-		err = syncService.ProcessSyncQueue()
+	retryCount := func() int {
+		var count int
+		err := db.QueryRow("SELECT retry_count FROM sync_queue WHERE task_id = ?", task.ID).Scan(&count)
 		require.NoError(t, err)
-		time.Sleep(10 * time.Millisecond)
+		return count
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		require.NoError(t, syncService.ProcessSyncQueue())
+		assert.Equal(t, attempt, retryCount())
 	}
 
-	// Check that items exist in sync queue (some may have been processed)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Len(t, fake.calls, 3)
+
 	var queueCount int
 	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", task.ID).Scan(&queueCount)
 	require.NoError(t, err)
+	assert.Equal(t, 0, queueCount)
 
-	// Should have at least one item (create operation)
-	assert.GreaterOrEqual(t, queueCount, 0, "Should have sync queue items")
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
 }
 
-func TestSyncService_ConflictResolution(t *testing.T) {
-	_, syncService, _, cleanup := setupTestServices()
-	defer cleanup()
+func TestSyncService_QueueItemTTL(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:  ":memory:",
+		SyncBatchSize: 10,
+		MaxRetries:    3,
+		QueueItemTTL:  time.Hour,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
 
-	// Test conflict resolution (this is a placeholder since the current implementation
-	// just logs the resolution)
-	err := syncService.ResolveConflicts()
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+	syncService.SetClient(&fakeSyncClient{err: errors.New("server returned 500")})
+
+	old, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Old"})
+	require.NoError(t, err)
+	fresh, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Fresh"})
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE sync_queue SET created_at = ? WHERE task_id = ?`, time.Now().Add(-2*time.Hour), old.ID)
 	require.NoError(t, err)
 
-	// In a real implementation, we would test actual conflict scenarios
-	// For now, we just verify the method doesn't error
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	deadLetters, total, err := syncService.GetDeadLetters("", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	assert.Equal(t, old.ID, deadLetters[0].TaskID)
+	assert.Equal(t, "expired", *deadLetters[0].ErrorMessage)
+
+	var retryCount int
+	var errorMessage string
+	require.NoError(t, db.QueryRow(`SELECT retry_count, error_message FROM sync_queue WHERE task_id = ?`, fresh.ID).
+		Scan(&retryCount, &errorMessage))
+	assert.Equal(t, 1, retryCount)
+	assert.Equal(t, "server returned 500", errorMessage)
+
+	stored, err := taskService.GetTaskByID(old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusError, stored.SyncStatus)
 }
 
-func TestTaskService_IntegrationWithSync(t *testing.T) {
-	taskService, syncService, db, cleanup := setupTestServices()
-	defer cleanup()
+// gatedSyncClient signals started on its first push and holds every push until
+// release is closed.
+type gatedSyncClient struct {
+	once    sync.Once
+	started chan struct{}
+	release chan struct{}
+}
 
-	// Create a task
-	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Integration Test"})
-	require.NoError(t, err)
+func (g *gatedSyncClient) push(task *models.Task) (*models.Task, error) {
+	g.once.Do(func() { close(g.started) })
+	<-g.release
+	copied := *task
+	copied.ServerID = stringPtr("srv_" + task.ID)
+	return &copied, nil
+}
 
-	// Verify it's in sync queue
-	var queueCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", task.ID).Scan(&queueCount)
-	require.NoError(t, err)
-	assert.Equal(t, 1, queueCount)
+func (g *gatedSyncClient) CreateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return g.push(task)
+}
 
-	// Update the task
-	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{
-		Title: stringPtr("Updated Integration Test"),
-	})
-	require.NoError(t, err)
+func (g *gatedSyncClient) UpdateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return g.push(task)
+}
 
-	// Should now have 2 items in queue (create + update)
-	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", task.ID).Scan(&queueCount)
+func (g *gatedSyncClient) DeleteTask(ctx context.Context, task *models.Task) error {
+	_, err := g.push(task)
+	return err
+}
+
+func TestSyncService_LockSkipsConcurrentRuns(t *testing.T) {
+	// Two instances sharing one database file
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	cfg := &config.Config{SyncBatchSize: 10, MaxRetries: 3, SyncLockTTL: time.Minute}
+
+	dbA, err := database.NewSQLiteDB(path)
 	require.NoError(t, err)
-	assert.Equal(t, 2, queueCount)
+	defer dbA.Close()
+	dbB, err := database.NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer dbB.Close()
 
-	// Delete the task
-	err = taskService.DeleteTask(task.ID)
+	syncA := services.NewSyncService(dbA, cfg)
+	syncB := services.NewSyncService(dbB, cfg)
+	taskService := services.NewTaskService(dbA, syncA)
+
+	gated := &gatedSyncClient{started: make(chan struct{}), release: make(chan struct{})}
+	syncA.SetClient(gated)
+	fakeB := &fakeSyncClient{}
+	syncB.SetClient(fakeB)
+
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Pushed once"})
 	require.NoError(t, err)
 
-	// Should now have 3 items in queue (create + update + delete)
-	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", task.ID).Scan(&queueCount)
+	done := make(chan error, 1)
+	go func() { done <- syncA.ProcessSyncQueue() }()
+	<-gated.started
+
+	// A holds the lock while its push is in flight
+	assert.ErrorIs(t, syncB.ProcessSyncQueue(), services.ErrSyncInProgress)
+	assert.ErrorIs(t, syncA.ProcessSyncQueue(), services.ErrSyncInProgress)
+	assert.Empty(t, fakeB.calls)
+
+	status, err := syncB.GetSyncStatus()
 	require.NoError(t, err)
-	assert.Equal(t, 3, queueCount)
+	assert.True(t, status.InProgress)
 
-	// Process sync queue
-	err = syncService.ProcessSyncQueue()
+	close(gated.release)
+	require.NoError(t, <-done)
+
+	// Once A is done the lock is free again and the queue is empty
+	require.NoError(t, syncB.ProcessSyncQueue())
+	assert.Empty(t, fakeB.calls)
+
+	status, err = syncB.GetSyncStatus()
 	require.NoError(t, err)
+	assert.False(t, status.InProgress)
 
-	// Verify task is still soft deleted (not returned by GetAllTasks)
-	tasks, err := taskService.GetAllTasks()
+	// A lock left behind by a crashed instance lapses when it expires
+	_, err = dbB.Exec(`INSERT INTO sync_locks (name, holder, acquired_at, expires_at) VALUES (?, ?, ?, ?)`,
+		"sync_queue", "crashed", time.Now().Add(-2*time.Minute), time.Now().Add(-time.Minute))
 	require.NoError(t, err)
-	assert.Len(t, tasks, 0)
+	assert.NoError(t, syncB.ProcessSyncQueue())
 }
 
-func TestTaskService_ConcurrentOperations(t *testing.T) {
-	taskService, _, _, cleanup := setupTestServices()
-	defer cleanup()
+func TestSyncService_ClaimBatchIsExclusive(t *testing.T) {
+	cfg := &config.Config{SyncBatchSize: 5, MaxRetries: 3, SyncClaimTimeout: time.Minute}
 
-	// Reduce concurrency to avoid database locking issues with shared cache
-	const numTasks = 5
-	taskCh := make(chan *models.Task, numTasks)
-	errCh := make(chan error, numTasks)
+	db, err := database.NewSQLiteDBWithPool(filepath.Join(t.TempDir(), "tasks.db"), database.PoolConfig{
+		BusyTimeoutMS: 5000,
+	})
+	require.NoError(t, err)
+	defer db.Close()
 
-	// Use a wait group to ensure all goroutines complete
-	var wg sync.WaitGroup
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
 
+	const numTasks = 20
 	for i := 0; i < numTasks; i++ {
+		_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Claim %d", i)})
+		require.NoError(t, err)
+	}
+
+	// Claimers race for the same rows until the queue is exhausted
+	const claimers = 8
+	var mu sync.Mutex
+	claimedBy := make(map[int]string)
+	duplicates := 0
+	var wg sync.WaitGroup
+	errCh := make(chan error, claimers)
+	for i := 0; i < claimers; i++ {
 		wg.Add(1)
-		go func(i int) {
+		go func() {
 			defer wg.Done()
-
-			// Add small delay to reduce contention
-			time.Sleep(time.Duration(i*10) * time.Millisecond)
-
-			task, err := taskService.CreateTask(&models.CreateTaskRequest{
-				Title: fmt.Sprintf("Concurrent Task %d", i),
-			})
-			if err != nil {
-				errCh <- err
-				return
+			for {
+				claimID, items, err := syncService.ClaimBatch(syncService.DefaultSyncOptions())
+				if err != nil {
+					errCh <- err
+					return
+				}
+				if len(items) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, item := range items {
+					if _, ok := claimedBy[item.ID]; ok {
+						duplicates++
+					}
+					claimedBy[item.ID] = claimID
+				}
+				mu.Unlock()
 			}
-			taskCh <- task
-		}(i)
+		}()
 	}
-
-	// Wait for all goroutines to complete
 	wg.Wait()
-	close(taskCh)
 	close(errCh)
+	for err := range errCh {
+		t.Errorf("Concurrent claim error: %v", err)
+	}
 
-	// Collect results
-	var createdTasks []*models.Task
-	var errors []error
+	assert.Zero(t, duplicates, "an item was claimed twice")
+	assert.Len(t, claimedBy, numTasks)
 
-	for task := range taskCh {
-		createdTasks = append(createdTasks, task)
-	}
+	// Claimed items are not offered again until released
+	_, items, err := syncService.ClaimBatch(syncService.DefaultSyncOptions())
+	require.NoError(t, err)
+	assert.Empty(t, items)
 
-	for err := range errCh {
-		errors = append(errors, err)
+	var firstID int
+	for id := range claimedBy {
+		firstID = id
+		break
 	}
-
-	// Report any errors but don't fail the test if we got some successful operations
-	for _, err := range errors {
-		t.Logf("Concurrent operation error: %v", err)
+	require.NoError(t, syncService.ReleaseClaim(claimedBy[firstID]))
+	_, items, err = syncService.ClaimBatch(syncService.DefaultSyncOptions())
+	require.NoError(t, err)
+	assert.NotEmpty(t, items)
+	for _, item := range items {
+		assert.Equal(t, claimedBy[firstID], claimedBy[item.ID])
 	}
 
-	// We should have created at least some tasks
-	assert.GreaterOrEqual(t, len(createdTasks), numTasks-2, "Should create most tasks successfully")
+	// A stale claim can be taken over
+	_, err = db.Exec(`UPDATE sync_queue SET claimed_at = ? WHERE id = ?`, time.Now().Add(-2*time.Minute), firstID)
+	require.NoError(t, err)
+	_, items, err = syncService.ClaimBatch(syncService.DefaultSyncOptions())
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, firstID, items[0].ID)
+}
 
-	// Verify tasks were persisted
-	allTasks, err := taskService.GetAllTasks()
+func TestSyncService_ProcessSyncQueueReleasesClaims(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	synced, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Synced"})
 	require.NoError(t, err)
-	assert.GreaterOrEqual(t, len(allTasks), len(createdTasks), "All created tasks should be persisted")
+	failed, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Failed"})
+	require.NoError(t, err)
+	syncService.SetClient(&fakeSyncClient{failTasks: map[string]error{failed.ID: errors.New("server returned 500")}})
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	var remaining, claimed int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sync_queue WHERE task_id = ?`, synced.ID).Scan(&remaining))
+	assert.Zero(t, remaining)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sync_queue WHERE claimed_by IS NOT NULL`).Scan(&claimed))
+	assert.Zero(t, claimed)
+
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, failed.ID, items[0].TaskID)
+	assert.Nil(t, items[0].ClaimedBy)
+}
+
+func TestTaskService_CreateTasks(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:        ":memory:",
+		SyncBatchSize:       10,
+		MaxRetries:          3,
+		EnforceUniqueTitles: true,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+
+	// The second entry repeats the first one's title
+	reqs := func() []*models.CreateTaskRequest {
+		return []*models.CreateTaskRequest{{Title: "Same"}, {Title: "Same"}, {Title: "Other"}}
+	}
+
+	_, err = taskService.CreateTasks(reqs(), false)
+	var itemErr *services.BatchItemError
+	require.ErrorAs(t, err, &itemErr)
+	assert.Equal(t, 1, itemErr.Index)
+	assert.ErrorIs(t, err, services.ErrDuplicateTitle)
+
+	tasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	results, err := taskService.CreateTasks(reqs(), true)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	require.NotNil(t, results[0].Task)
+	assert.ErrorIs(t, results[1].Err, services.ErrDuplicateTitle)
+	assert.Nil(t, results[1].Task)
+	require.NotNil(t, results[2].Task)
+
+	// The rejected entry left nothing behind
+	tasks, err = taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, results[0].Task.ID, items[0].TaskID)
+	assert.Equal(t, results[2].Task.ID, items[1].TaskID)
+}
+
+func TestSyncService_InjectedFailures(t *testing.T) {
+	serverErr := errors.New("server returned 500")
+
+	cases := map[string]struct {
+		fake func(ids []string) *fakeSyncClient
+		// failedAt is the index of the task whose push fails, or -1
+		failedAt int
+	}{
+		"always succeeds": {
+			fake:     func([]string) *fakeSyncClient { return &fakeSyncClient{} },
+			failedAt: -1,
+		},
+		"fails the second call": {
+			fake: func([]string) *fakeSyncClient {
+				return &fakeSyncClient{script: failingNthCall(2, serverErr)}
+			},
+			failedAt: 1,
+		},
+		"fails one task": {
+			fake: func(ids []string) *fakeSyncClient {
+				return &fakeSyncClient{failTasks: map[string]error{ids[0]: serverErr}}
+			},
+			failedAt: 0,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			taskService, syncService, db, cleanup := setupTestServices()
+			defer cleanup()
+
+			var ids []string
+			for i := 0; i < 3; i++ {
+				task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Task %d", i)})
+				require.NoError(t, err)
+				ids = append(ids, task.ID)
+			}
+
+			fake := tc.fake(ids)
+			syncService.SetClient(fake)
+			require.NoError(t, syncService.ProcessSyncQueue())
+			assert.Len(t, fake.calls, 3)
+
+			// Only the failed push stays queued, with one retry counted
+			var remaining []string
+			rows, err := db.Query("SELECT task_id FROM sync_queue WHERE retry_count = 1")
+			require.NoError(t, err)
+			for rows.Next() {
+				var id string
+				require.NoError(t, rows.Scan(&id))
+				remaining = append(remaining, id)
+			}
+			require.NoError(t, rows.Close())
+
+			var queueCount int
+			require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue").Scan(&queueCount))
+			if tc.failedAt < 0 {
+				assert.Equal(t, 0, queueCount)
+			} else {
+				assert.Equal(t, 1, queueCount)
+				assert.Equal(t, []string{ids[tc.failedAt]}, remaining)
+			}
+		})
+	}
+}
+
+func TestSyncService_RetriesStopAtMaxRetries(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{err: errors.New("server returned 500")}
+	syncService.SetClient(fake)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Always failing"})
+	require.NoError(t, err)
+
+	// MaxRetries is 3, so the fourth run leaves the item alone
+	for i := 0; i < 4; i++ {
+		require.NoError(t, syncService.ProcessSyncQueue())
+	}
+	assert.Len(t, fake.calls, 3)
+
+	var retryCount int
+	err = db.QueryRow("SELECT retry_count FROM sync_queue WHERE task_id = ?", task.ID).Scan(&retryCount)
+	require.NoError(t, err)
+	assert.Equal(t, 3, retryCount)
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusError, stored.SyncStatus)
+}
+
+func TestSyncService_ServerTimeoutIsRetried(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:    ":memory:",
+		SyncBatchSize:   5,
+		MaxRetries:      3,
+		SyncItemTimeout: 20 * time.Millisecond,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+	syncService.SetClient(&fakeSyncClient{blocking: true})
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Hung server"})
+	require.NoError(t, err)
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	var retryCount int
+	var lastError string
+	err = db.QueryRow("SELECT retry_count, error_message FROM sync_queue WHERE task_id = ?", task.ID).
+		Scan(&retryCount, &lastError)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retryCount)
+	assert.Contains(t, lastError, context.DeadlineExceeded.Error())
+}
+
+func TestSyncService_ConflictResolution(t *testing.T) {
+	_, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	// Test conflict resolution (this is a placeholder since the current implementation
+	// just logs the resolution)
+	err := syncService.ResolveConflicts()
+	require.NoError(t, err)
+
+	// In a real implementation, we would test actual conflict scenarios
+	// For now, we just verify the method doesn't error
+}
+
+func TestSyncService_ResolveConflict_RemoteWins(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	local, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Local title"})
+	require.NoError(t, err)
+
+	remote := *local
+	remote.Title = "Remote title"
+	remote.UpdatedAt = local.UpdatedAt.Add(time.Minute)
+
+	resolved, err := syncService.ResolveConflict(local, &remote)
+	require.NoError(t, err)
+	assert.Equal(t, "Remote title", resolved.Title)
+
+	stored, err := taskService.GetTaskByID(local.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Remote title", stored.Title)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+
+	var count int
+	var winner string
+	err = db.QueryRow("SELECT COUNT(*), MAX(winner) FROM sync_conflicts WHERE task_id = ?", local.ID).Scan(&count, &winner)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, string(models.ConflictWinnerRemote), winner)
+}
+
+func TestSyncService_ResolveConflict_LocalWins(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	local, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Local title"})
+	require.NoError(t, err)
+
+	remote := *local
+	remote.Title = "Stale remote title"
+	remote.UpdatedAt = local.UpdatedAt.Add(-time.Minute)
+
+	resolved, err := syncService.ResolveConflict(local, &remote)
+	require.NoError(t, err)
+	assert.Equal(t, "Local title", resolved.Title)
+
+	stored, err := taskService.GetTaskByID(local.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Local title", stored.Title)
+
+	// The local copy is pushed again
+	var queueCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ? AND operation_type = 'update'", local.ID).Scan(&queueCount)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queueCount)
+
+	conflicts, total, err := syncService.GetConflicts(10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, local.ID, conflicts[0].TaskID)
+	assert.Equal(t, models.ConflictWinnerLocal, conflicts[0].Winner)
+	assert.Contains(t, string(conflicts[0].RemoteData), "Stale remote title")
+}
+
+func TestSyncService_ConflictStrategies(t *testing.T) {
+	cases := []struct {
+		strategy   string
+		wantWinner models.ConflictWinner
+		wantTitle  string
+		wantPushes int
+	}{
+		{strategy: "last_write_wins", wantWinner: models.ConflictWinnerRemote, wantTitle: "Remote title", wantPushes: 0},
+		{strategy: "server_wins", wantWinner: models.ConflictWinnerRemote, wantTitle: "Remote title", wantPushes: 0},
+		{strategy: "client_wins", wantWinner: models.ConflictWinnerLocal, wantTitle: "Local title", wantPushes: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.strategy, func(t *testing.T) {
+			cfg := &config.Config{
+				DatabasePath:     ":memory:",
+				SyncBatchSize:    5,
+				MaxRetries:       3,
+				ConflictStrategy: tc.strategy,
+			}
+			db, err := database.NewSQLiteDB(cfg.DatabasePath)
+			require.NoError(t, err)
+			defer db.Close()
+
+			syncService := services.NewSyncService(db, cfg)
+			taskService := services.NewTaskService(db, syncService)
+
+			// The same pair for every strategy: the remote copy is newer
+			local, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Local title"})
+			require.NoError(t, err)
+			remote := *local
+			remote.Title = "Remote title"
+			remote.UpdatedAt = local.UpdatedAt.Add(time.Minute)
+
+			resolved, err := syncService.ResolveConflict(local, &remote)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTitle, resolved.Title)
+
+			stored, err := taskService.GetTaskByID(local.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTitle, stored.Title)
+
+			var pushes int
+			err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ? AND operation_type = 'update'", local.ID).Scan(&pushes)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPushes, pushes)
+
+			conflicts, _, err := syncService.GetConflicts(10, 0)
+			require.NoError(t, err)
+			require.Len(t, conflicts, 1)
+			assert.Equal(t, tc.wantWinner, conflicts[0].Winner)
+		})
+	}
+}
+
+func TestSyncService_ManualReviewOnStatusConflict(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:                 ":memory:",
+		SyncBatchSize:                5,
+		MaxRetries:                   3,
+		ConflictStrategy:             "server_wins",
+		ManualReviewOnStatusConflict: true,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+
+	t.Run("auto-resolved when completed agrees", func(t *testing.T) {
+		local, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Local title"})
+		require.NoError(t, err)
+		remote := *local
+		remote.Title = "Remote title"
+
+		resolved, err := syncService.ResolveConflict(local, &remote)
+		require.NoError(t, err)
+		assert.Equal(t, "Remote title", resolved.Title)
+
+		stored, err := taskService.GetTaskByID(local.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Remote title", stored.Title)
+		assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+
+		conflicts, _, err := syncService.GetConflicts(1, 0)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.False(t, conflicts[0].NeedsReview)
+
+		_, err = syncService.ResolveReviewedConflict(conflicts[0].ID, models.ConflictWinnerLocal)
+		assert.ErrorIs(t, err, services.ErrConflictNotPending)
+	})
+
+	t.Run("escalated when completed differs", func(t *testing.T) {
+		local, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Local title"})
+		require.NoError(t, err)
+		_, err = db.Exec("DELETE FROM sync_queue WHERE task_id = ?", local.ID)
+		require.NoError(t, err)
+		remote := *local
+		remote.Title = "Remote title"
+		remote.Completed = true
+
+		_, err = syncService.ResolveConflict(local, &remote)
+		require.NoError(t, err)
+
+		// Neither copy is applied while the conflict waits
+		stored, err := taskService.GetTaskByID(local.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Local title", stored.Title)
+		assert.False(t, stored.Completed)
+		assert.Equal(t, models.SyncStatusError, stored.SyncStatus)
+
+		var queued int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", local.ID).Scan(&queued))
+		assert.Equal(t, 0, queued)
+
+		conflicts, _, err := syncService.GetConflicts(1, 0)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.True(t, conflicts[0].NeedsReview)
+		assert.Equal(t, models.ConflictWinnerRemote, conflicts[0].Winner)
+
+		_, err = syncService.ResolveReviewedConflict(conflicts[0].ID, "neither")
+		assert.ErrorIs(t, err, services.ErrInvalidConflictWinner)
+
+		conflict, err := syncService.ResolveReviewedConflict(conflicts[0].ID, models.ConflictWinnerLocal)
+		require.NoError(t, err)
+		assert.Equal(t, models.ConflictWinnerLocal, conflict.Winner)
+		assert.False(t, conflict.NeedsReview)
+
+		stored, err = taskService.GetTaskByID(local.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Local title", stored.Title)
+		assert.Equal(t, models.SyncStatusPending, stored.SyncStatus)
+
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ? AND operation_type = 'update'", local.ID).Scan(&queued))
+		assert.Equal(t, 1, queued)
+
+		_, err = syncService.ResolveReviewedConflict(conflicts[0].ID, models.ConflictWinnerRemote)
+		assert.ErrorIs(t, err, services.ErrConflictNotPending)
+	})
+
+	_, err = syncService.ResolveReviewedConflict(999, models.ConflictWinnerLocal)
+	assert.ErrorIs(t, err, services.ErrConflictNotFound)
+}
+
+// fakeSyncClient records each call. A successful call echoes the pushed task,
+// or reply when it is set. A task listed in failTasks always fails
+// with its error. Other calls take their result from script in order, where a
+// nil entry succeeds; once script is used up they fail with err, or succeed
+// when err is nil. A blocking client waits for the sync context to end, as a
+// hung server would.
+type fakeSyncClient struct {
+	calls     []string
+	remote    *models.Task
+	reply     *models.Task
+	err       error
+	script    []error
+	failTasks map[string]error
+	blocking  bool
+	delay     time.Duration
+}
+
+// failingNthCall returns a script whose nth call (counting from 1) fails with
+// err and whose earlier calls succeed.
+func failingNthCall(n int, err error) []error {
+	script := make([]error, n)
+	script[n-1] = err
+	return script
+}
+
+func (f *fakeSyncClient) respond(ctx context.Context, op string, task *models.Task) (*models.Task, error) {
+	f.calls = append(f.calls, op+":"+task.ID)
+	if f.blocking {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	err := f.err
+	if len(f.script) > 0 {
+		err, f.script = f.script[0], f.script[1:]
+	}
+	if taskErr, ok := f.failTasks[task.ID]; ok {
+		err = taskErr
+	}
+	if err != nil {
+		return f.remote, err
+	}
+	copied := *task
+	if f.reply != nil {
+		copied = *f.reply
+	}
+	copied.ServerID = stringPtr("srv_" + task.ID)
+	return &copied, nil
+}
+
+func (f *fakeSyncClient) CreateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return f.respond(ctx, "create", task)
+}
+
+func (f *fakeSyncClient) UpdateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return f.respond(ctx, "update", task)
+}
+
+func (f *fakeSyncClient) DeleteTask(ctx context.Context, task *models.Task) error {
+	_, err := f.respond(ctx, "delete", task)
+	return err
+}
+
+func TestSyncService_UsesInjectedClient(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Pushed"})
+	require.NoError(t, err)
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, []string{"create:" + task.ID}, fake.calls)
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+	assert.Equal(t, "srv_"+task.ID, *stored.ServerID)
+
+	var queueCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue").Scan(&queueCount)
+	require.NoError(t, err)
+	assert.Equal(t, 0, queueCount)
+}
+
+func TestSyncService_NewWithTransport(t *testing.T) {
+	cfg := &config.Config{DatabasePath: ":memory:", SyncBatchSize: 5, MaxRetries: 3}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The HTTP client is the default transport
+	var _ syncclient.SyncTransport = (*syncclient.Client)(nil)
+
+	fake := &fakeSyncClient{script: []error{syncclient.ErrServerUnavailable}}
+	syncService := services.NewSyncServiceWithTransport(db, cfg, fake)
+	taskService := services.NewTaskService(db, syncService)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Retried once"})
+	require.NoError(t, err)
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+	items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, 1, items[0].RetryCount)
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, []string{"create:" + task.ID, "create:" + task.ID}, fake.calls)
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+}
+
+func TestTaskService_GetByServerID(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	syncService.SetClient(&fakeSyncClient{})
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Known remotely"})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	synced, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	require.NotNil(t, synced.ServerID)
+
+	found, err := taskService.GetByServerID(*synced.ServerID)
+	require.NoError(t, err)
+	assert.Equal(t, task.ID, found.ID)
+
+	// Other users and unknown server IDs find nothing
+	_, err = taskService.ForUser("someone-else").GetByServerID(*synced.ServerID)
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+	_, err = taskService.GetByServerID("srv_unknown")
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+}
+
+func TestSyncService_ProcessSyncQueueForTasks(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
+	var ids []string
+	for _, title := range []string{"Stuck", "Waiting", "Also stuck", "Untouched"} {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: title})
+		require.NoError(t, err)
+		ids = append(ids, task.ID)
+	}
+	// A second change to a targeted task is pushed too
+	_, err := taskService.UpdateTask(ids[0], &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+
+	require.NoError(t, syncService.ProcessSyncQueueForTasks([]string{ids[0], ids[2]}))
+	assert.ElementsMatch(t, []string{"create:" + ids[0], "update:" + ids[0], "create:" + ids[2]}, fake.calls)
+
+	var remaining []string
+	rows, err := db.Query("SELECT task_id FROM sync_queue ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		remaining = append(remaining, id)
+	}
+	assert.Equal(t, []string{ids[1], ids[3]}, remaining)
+
+	fake.calls = nil
+	err = syncService.ProcessSyncQueueForTasks([]string{ids[1], "missing"})
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+	assert.Contains(t, err.Error(), "missing")
+	assert.Empty(t, fake.calls, "nothing is pushed when an ID is unknown")
+
+	// A hard-deleted task is still known while its delete is queued
+	require.NoError(t, taskService.HardDeleteTask(ids[3]))
+	require.NoError(t, syncService.ProcessSyncQueueForTasks([]string{ids[3]}))
+	assert.Equal(t, []string{"delete:" + ids[3]}, fake.calls)
+}
+
+func TestSyncService_ProcessSyncQueueWithProgress(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	// One item fails, which still counts as processed
+	fake := &fakeSyncClient{script: failingNthCall(2, syncclient.ErrServerUnavailable)}
+	syncService.SetClient(fake)
+	for i := 0; i < 3; i++ {
+		_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Task %d", i)})
+		require.NoError(t, err)
+	}
+
+	var reports [][2]int
+	err := syncService.ProcessSyncQueueWithProgress(context.Background(), func(processed, total int) {
+		reports = append(reports, [2]int{processed, total})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][2]int{{1, 3}, {2, 3}, {3, 3}}, reports)
+	assert.Len(t, fake.calls, 3)
+
+	// An empty queue reports nothing
+	reports = nil
+	_, err = db.Exec(`DELETE FROM sync_queue`)
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueueWithProgress(context.Background(), func(processed, total int) {
+		reports = append(reports, [2]int{processed, total})
+	}))
+	assert.Empty(t, reports)
+}
+
+func TestSyncService_ClientConflictIsResolved(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Local"})
+	require.NoError(t, err)
+
+	remote := *task
+	remote.Title = "Newer on server"
+	remote.UpdatedAt = task.UpdatedAt.Add(time.Minute)
+	syncService.SetClient(&fakeSyncClient{remote: &remote, err: syncclient.ErrConflict})
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Newer on server", stored.Title)
+
+	var conflictCount, queueCount int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_conflicts").Scan(&conflictCount))
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue").Scan(&queueCount))
+	assert.Equal(t, 1, conflictCount)
+	assert.Equal(t, 0, queueCount)
+}
+
+func TestSyncService_ConflictKeepsWinningLocalEdit(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Original"})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	updated, err := taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Title: stringPtr("Edited locally")})
+	require.NoError(t, err)
+
+	// The server's copy is older, so the local edit wins and must be pushed again
+	remote := *updated
+	remote.Title = "Stale on server"
+	remote.UpdatedAt = updated.UpdatedAt.Add(-time.Minute)
+	fake.remote = &remote
+	fake.err = syncclient.ErrConflict
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{})
+	require.NoError(t, err)
+	require.Len(t, items, 1, "the winning local edit is still queued")
+	assert.Equal(t, models.OperationTypeUpdate, items[0].OperationType)
+	assert.Zero(t, items[0].RetryCount)
+
+	var conflicts int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_conflicts").Scan(&conflicts))
+	assert.Equal(t, 1, conflicts)
+
+	fake.err = nil
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, []string{"create:" + task.ID, "update:" + task.ID, "update:" + task.ID}, fake.calls)
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited locally", stored.Title)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+}
+
+func TestSyncService_NewerRemoteIsAConflict(t *testing.T) {
+	cases := []struct {
+		name         string
+		title        string
+		offset       time.Duration
+		wantConflict bool
+		wantTitle    string
+	}{
+		{name: "newer and different", title: "Changed on server", offset: time.Minute, wantConflict: true, wantTitle: "Changed on server"},
+		{name: "newer but identical", title: "Local", offset: time.Minute, wantTitle: "Local"},
+		{name: "older and different", title: "Stale on server", offset: -time.Minute, wantTitle: "Local"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			taskService, syncService, db, cleanup := setupTestServices()
+			defer cleanup()
+
+			task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Local"})
+			require.NoError(t, err)
+
+			remote := *task
+			remote.Title = tc.title
+			remote.UpdatedAt = task.UpdatedAt.Add(tc.offset)
+			syncService.SetClient(&fakeSyncClient{reply: &remote})
+
+			require.NoError(t, syncService.ProcessSyncQueue())
+
+			var conflictCount, queueCount int
+			require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_conflicts").Scan(&conflictCount))
+			require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue").Scan(&queueCount))
+			if tc.wantConflict {
+				assert.Equal(t, 1, conflictCount)
+			} else {
+				assert.Equal(t, 0, conflictCount)
+			}
+			assert.Equal(t, 0, queueCount)
+
+			stored, err := taskService.GetTaskByID(task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTitle, stored.Title)
+			assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+		})
+	}
+}
+
+func TestServices_WithCancelledContext(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := taskService.WithContext(ctx).GetAllTasks()
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = taskService.WithContext(ctx).CreateTask(&models.CreateTaskRequest{Title: "Abandoned"})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = syncService.WithContext(ctx).GetSyncStatus()
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The services they were made from are unaffected
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Kept"})
+	require.NoError(t, err)
+	tasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+}
+
+func TestSyncService_PauseAndResume(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Held back"})
+	require.NoError(t, err)
+
+	require.NoError(t, syncService.Pause())
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Empty(t, fake.calls)
+
+	status, err := syncService.GetSyncStatus()
+	require.NoError(t, err)
+	assert.True(t, status.Paused)
+	assert.Equal(t, 1, status.PendingCount)
+
+	// The flag lives in the database, so a new service starts paused
+	restarted := services.NewSyncService(db, &config.Config{SyncBatchSize: 5, MaxRetries: 3})
+	restarted.SetClient(fake)
+	paused, err := restarted.IsPaused()
+	require.NoError(t, err)
+	assert.True(t, paused)
+	require.NoError(t, restarted.ProcessSyncQueue())
+	assert.Empty(t, fake.calls)
+
+	require.NoError(t, syncService.Resume())
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Len(t, fake.calls, 1)
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+
+	status, err = syncService.GetSyncStatus()
+	require.NoError(t, err)
+	assert.False(t, status.Paused)
+	assert.Equal(t, 0, status.PendingCount)
+}
+
+func TestSyncService_GetSyncAttempts(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Flaky"})
+	require.NoError(t, err)
+
+	// Pairs of attempts share a timestamp so the id tie-breaker is exercised.
+	// Even-numbered attempts succeed.
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 7; i++ {
+		_, err := db.Exec(`INSERT INTO sync_attempts (task_id, operation_type, success, error_message, attempted_at)
+            VALUES (?, 'update', ?, NULL, ?)`, task.ID, i%2 == 0, base.Add(time.Duration(i/2)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	var ids []int
+	var pages int
+	cursor := ""
+	for {
+		attempts, next, err := syncService.GetSyncAttempts(nil, cursor, 3)
+		require.NoError(t, err)
+		pages++
+		for _, attempt := range attempts {
+			ids = append(ids, attempt.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []int{7, 6, 5, 4, 3, 2, 1}, ids)
+
+	failed := false
+	failures, next, err := syncService.GetSyncAttempts(&failed, "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	require.Len(t, failures, 3)
+	for _, attempt := range failures {
+		assert.False(t, attempt.Success)
+	}
+
+	succeeded := true
+	successes, next, err := syncService.GetSyncAttempts(&succeeded, "", 2)
+	require.NoError(t, err)
+	require.Len(t, successes, 2)
+	assert.Equal(t, 7, successes[0].ID)
+	assert.Equal(t, 5, successes[1].ID)
+	successes, _, err = syncService.GetSyncAttempts(&succeeded, next, 2)
+	require.NoError(t, err)
+	require.Len(t, successes, 2)
+	assert.Equal(t, 3, successes[0].ID)
+	assert.Equal(t, 1, successes[1].ID)
+
+	_, _, err = syncService.GetSyncAttempts(nil, "not-a-cursor", 3)
+	assert.ErrorIs(t, err, services.ErrInvalidCursor)
+}
+
+func TestTaskService_GetSyncHistory(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Flaky"})
+	require.NoError(t, err)
+
+	// First attempt fails, second succeeds
+	fake := &fakeSyncClient{err: syncclient.ErrServerUnavailable}
+	syncService.SetClient(fake)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	fake.err = nil
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	history, err := taskService.GetSyncHistory(task.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	assert.False(t, history[0].Success)
+	require.NotNil(t, history[0].ErrorMessage)
+	assert.Contains(t, *history[0].ErrorMessage, "unavailable")
+	assert.Equal(t, models.OperationTypeCreate, history[0].OperationType)
+
+	assert.True(t, history[1].Success)
+	assert.Nil(t, history[1].ErrorMessage)
+	assert.False(t, history[1].AttemptedAt.Before(history[0].AttemptedAt))
+
+	_, err = taskService.GetSyncHistory("non-existent-id")
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+}
+
+// recordingNotifier captures task events instead of sending them.
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []models.TaskEvent
+}
+
+func (r *recordingNotifier) Notify(event models.TaskEventType, task *models.Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, models.TaskEvent{Event: event, Task: task})
+}
+
+func TestTaskService_NotifiesLifecycleEvents(t *testing.T) {
+	taskService, _, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	recorder := &recordingNotifier{}
+	taskService.SetNotifier(recorder)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Notify me"})
+	require.NoError(t, err)
+
+	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Title: stringPtr("Renamed")})
+	require.NoError(t, err)
+
+	require.NoError(t, taskService.DeleteTask(task.ID))
+
+	// Failed operations emit nothing
+	_, err = taskService.UpdateTask("non-existent-id", &models.UpdateTaskRequest{Title: stringPtr("Nope")})
+	require.Error(t, err)
+
+	require.Len(t, recorder.events, 3)
+
+	assert.Equal(t, models.TaskEventCreated, recorder.events[0].Event)
+	assert.Equal(t, "Notify me", recorder.events[0].Task.Title)
+
+	assert.Equal(t, models.TaskEventUpdated, recorder.events[1].Event)
+	assert.Equal(t, "Renamed", recorder.events[1].Task.Title)
+
+	assert.Equal(t, models.TaskEventDeleted, recorder.events[2].Event)
+	assert.Equal(t, task.ID, recorder.events[2].Task.ID)
+	assert.True(t, recorder.events[2].Task.IsDeleted)
+}
+
+func TestSyncService_GetQueueItem(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	syncService.SetClient(&fakeSyncClient{err: errors.New("server down")})
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Stuck", Description: stringPtr("Won't sync")})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	detail, err := syncService.GetQueueItem(items[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, task.ID, detail.TaskID)
+	assert.Equal(t, models.OperationTypeCreate, detail.OperationType)
+	assert.Equal(t, 1, detail.RetryCount)
+	assert.NotNil(t, detail.LastAttempt)
+	require.NotNil(t, detail.ErrorMessage)
+	assert.Equal(t, "server down", *detail.ErrorMessage)
+	assert.False(t, detail.DeadLettered)
+	require.NotNil(t, detail.TaskData)
+	assert.Equal(t, "Stuck", detail.TaskData.Title)
+	assert.Equal(t, "Won't sync", *detail.TaskData.Description)
+	assert.Empty(t, detail.DecodeError)
+
+	// A corrupt payload is still inspectable
+	_, err = db.Exec(`UPDATE sync_queue SET task_data = 'not json' WHERE id = ?`, items[0].ID)
+	require.NoError(t, err)
+	detail, err = syncService.GetQueueItem(items[0].ID)
+	require.NoError(t, err)
+	assert.Nil(t, detail.TaskData)
+	assert.NotEmpty(t, detail.DecodeError)
+
+	_, err = syncService.GetQueueItem(items[0].ID + 100)
+	assert.ErrorIs(t, err, services.ErrQueueItemNotFound)
+}
+
+func TestSyncService_DryRunSync(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Planned"})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+
+	before, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+
+	plan, err := syncService.DryRunSync()
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	assert.Equal(t, task.ID, plan[0].TaskID)
+	assert.Equal(t, models.OperationTypeCreate, plan[0].OperationType)
+	assert.Equal(t, models.OperationTypeUpdate, plan[1].OperationType)
+	assert.Equal(t, 0, plan[0].RetryCount)
+
+	// Nothing was pushed and the queue is untouched
+	assert.Empty(t, fake.calls)
+	after, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusPending, stored.SyncStatus)
+}
+
+func TestSyncService_RetryJitter(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:       ":memory:",
+		SyncBatchSize:      5,
+		MaxRetries:         3,
+		RetryBaseDelay:     time.Minute,
+		RetryJitterPercent: 50,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+	syncService.SeedJitter(42)
+	syncService.SetClient(&fakeSyncClient{err: syncclient.ErrServerUnavailable})
+
+	first, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "First"})
+	require.NoError(t, err)
+	second, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Second"})
+	require.NoError(t, err)
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	offsets := make(map[string]time.Duration)
+	for _, item := range items {
+		require.Equal(t, 1, item.RetryCount)
+		require.NotNil(t, item.NextAttemptAt)
+		offset := item.NextAttemptAt.Sub(*item.LastAttempt)
+		assert.GreaterOrEqual(t, offset, 30*time.Second)
+		assert.LessOrEqual(t, offset, 90*time.Second)
+		offsets[item.TaskID] = offset
+	}
+	assert.NotEqual(t, offsets[first.ID], offsets[second.ID])
+
+	// Items are not retried before their next attempt time
+	require.NoError(t, syncService.ProcessSyncQueue())
+	items, err = syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	for _, item := range items {
+		assert.Equal(t, 1, item.RetryCount)
+	}
+
+	// The same seed gives the same sequence of delays
+	syncService.SeedJitter(7)
+	a := []time.Duration{syncService.RetryDelay(1), syncService.RetryDelay(1)}
+	syncService.SeedJitter(7)
+	b := []time.Duration{syncService.RetryDelay(1), syncService.RetryDelay(1)}
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a[0], a[1])
+}
+
+func TestRetryStrategy_Schedules(t *testing.T) {
+	last := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		strategy services.RetryStrategy
+		delays   []time.Duration
+	}{
+		{"fixed", services.FixedRetry{Delay: time.Minute},
+			[]time.Duration{time.Minute, time.Minute, time.Minute, time.Minute}},
+		{"exponential", services.ExponentialRetry{BaseDelay: time.Minute, MaxDelay: 5 * time.Minute},
+			[]time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute}},
+		{"linear", services.LinearRetry{Step: time.Minute, MaxDelay: 3 * time.Minute},
+			[]time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}},
+		{"linear uncapped", services.LinearRetry{Step: time.Minute},
+			[]time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 4 * time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.delays {
+				assert.Equal(t, last.Add(want), tt.strategy.NextAttempt(i+1, last), "retry %d", i+1)
+			}
+			assert.Equal(t, last, tt.strategy.NextAttempt(0, last))
+			assert.False(t, tt.strategy.ShouldGiveUp(100))
+		})
+	}
+
+	limited := services.LinearRetry{Step: time.Minute, MaxAttempts: 2}
+	assert.False(t, limited.ShouldGiveUp(1))
+	assert.True(t, limited.ShouldGiveUp(2))
+
+	assert.NoError(t, services.ValidateRetryStrategy("linear"))
+	assert.NoError(t, services.ValidateRetryStrategy(""))
+	assert.Error(t, services.ValidateRetryStrategy("random"))
+	assert.Equal(t, services.FixedRetry{Delay: time.Second},
+		services.NewRetryStrategy(&config.Config{RetryStrategy: "fixed", RetryBaseDelay: time.Second, RetryMaxDelay: time.Minute}))
+	assert.Equal(t, services.ExponentialRetry{BaseDelay: time.Second, MaxDelay: time.Minute},
+		services.NewRetryStrategy(&config.Config{RetryBaseDelay: time.Second, RetryMaxDelay: time.Minute}))
+}
+
+func TestSyncService_RetryStrategy(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:   ":memory:",
+		SyncBatchSize:  5,
+		MaxRetries:     5,
+		RetryBaseDelay: time.Minute,
+		RetryMaxDelay:  time.Hour,
+		RetryStrategy:  "linear",
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+	syncService.SetClient(&fakeSyncClient{err: syncclient.ErrServerUnavailable})
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Linear"})
+	require.NoError(t, err)
+
+	// The configured strategy schedules the next attempt
+	require.NoError(t, syncService.ProcessSyncQueue())
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.NotNil(t, items[0].NextAttemptAt)
+	assert.Equal(t, time.Minute, items[0].NextAttemptAt.Sub(*items[0].LastAttempt))
+
+	// An injected strategy that gives up dead-letters the item under MAX_RETRIES
+	syncService.SetRetryStrategy(services.FixedRetry{MaxAttempts: 2})
+	_, err = db.Exec(`UPDATE sync_queue SET next_attempt_at = NULL`)
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	deadLetters, total, err := syncService.GetDeadLetters(task.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, deadLetters, 1)
+	assert.Contains(t, *deadLetters[0].ErrorMessage, "retry strategy gave up after 2 attempts")
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusError, stored.SyncStatus)
+
+	pending, err := syncService.GetPendingCount()
+	require.NoError(t, err)
+	assert.Equal(t, 0, pending)
+}
+
+func TestSyncService_StatusQueueAge(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	// Empty queue reports no age
+	status, err := syncService.GetSyncStatus()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), status.OldestPendingAge)
+	assert.Equal(t, 0, status.DeadLetterCount)
+
+	oldest, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Oldest"})
+	require.NoError(t, err)
+	newer, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Newer"})
+	require.NoError(t, err)
+	dead, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Dead"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	_, err = db.Exec("UPDATE sync_queue SET created_at = ? WHERE task_id = ?", now.Add(-2*time.Hour), oldest.ID)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE sync_queue SET created_at = ? WHERE task_id = ?", now.Add(-10*time.Minute), newer.ID)
+	require.NoError(t, err)
+	// Dead-lettered items are older still but no longer count as pending
+	_, err = db.Exec("UPDATE sync_queue SET created_at = ?, retry_count = 3 WHERE task_id = ?", now.Add(-24*time.Hour), dead.ID)
+	require.NoError(t, err)
+
+	status, err = syncService.GetSyncStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.PendingCount)
+	assert.Equal(t, 1, status.DeadLetterCount)
+	assert.GreaterOrEqual(t, status.OldestPendingAge, 2*time.Hour)
+	assert.Less(t, status.OldestPendingAge, 2*time.Hour+time.Minute)
+}
+
+func TestTaskService_GetTasksAfter(t *testing.T) {
+	taskService, _, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	// Several tasks share an updated_at so the id tie-breaker is exercised
+	base := time.Now().Add(-time.Hour)
+	created := make(map[string]bool)
+	for i := 0; i < 7; i++ {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Task %d", i)})
+		require.NoError(t, err)
+		_, err = db.Exec("UPDATE tasks SET updated_at = ? WHERE id = ?", base.Add(time.Duration(i/3)*time.Minute), task.ID)
+		require.NoError(t, err)
+		created[task.ID] = true
+	}
+
+	seen := make(map[string]bool)
+	var pages int
+	cursor := ""
+	for {
+		tasks, next, err := taskService.GetTasksAfter(cursor, 3)
+		require.NoError(t, err)
+		pages++
+		for _, task := range tasks {
+			assert.False(t, seen[task.ID], "task %s returned twice", task.ID)
+			seen[task.ID] = true
+		}
+		if next == "" {
+			break
+		}
+		require.Len(t, tasks, 3)
+		cursor = next
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, created, seen)
+
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		_, _, err := taskService.GetTasksAfter(cursor, 3)
+		assert.ErrorIs(t, err, services.ErrInvalidCursor, cursor)
+	}
+}
+
+func TestTaskService_ForceResync(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	syncService.SetClient(&fakeSyncClient{})
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Stale on server"})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	synced, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.SyncStatusSynced, synced.SyncStatus)
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Empty(t, items)
+
+	resynced, err := taskService.ForceResync(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusPending, resynced.SyncStatus)
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusPending, stored.SyncStatus)
+
+	items, err = syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, task.ID, items[0].TaskID)
+	assert.Equal(t, models.OperationTypeUpdate, items[0].OperationType)
+
+	_, err = taskService.ForceResync("missing")
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+}
+
+func TestSyncService_ListSyncQueue(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	first, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "First"})
+	require.NoError(t, err)
+	second, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Second"})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(first.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(second.ID))
+
+	_, err = db.Exec("UPDATE sync_queue SET retry_count = 2 WHERE task_id = ? AND operation_type = 'create'", second.ID)
+	require.NoError(t, err)
+
+	t.Run("operation type", func(t *testing.T) {
+		items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{OperationType: models.OperationTypeCreate})
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		for _, item := range items {
+			assert.Equal(t, models.OperationTypeCreate, item.OperationType)
+		}
+	})
+
+	t.Run("min retries", func(t *testing.T) {
+		items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{MinRetries: 1})
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, second.ID, items[0].TaskID)
+		assert.Equal(t, 2, items[0].RetryCount)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{Limit: 3})
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		assert.Equal(t, first.ID, items[0].TaskID)
+
+		all, err := syncService.GetSyncQueueContents()
+		require.NoError(t, err)
+		assert.Len(t, all, 4)
+	})
+}
+
+func TestSyncService_RecordsRuns(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
+	for _, title := range []string{"First", "Second"} {
+		_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: title})
+		require.NoError(t, err)
+	}
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Third"})
+	require.NoError(t, err)
+	fake.err = syncclient.ErrServerUnavailable
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	runs, err := syncService.GetSyncRuns(10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+
+	// Newest first
+	assert.Equal(t, 1, runs[0].Processed)
+	assert.Equal(t, 0, runs[0].Succeeded)
+	assert.Equal(t, 1, runs[0].Failed)
+
+	assert.Equal(t, 2, runs[1].Processed)
+	assert.Equal(t, 2, runs[1].Succeeded)
+	assert.Equal(t, 0, runs[1].Failed)
+	assert.False(t, runs[1].FinishedAt.Before(runs[1].StartedAt))
+
+	runs, err = syncService.GetSyncRuns(1)
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+}
+
+func TestSyncService_ResetQueue(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	ok, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Queued"})
+	require.NoError(t, err)
+	failed, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Dead-lettered"})
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE sync_queue SET retry_count = 3 WHERE task_id = ?", failed.ID)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE tasks SET sync_status = 'error' WHERE id = ?", failed.ID)
+	require.NoError(t, err)
+
+	// Without resetErrored the task statuses are left alone
+	require.NoError(t, syncService.ResetQueue(false))
+	stored, err := taskService.GetTaskByID(failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusError, stored.SyncStatus)
+
+	// A task the server knows about whose delete failed
+	deleted, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Deleted"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(deleted.ID))
+	_, err = db.Exec("UPDATE tasks SET sync_status = 'error', server_id = 'srv_1' WHERE id = ?", deleted.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, syncService.AddToQueue(ok.ID, models.OperationTypeUpdate, ok))
+	require.NoError(t, syncService.ResetQueue(true))
+
+	// Only the errored tasks are queued again, each with fresh retries
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	queued := map[string]models.OperationType{}
+	for _, item := range items {
+		queued[item.TaskID] = item.OperationType
+		assert.Zero(t, item.RetryCount)
+	}
+	assert.Equal(t, map[string]models.OperationType{
+		failed.ID:  models.OperationTypeCreate,
+		deleted.ID: models.OperationTypeDelete,
+	}, queued)
+
+	stored, err = taskService.GetTaskByID(failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusPending, stored.SyncStatus)
+
+	status, err := syncService.GetSyncStatus()
+	require.NoError(t, err)
+	assert.Equal(t, 0, status.DeadLetterCount)
+	assert.Equal(t, 0, status.ErrorCount)
+	assert.Equal(t, 2, status.PendingCount)
+
+	// The next pass pushes them, so nothing is left pending
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.ElementsMatch(t, []string{"create:" + failed.ID, "delete:" + deleted.ID}, fake.calls)
+	stored, err = taskService.GetTaskByID(failed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+}
+
+func TestTaskService_BulkSetCompleted(t *testing.T) {
+	taskService, _, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	first, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "First"})
+	require.NoError(t, err)
+	second, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Second"})
+	require.NoError(t, err)
+
+	updated, notFound, err := taskService.BulkSetCompleted([]string{first.ID, second.ID}, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated)
+	assert.Empty(t, notFound)
+
+	for _, id := range []string{first.ID, second.ID} {
+		stored, err := taskService.GetTaskByID(id)
+		require.NoError(t, err)
+		assert.True(t, stored.Completed)
+
+		var updates int
+		require.NoError(t, db.QueryRow(
+			"SELECT COUNT(*) FROM sync_queue WHERE task_id = ? AND operation_type = 'update'", id).Scan(&updates))
+		assert.Equal(t, 1, updates)
+	}
+
+	// Missing and deleted IDs are skipped; the rest still change
+	require.NoError(t, taskService.DeleteTask(second.ID))
+	updated, notFound, err = taskService.BulkSetCompleted([]string{first.ID, "missing", second.ID}, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+	assert.Equal(t, []string{"missing", second.ID}, notFound)
+
+	stored, err := taskService.GetTaskByID(first.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Completed)
+}
+
+func TestTaskService_BulkDelete(t *testing.T) {
+	taskService, _, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	first, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "First", Tags: []string{"old"}})
+	require.NoError(t, err)
+	second, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Second"})
+	require.NoError(t, err)
+	gone, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Gone"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(gone.ID))
+	kept, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Kept"})
+	require.NoError(t, err)
+
+	// Missing IDs are skipped, repeats count once and already-deleted tasks are left alone
+	deleted, notFound, err := taskService.BulkDelete([]string{first.ID, "missing", second.ID, first.ID, gone.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, []string{"missing"}, notFound)
+
+	for _, id := range []string{first.ID, second.ID, gone.ID} {
+		_, err := taskService.GetTaskByID(id)
+		assert.ErrorIs(t, err, services.ErrTaskNotFound)
+
+		var deletes int
+		require.NoError(t, db.QueryRow(
+			"SELECT COUNT(*) FROM sync_queue WHERE task_id = ? AND operation_type = 'delete'", id).Scan(&deletes))
+		assert.Equal(t, 1, deletes)
+	}
+
+	var tags int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM task_tags WHERE task_id = ?", first.ID).Scan(&tags))
+	assert.Equal(t, 0, tags)
+
+	_, err = taskService.GetTaskByID(kept.ID)
+	assert.NoError(t, err)
+}
+
+func TestTaskService_DependencyCycles(t *testing.T) {
+	taskService, _, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	var ids []string
+	for _, title := range []string{"Design", "Build", "Ship"} {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: title})
+		require.NoError(t, err)
+		ids = append(ids, task.ID)
+	}
+	design, build, ship := ids[0], ids[1], ids[2]
+
+	require.NoError(t, taskService.AddDependency(build, design))
+	require.NoError(t, taskService.AddDependency(ship, build))
+	require.NoError(t, taskService.AddDependency(ship, build), "adding twice is a no-op")
+
+	assert.ErrorIs(t, taskService.AddDependency(design, design), services.ErrDependencyCycle)
+	assert.ErrorIs(t, taskService.AddDependency(design, build), services.ErrDependencyCycle)
+	assert.ErrorIs(t, taskService.AddDependency(design, ship), services.ErrDependencyCycle)
+	assert.ErrorIs(t, taskService.AddDependency(design, "missing"), services.ErrTaskNotFound)
+
+	deps, err := taskService.GetDependencies(ship)
+	require.NoError(t, err)
+	require.Len(t, deps, 1)
+	assert.Equal(t, build, deps[0].ID)
+
+	// Another user can't see or link these tasks
+	assert.ErrorIs(t, taskService.ForUser("bob").AddDependency(design, ship), services.ErrTaskNotFound)
+
+	require.NoError(t, taskService.RemoveDependency(ship, build))
+	assert.ErrorIs(t, taskService.RemoveDependency(ship, build), services.ErrDependencyNotFound)
+	require.NoError(t, taskService.AddDependency(design, ship), "no cycle once the link is gone")
+}
+
+func TestTaskService_Comments(t *testing.T) {
+	taskService, _, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Discussed"})
+	require.NoError(t, err)
+
+	first, err := taskService.ForUser("alice").AddComment(task.ID, &models.CreateCommentRequest{Body: "Looks good"})
+	require.ErrorIs(t, err, services.ErrTaskNotFound, "only the owner can comment")
+	assert.Nil(t, first)
+
+	first, err = taskService.AddComment(task.ID, &models.CreateCommentRequest{Body: "Looks good"})
+	require.NoError(t, err)
+	assert.Equal(t, models.SystemActor, first.Author)
+	_, err = taskService.AddComment(task.ID, &models.CreateCommentRequest{Body: "Ship it", Author: "bob"})
+	require.NoError(t, err)
+
+	comments, err := taskService.GetComments(task.ID)
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, first.ID, comments[0].ID)
+	assert.Equal(t, "Looks good", comments[0].Body)
+	assert.Equal(t, "bob", comments[1].Author)
+
+	_, err = taskService.GetComments("missing")
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+
+	// Comments never reach the sync queue
+	var queued int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sync_queue WHERE task_id = ?`, task.ID).Scan(&queued))
+	assert.Equal(t, 1, queued, "only the create is queued")
+
+	countComments := func(taskID string) int {
+		var n int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM task_comments WHERE task_id = ?`, taskID).Scan(&n))
+		return n
+	}
+
+	require.NoError(t, taskService.DeleteTask(task.ID))
+	assert.Equal(t, 2, countComments(task.ID), "soft delete keeps comments")
+
+	_, err = taskService.RestoreTask(task.ID)
+	require.NoError(t, err)
+	comments, err = taskService.GetComments(task.ID)
+	require.NoError(t, err)
+	assert.Len(t, comments, 2, "restore brings the comments back")
+
+	hard, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Hard deleted"})
+	require.NoError(t, err)
+	_, err = taskService.AddComment(hard.ID, &models.CreateCommentRequest{Body: "Gone soon"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.HardDeleteTask(hard.ID))
+	assert.Zero(t, countComments(hard.ID), "hard delete cascades to comments")
+}
+
+func TestTaskService_CompletionBlockedByDependencies(t *testing.T) {
+	taskService, _, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	blocker, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Blocker"})
+	require.NoError(t, err)
+	blocked, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Blocked"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.AddDependency(blocked.ID, blocker.ID))
+
+	_, err = taskService.UpdateTask(blocked.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	assert.ErrorIs(t, err, services.ErrIncompleteDependencies)
+
+	// Other edits are still allowed
+	_, err = taskService.UpdateTask(blocked.ID, &models.UpdateTaskRequest{Title: stringPtr("Still blocked")})
+	require.NoError(t, err)
+
+	forced, err := taskService.UpdateTask(blocked.ID, &models.UpdateTaskRequest{Completed: boolPtr(true), Force: true})
+	require.NoError(t, err)
+	assert.True(t, forced.Completed)
+
+	// Once the dependency is done, completing needs no force
+	_, err = taskService.UpdateTask(blocked.ID, &models.UpdateTaskRequest{Completed: boolPtr(false)})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(blocker.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(blocked.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	assert.NoError(t, err)
+}
+
+func TestSyncService_QueueLimit(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:     ":memory:",
+		SyncBatchSize:    5,
+		MaxRetries:       3,
+		MaxQueueSize:     2,
+		QueueSizeRefresh: time.Hour,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	syncService.SetClient(&fakeSyncClient{})
+	taskService := services.NewTaskService(db, syncService)
+
+	for i := 0; i < 2; i++ {
+		_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Queued %d", i)})
+		require.NoError(t, err)
+	}
+
+	// The rejected change is rolled back along with its queue entry
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Overflow"})
+	assert.ErrorIs(t, err, services.ErrQueueFull)
+
+	tasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	// Draining the queue makes room again without waiting for the refresh
+	require.NoError(t, syncService.ProcessSyncQueue())
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "After drain"})
+	assert.NoError(t, err)
+}
+
+func TestSyncService_GetTasksBySyncStatus(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	byStatus := map[models.SyncStatus][]string{}
+	for i, status := range []models.SyncStatus{
+		models.SyncStatusPending, models.SyncStatusSynced, models.SyncStatusSynced,
+		models.SyncStatusError, models.SyncStatusError, models.SyncStatusError,
+	} {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Task %d", i)})
+		require.NoError(t, err)
+		_, err = db.Exec(`UPDATE tasks SET sync_status = ? WHERE id = ?`, status, task.ID)
+		require.NoError(t, err)
+		byStatus[status] = append(byStatus[status], task.ID)
+	}
+
+	for status, ids := range byStatus {
+		tasks, total, err := syncService.GetTasksBySyncStatus(status, 50, 0)
+		require.NoError(t, err)
+		assert.Equal(t, len(ids), total, status)
+
+		var got []string
+		for _, task := range tasks {
+			assert.Equal(t, status, task.SyncStatus)
+			got = append(got, task.ID)
+		}
+		assert.ElementsMatch(t, ids, got, status)
+	}
+
+	// Paging keeps the total for the whole status
+	tasks, total, err := syncService.GetTasksBySyncStatus(models.SyncStatusError, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Len(t, tasks, 1)
+}
+
+func TestSyncService_GetDeadLetters(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	var dead []string
+	for i := 0; i < 3; i++ {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Dead %d", i)})
+		require.NoError(t, err)
+		_, err = db.Exec(`UPDATE sync_queue SET retry_count = 3, error_message = ?, last_attempt = ?
+            WHERE task_id = ?`, fmt.Sprintf("failure %d", i), time.Now().Add(time.Duration(i)*time.Minute), task.ID)
+		require.NoError(t, err)
+		dead = append(dead, task.ID)
+	}
+	_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Still retrying"})
+	require.NoError(t, err)
+
+	// Newest attempt first, and retrying items are left out
+	items, total, err := syncService.GetDeadLetters("", 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, items, 2)
+	assert.Equal(t, dead[2], items[0].TaskID)
+	assert.Equal(t, 3, items[0].RetryCount)
+	require.NotNil(t, items[0].ErrorMessage)
+	assert.Equal(t, "failure 2", *items[0].ErrorMessage)
+
+	items, _, err = syncService.GetDeadLetters("", 2, 2)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, dead[0], items[0].TaskID)
+
+	items, total, err = syncService.GetDeadLetters("", 2, 3)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Equal(t, 3, total)
+
+	items, total, err = syncService.GetDeadLetters(dead[1], 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, items, 1)
+	assert.Equal(t, dead[1], items[0].TaskID)
+}
+
+func TestTaskService_HardDeleteTask(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	soft, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Soft", Tags: []string{"kept"}})
+	require.NoError(t, err)
+	hard, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Hard", Tags: []string{"gone"}})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(hard.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+
+	require.NoError(t, taskService.DeleteTask(soft.ID))
+	require.NoError(t, taskService.HardDeleteTask(hard.ID))
+
+	count := func(query, id string) int {
+		var n int
+		require.NoError(t, db.QueryRow(query, id).Scan(&n))
+		return n
+	}
+
+	// Soft delete only flags the row
+	assert.Equal(t, 1, count("SELECT COUNT(*) FROM tasks WHERE id = ? AND is_deleted = 1", soft.ID))
+
+	// Hard delete removes the row and everything holding a copy of it
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM tasks WHERE id = ?", hard.ID))
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM task_tags WHERE task_id = ?", hard.ID))
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM task_events WHERE task_id = ?", hard.ID))
+
+	// Only the delete is left queued, and it outlives the row
+	assert.Equal(t, 1, count("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", hard.ID))
+	assert.Equal(t, 1, count("SELECT COUNT(*) FROM sync_queue WHERE task_id = ? AND operation_type = 'delete'", hard.ID))
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Contains(t, fake.calls, "delete:"+hard.ID)
+	assert.Equal(t, 0, count("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", hard.ID))
+
+	assert.ErrorIs(t, taskService.HardDeleteTask(hard.ID), services.ErrTaskNotFound)
+}
+
+func TestTaskService_HardDeleteSoftDeletedTask(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Trashed"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(task.ID))
+
+	// Emptying the trash removes a task that was already soft-deleted
+	require.NoError(t, taskService.HardDeleteTask(task.ID))
+
+	var rows, queued int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM tasks WHERE id = ?", task.ID).Scan(&rows))
+	assert.Equal(t, 0, rows)
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ? AND operation_type = 'delete'", task.ID).Scan(&queued))
+	assert.Equal(t, 1, queued)
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, []string{"delete:" + task.ID}, fake.calls)
+
+	assert.ErrorIs(t, taskService.HardDeleteTask(task.ID), services.ErrTaskNotFound)
+}
+
+func TestDatabase_SyncQueueForeignKeyIsDropped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	// A queue created before hard deletes existed cascades from tasks
+	db, err := database.NewSQLiteDB(path)
+	require.NoError(t, err)
+	_, err = db.Exec(`DROP TABLE sync_queue`)
+	require.NoError(t, err)
+	// It also predates versioned migrations
+	_, err = db.Exec(`DROP TABLE schema_migrations`)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE sync_queue (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id TEXT NOT NULL,
+        operation_type TEXT NOT NULL,
+        task_data TEXT NOT NULL,
+        retry_count INTEGER NOT NULL DEFAULT 0,
+        created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        last_attempt DATETIME,
+        error_message TEXT,
+        next_attempt_at DATETIME,
+        FOREIGN KEY (task_id) REFERENCES tasks(id) ON DELETE CASCADE
+    )`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO tasks (id, title, created_at, updated_at) VALUES ('t1', 'Old', ?, ?)`, time.Now(), time.Now())
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO sync_queue (task_id, operation_type, task_data) VALUES ('t1', 'create', '{}')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = database.NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var foreignKeys, queued int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM pragma_foreign_key_list('sync_queue')`).Scan(&foreignKeys))
+	assert.Equal(t, 0, foreignKeys)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sync_queue WHERE task_id = 't1'`).Scan(&queued))
+	assert.Equal(t, 1, queued)
+}
+
+func TestDatabase_SyncQueueCheckAllowsNewOperations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	// A queue created before restore and archive only allows three operations
+	db, err := database.NewSQLiteDB(path)
+	require.NoError(t, err)
+	_, err = db.Exec(`DROP TABLE sync_queue`)
+	require.NoError(t, err)
+	// It also predates versioned migrations
+	_, err = db.Exec(`DROP TABLE schema_migrations`)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE sync_queue (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        task_id TEXT NOT NULL,
+        operation_type TEXT NOT NULL,
+        task_data TEXT NOT NULL,
+        retry_count INTEGER NOT NULL DEFAULT 0,
+        created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        last_attempt DATETIME,
+        error_message TEXT,
+        next_attempt_at DATETIME,
+        CONSTRAINT chk_operation_type CHECK (operation_type IN ('create', 'update', 'delete'))
+    )`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO sync_queue (task_id, operation_type, task_data) VALUES ('t1', 'update', '{}')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO sync_queue (task_id, operation_type, task_data) VALUES ('t1', 'archive', '{}')`)
+	require.Error(t, err)
+	require.NoError(t, db.Close())
+
+	db, err = database.NewSQLiteDB(path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var queued int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sync_queue WHERE task_id = 't1'`).Scan(&queued))
+	assert.Equal(t, 1, queued)
+	for _, op := range []string{"restore", "archive"} {
+		_, err = db.Exec(`INSERT INTO sync_queue (task_id, operation_type, task_data) VALUES ('t1', ?, '{}')`, op)
+		assert.NoError(t, err, op)
+	}
+	_, err = db.Exec(`INSERT INTO sync_queue (task_id, operation_type, task_data) VALUES ('t1', 'rename', '{}')`)
+	assert.Error(t, err)
+}
+
+// lifecycleSyncClient is a fakeSyncClient whose server has its own restore and
+// archive operations.
+type lifecycleSyncClient struct {
+	*fakeSyncClient
+}
+
+func (l lifecycleSyncClient) RestoreTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return l.respond(ctx, "restore", task)
+}
+
+func (l lifecycleSyncClient) ArchiveTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return l.respond(ctx, "archive", task)
+}
+
+func TestSyncService_RestoreAndArchiveOperations(t *testing.T) {
+	cases := []struct {
+		name      string
+		lifecycle bool
+		wantCalls func(archived, restored string) []string
+	}{
+		{
+			name: "plain client pushes updates",
+			wantCalls: func(archived, restored string) []string {
+				return []string{"update:" + archived, "update:" + restored}
+			},
+		},
+		{
+			name:      "lifecycle client gets its own operations",
+			lifecycle: true,
+			wantCalls: func(archived, restored string) []string {
+				return []string{"archive:" + archived, "restore:" + restored}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			taskService, syncService, db, cleanup := setupTestServices()
+			defer cleanup()
+
+			fake := &fakeSyncClient{}
+			syncService.SetClient(fake)
+			if tc.lifecycle {
+				syncService.SetClient(lifecycleSyncClient{fake})
+			}
+
+			archived, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Archive me"})
+			require.NoError(t, err)
+			restored, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Restore me"})
+			require.NoError(t, err)
+			require.NoError(t, taskService.DeleteTask(restored.ID))
+			require.NoError(t, syncService.ProcessSyncQueue())
+			fake.calls = nil
+
+			_, err = taskService.ArchiveTask(archived.ID)
+			require.NoError(t, err)
+			task, err := taskService.RestoreTask(restored.ID)
+			require.NoError(t, err)
+			assert.False(t, task.IsDeleted)
+
+			var ops []string
+			rows, err := db.Query(`SELECT operation_type FROM sync_queue ORDER BY id`)
+			require.NoError(t, err)
+			for rows.Next() {
+				var op string
+				require.NoError(t, rows.Scan(&op))
+				ops = append(ops, op)
+			}
+			require.NoError(t, rows.Close())
+			assert.Equal(t, []string{"archive", "restore"}, ops)
+
+			require.NoError(t, syncService.ProcessSyncQueue())
+			assert.Equal(t, tc.wantCalls(archived.ID, restored.ID), fake.calls)
+
+			stored, err := taskService.GetTaskByID(restored.ID)
+			require.NoError(t, err)
+			assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+
+			// Only deleted tasks can be restored
+			_, err = taskService.RestoreTask(restored.ID)
+			assert.ErrorIs(t, err, services.ErrTaskNotFound)
+		})
+	}
+}
+
+func TestTaskService_GetChangedSince(t *testing.T) {
+	taskService, _, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	untouched, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Untouched"})
+	require.NoError(t, err)
+	edited, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Edited"})
+	require.NoError(t, err)
+	removed, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Removed"})
+	require.NoError(t, err)
+
+	initial, err := taskService.GetChangedSince(time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, initial.Tasks, 3)
+
+	_, err = taskService.UpdateTask(edited.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(removed.ID))
+	added, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Added"})
+	require.NoError(t, err)
+	_, err = taskService.ForUser("someone-else").CreateTask(&models.CreateTaskRequest{Title: "Not mine"})
+	require.NoError(t, err)
+
+	changes, err := taskService.GetChangedSince(initial.ServerTime)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, task := range changes.Tasks {
+		ids = append(ids, task.ID)
+	}
+	assert.Equal(t, []string{edited.ID, removed.ID, added.ID}, ids)
+	assert.NotContains(t, ids, untouched.ID)
+	assert.True(t, changes.Tasks[0].Completed)
+	assert.True(t, changes.Tasks[1].IsDeleted)
+	assert.True(t, changes.ServerTime.After(initial.ServerTime))
+
+	// Nothing has changed since the last call
+	latest, err := taskService.GetChangedSince(changes.ServerTime)
+	require.NoError(t, err)
+	assert.Empty(t, latest.Tasks)
+}
+
+func TestTaskService_ForUser(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	alice := taskService.ForUser("alice")
+	bob := taskService.ForUser("bob")
+
+	task, err := alice.CreateTask(&models.CreateTaskRequest{Title: "Alice's task"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", task.UserID)
+
+	_, err = bob.GetTaskByID(task.ID)
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+	_, err = bob.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+	assert.ErrorIs(t, bob.DeleteTask(task.ID), services.ErrTaskNotFound)
+
+	tasks, err := bob.GetAllTasks()
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	stored, err := alice.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Completed)
+
+	// The queued operation carries the owner
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "alice", items[0].UserID)
+}
+
+func TestTaskService_AuditFields(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	t.Run("anonymous changes are made by system", func(t *testing.T) {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Anonymous"})
+		require.NoError(t, err)
+		assert.Equal(t, models.SystemActor, task.CreatedBy)
+		assert.Equal(t, models.SystemActor, task.UpdatedBy)
+	})
+
+	alice := taskService.ForUser("alice")
+	task, err := alice.CreateTask(&models.CreateTaskRequest{Title: "Audited"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", task.CreatedBy)
+	assert.Equal(t, "alice", task.UpdatedBy)
+
+	stored, err := alice.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", stored.CreatedBy)
+	assert.Equal(t, "alice", stored.UpdatedBy)
+
+	// The queued payload carries the audit fields to the server
+	items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{})
+	require.NoError(t, err)
+	var queued *models.Task
+	for _, item := range items {
+		if item.TaskID == task.ID {
+			queued, err = item.GetTaskData()
+			require.NoError(t, err)
+		}
+	}
+	require.NotNil(t, queued)
+	assert.Equal(t, "alice", queued.CreatedBy)
+	assert.Equal(t, "alice", queued.UpdatedBy)
+
+	// An edit made by someone else on the server is recorded when it wins
+	remote := *stored
+	remote.Title = "Edited by bob"
+	remote.UpdatedBy = "bob"
+	remote.UpdatedAt = stored.UpdatedAt.Add(time.Minute)
+	_, err = syncService.ResolveConflict(stored, &remote)
+	require.NoError(t, err)
+
+	stored, err = alice.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", stored.CreatedBy)
+	assert.Equal(t, "bob", stored.UpdatedBy)
+
+	updated, err := alice.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", updated.CreatedBy)
+	assert.Equal(t, "alice", updated.UpdatedBy)
+}
+
+func TestTaskService_ArchiveTask(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	kept, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Still visible"})
+	require.NoError(t, err)
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Done and hidden"})
+	require.NoError(t, err)
+
+	archived, err := taskService.ArchiveTask(task.ID)
+	require.NoError(t, err)
+	assert.True(t, archived.Archived)
+	assert.Equal(t, models.SyncStatusPending, archived.SyncStatus)
+
+	// Archiving queues an archive operation carrying the flag
+	items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{OperationType: models.OperationTypeArchive})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	queued, err := items[0].GetTaskData()
+	require.NoError(t, err)
+	assert.True(t, queued.Archived)
+
+	tasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, kept.ID, tasks[0].ID)
+
+	tasks, err = taskService.ListTasks(&models.TaskFilter{IncludeArchived: true})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	// Archived tasks can still be fetched directly
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Archived)
+
+	unarchived, err := taskService.UnarchiveTask(task.ID)
+	require.NoError(t, err)
+	assert.False(t, unarchived.Archived)
+
+	tasks, err = taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	require.NoError(t, taskService.DeleteTask(kept.ID))
+	_, err = taskService.ArchiveTask(kept.ID)
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+}
+
+func TestTaskService_GetActivity(t *testing.T) {
+	taskService, _, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	first, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "First"})
+	require.NoError(t, err)
+	second, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Second"})
+	require.NoError(t, err)
+	_, err = taskService.UpdateTask(first.ID, &models.UpdateTaskRequest{Title: stringPtr("First, renamed")})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(second.ID))
+	_, err = taskService.ArchiveTask(first.ID)
+	require.NoError(t, err)
+
+	// Another user's changes stay out of the feed
+	_, err = taskService.ForUser("someone-else").CreateTask(&models.CreateTaskRequest{Title: "Private"})
+	require.NoError(t, err)
+
+	type step struct {
+		event  models.TaskEventType
+		taskID string
+	}
+	want := []step{
+		{models.TaskEventUpdated, first.ID},
+		{models.TaskEventDeleted, second.ID},
+		{models.TaskEventUpdated, first.ID},
+		{models.TaskEventCreated, second.ID},
+		{models.TaskEventCreated, first.ID},
+	}
+
+	// Walk the feed two entries at a time
+	var got []step
+	var titles []string
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		entries, next, err := taskService.GetActivity(cursor, 2)
+		require.NoError(t, err)
+		for _, entry := range entries {
+			got = append(got, step{entry.Event, entry.TaskID})
+
+			var snapshot models.Task
+			require.NoError(t, json.Unmarshal(entry.Task, &snapshot))
+			titles = append(titles, snapshot.Title)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	assert.Equal(t, want, got)
+	assert.Equal(t, []string{"First, renamed", "Second", "First, renamed", "Second", "First"}, titles)
+
+	_, _, err = taskService.GetActivity("not-a-cursor", 2)
+	assert.ErrorIs(t, err, services.ErrInvalidCursor)
+}
+
+func TestTaskService_ActivityRollsBackWithMutation(t *testing.T) {
+	cfg := &config.Config{DatabasePath: ":memory:", SyncBatchSize: 5, MaxRetries: 3, EnforceUniqueTitles: true}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	taskService := services.NewTaskService(db, services.NewSyncService(db, cfg))
+
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Only once"})
+	require.NoError(t, err)
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Only once"})
+	require.ErrorIs(t, err, services.ErrDuplicateTitle)
+
+	entries, _, err := taskService.GetActivity("", 10)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestTaskService_RecurringTaskCompletion(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	due := time.Date(2024, time.March, 4, 9, 0, 0, 0, time.Local)
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{
+		Title:          "Water plants",
+		Tags:           []string{"home"},
+		DueDate:        &due,
+		RecurrenceRule: stringPtr(models.RecurrenceWeekly),
+	})
+	require.NoError(t, err)
+
+	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+
+	tasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+
+	var next *models.Task
+	for _, candidate := range tasks {
+		if candidate.ID != task.ID {
+			next = candidate
+		}
+	}
+	require.NotNil(t, next)
+	assert.Equal(t, "Water plants", next.Title)
+	assert.False(t, next.Completed)
+	assert.Equal(t, []string{"home"}, next.Tags)
+	require.NotNil(t, next.RecurrenceRule)
+	assert.Equal(t, models.RecurrenceWeekly, *next.RecurrenceRule)
+	require.NotNil(t, next.DueDate)
+	assert.True(t, due.AddDate(0, 0, 7).Equal(*next.DueDate))
+
+	// The new occurrence is queued for creation on the server
+	items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{OperationType: models.OperationTypeCreate})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, next.ID, items[1].TaskID)
+
+	// Updating an already completed task does not spawn another occurrence
+	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	tasks, err = taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+}
+
+func TestTaskService_EnforceUniqueTitles(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:        ":memory:",
+		SyncBatchSize:       5,
+		MaxRetries:          3,
+		EnforceUniqueTitles: true,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	taskService := services.NewTaskService(db, services.NewSyncService(db, cfg))
+
+	first, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Quarterly report"})
+	require.NoError(t, err)
+
+	t.Run("collision", func(t *testing.T) {
+		_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Quarterly report"})
+		assert.ErrorIs(t, err, services.ErrDuplicateTitle)
+
+		other, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Budget"})
+		require.NoError(t, err)
+		_, err = taskService.UpdateTask(other.ID, &models.UpdateTaskRequest{Title: stringPtr("Quarterly report")})
+		assert.ErrorIs(t, err, services.ErrDuplicateTitle)
+
+		// Other users have their own namespace
+		_, err = taskService.ForUser("someone-else").CreateTask(&models.CreateTaskRequest{Title: "Quarterly report"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("own title", func(t *testing.T) {
+		updated, err := taskService.UpdateTask(first.ID, &models.UpdateTaskRequest{Title: stringPtr("Quarterly report")})
+		require.NoError(t, err)
+		assert.Equal(t, "Quarterly report", updated.Title)
+	})
+
+	t.Run("deleted task does not count", func(t *testing.T) {
+		require.NoError(t, taskService.DeleteTask(first.ID))
+		_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Quarterly report"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestSyncService_ReplayedCreateBecomesUpdate(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Synced once"})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.ServerID)
+	assert.Equal(t, "srv_"+task.ID, *stored.ServerID)
+
+	// Simulate a crash between the server ack and the local commit by queueing
+	// the original create again
+	require.NoError(t, syncService.AddToQueue(task.ID, models.OperationTypeCreate, task))
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	assert.Equal(t, []string{"create:" + task.ID, "update:" + task.ID}, fake.calls)
+
+	stored, err = taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "srv_"+task.ID, *stored.ServerID)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+}
+
+func TestTaskService_GetStats(t *testing.T) {
+	taskService, _, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	create := func(title string) *models.Task {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: title})
+		require.NoError(t, err)
+		return task
+	}
+
+	completed := create("Completed")
+	deleted := create("Deleted")
+	failed := create("Failed")
+	synced := create("Synced")
+
+	_, err := taskService.UpdateTask(completed.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(deleted.ID))
+	_, err = db.Exec("UPDATE tasks SET sync_status = 'error' WHERE id = ?", failed.ID)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE tasks SET sync_status = 'synced' WHERE id = ?", synced.ID)
+	require.NoError(t, err)
+
+	// Another user's tasks are not counted
+	_, err = taskService.ForUser("other").CreateTask(&models.CreateTaskRequest{Title: "Elsewhere"})
+	require.NoError(t, err)
+
+	stats, err := taskService.GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, &services.TaskStats{
+		Active:      3,
+		Completed:   1,
+		PendingSync: 2,
+		ErrorSync:   1,
+		Deleted:     1,
+	}, stats)
+
+	empty, err := taskService.ForUser("nobody").GetStats()
+	require.NoError(t, err)
+	assert.Equal(t, &services.TaskStats{}, empty)
+}
+
+func TestTaskService_ImportTasks(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	line := func(task *models.Task) string {
+		data, err := json.Marshal(task)
+		require.NoError(t, err)
+		return string(data) + "\n"
+	}
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	first := models.NewTask("Imported one", nil)
+	first.UpdatedAt = base
+	first.Tags = []string{"backup"}
+	second := models.NewTask("Imported two", nil)
+	second.UpdatedAt = base
+
+	t.Run("fresh import", func(t *testing.T) {
+		imported, updated, err := taskService.ImportTasks(strings.NewReader(line(first) + line(second)))
+		require.NoError(t, err)
+		assert.Equal(t, 2, imported)
+		assert.Equal(t, 0, updated)
+
+		stored, err := taskService.GetTaskByID(first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Imported one", stored.Title)
+		assert.Equal(t, []string{"backup"}, stored.Tags)
+
+		items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{OperationType: models.OperationTypeCreate})
+		require.NoError(t, err)
+		assert.Len(t, items, 2)
+	})
+
+	t.Run("updates existing tasks", func(t *testing.T) {
+		newer := *first
+		newer.Title = "Newer title"
+		newer.UpdatedAt = base.Add(time.Minute)
+		older := *second
+		older.Title = "Stale title"
+		older.UpdatedAt = base.Add(-time.Minute)
+
+		imported, updated, err := taskService.ImportTasks(strings.NewReader(line(&newer) + line(&older)))
+		require.NoError(t, err)
+		assert.Equal(t, 0, imported)
+		assert.Equal(t, 1, updated)
+
+		stored, err := taskService.GetTaskByID(first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Newer title", stored.Title)
+
+		stored, err = taskService.GetTaskByID(second.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Imported two", stored.Title)
+	})
+
+	t.Run("malformed line", func(t *testing.T) {
+		third := models.NewTask("Imported three", nil)
+		input := "{not json\n" + line(third) + `{"id":"no-title","title":" ","updated_at":"2024-01-01T00:00:00Z"}` + "\n"
+
+		imported, updated, err := taskService.ImportTasks(strings.NewReader(input))
+		assert.Equal(t, 1, imported)
+		assert.Equal(t, 0, updated)
+
+		var importErr *services.ImportError
+		require.ErrorAs(t, err, &importErr)
+		require.Len(t, importErr.Lines, 2)
+		assert.Equal(t, 1, importErr.Lines[0].Line)
+		assert.Equal(t, 3, importErr.Lines[1].Line)
+
+		_, err = taskService.GetTaskByID(third.ID)
+		assert.NoError(t, err)
+	})
+}
+
+func TestTaskService_ImportTasksUniqueTitles(t *testing.T) {
+	cfg := &config.Config{DatabasePath: ":memory:", SyncBatchSize: 5, MaxRetries: 3, EnforceUniqueTitles: true}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	taskService := services.NewTaskService(db, services.NewSyncService(db, cfg))
+
+	existing, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Quarterly report"})
+	require.NoError(t, err)
+
+	line := func(task *models.Task) string {
+		data, err := json.Marshal(task)
+		require.NoError(t, err)
+		return string(data) + "\n"
+	}
+	clash := models.NewTask("Quarterly report", nil)
+	fresh := models.NewTask("Budget", nil)
+	sameBatch := models.NewTask("Budget", nil)
+	deleted := models.NewTask("Quarterly report", nil)
+	deleted.IsDeleted = true
+	renamed := *existing
+	renamed.Title = "Budget"
+	renamed.UpdatedAt = existing.UpdatedAt.Add(time.Minute)
+
+	input := line(clash) + line(fresh) + line(sameBatch) + line(deleted) + line(&renamed)
+	imported, updated, err := taskService.ImportTasks(strings.NewReader(input))
+	assert.Equal(t, 2, imported, "the fresh title and the deleted task are imported")
+	assert.Equal(t, 0, updated)
+
+	var importErr *services.ImportError
+	require.ErrorAs(t, err, &importErr)
+	require.Len(t, importErr.Lines, 3)
+	for i, lineNo := range []int{1, 3, 5} {
+		assert.Equal(t, lineNo, importErr.Lines[i].Line)
+		assert.Equal(t, services.ErrDuplicateTitle.Error(), importErr.Lines[i].Error)
+	}
+
+	_, err = taskService.GetTaskByID(clash.ID)
+	assert.ErrorIs(t, err, services.ErrTaskNotFound)
+	stored, err := taskService.GetTaskByID(existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Quarterly report", stored.Title, "a clashing replacement leaves the task alone")
+}
+
+func TestTaskService_IntegrationWithSync(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+
+	// Create a task
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Integration Test"})
+	require.NoError(t, err)
+
+	// Verify it's in sync queue
+	var queueCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", task.ID).Scan(&queueCount)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queueCount)
+
+	// Update the task
+	_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{
+		Title: stringPtr("Updated Integration Test"),
+	})
+	require.NoError(t, err)
+
+	// Should now have 2 items in queue (create + update)
+	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", task.ID).Scan(&queueCount)
+	require.NoError(t, err)
+	assert.Equal(t, 2, queueCount)
+
+	// Delete the task
+	err = taskService.DeleteTask(task.ID)
+	require.NoError(t, err)
+
+	// Should now have 3 items in queue (create + update + delete)
+	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", task.ID).Scan(&queueCount)
+	require.NoError(t, err)
+	assert.Equal(t, 3, queueCount)
+
+	// Process sync queue
+	err = syncService.ProcessSyncQueue()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"create:" + task.ID, "update:" + task.ID, "delete:" + task.ID}, fake.calls)
+
+	// Verify task is still soft deleted (not returned by GetAllTasks)
+	tasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Len(t, tasks, 0)
+}
+
+func TestTaskService_ConcurrentOperations(t *testing.T) {
+	taskService, _, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	// Reduce concurrency to avoid database locking issues with shared cache
+	const numTasks = 5
+	taskCh := make(chan *models.Task, numTasks)
+	errCh := make(chan error, numTasks)
+
+	// Use a wait group to ensure all goroutines complete
+	var wg sync.WaitGroup
+
+	for i := 0; i < numTasks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			task, err := taskService.CreateTask(&models.CreateTaskRequest{
+				Title: fmt.Sprintf("Concurrent Task %d", i),
+			})
+			if err != nil {
+				errCh <- err
+				return
+			}
+			taskCh <- task
+		}(i)
+	}
+
+	// Wait for all goroutines to complete
+	wg.Wait()
+	close(taskCh)
+	close(errCh)
+
+	// Collect results
+	var createdTasks []*models.Task
+	var errors []error
+
+	for task := range taskCh {
+		createdTasks = append(createdTasks, task)
+	}
+
+	for err := range errCh {
+		errors = append(errors, err)
+	}
+
+	// Report any errors but don't fail the test if we got some successful operations
+	for _, err := range errors {
+		t.Logf("Concurrent operation error: %v", err)
+	}
+
+	// We should have created at least some tasks
+	assert.GreaterOrEqual(t, len(createdTasks), numTasks-2, "Should create most tasks successfully")
+
+	// Verify tasks were persisted
+	allTasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(allTasks), len(createdTasks), "All created tasks should be persisted")
+}
+
+func TestTaskService_DeletedAt(t *testing.T) {
+	taskService, _, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Short-lived"})
+	require.NoError(t, err)
+	assert.Nil(t, task.DeletedAt)
+
+	stored, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.DeletedAt)
+
+	before := time.Now()
+	require.NoError(t, taskService.DeleteTask(task.ID))
+
+	var deleted *models.Task
+	var buf bytes.Buffer
+	require.NoError(t, taskService.StreamTasks(&buf))
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &deleted))
+	require.NotNil(t, deleted.DeletedAt)
+	assert.False(t, deleted.DeletedAt.Before(before.Truncate(time.Second)))
+
+	restored, err := taskService.RestoreTask(task.ID)
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+
+	stored, err = taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.DeletedAt)
+}
+
+func TestTaskService_PurgeDeleted(t *testing.T) {
+	taskService, _, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	oldTime := time.Now().Add(-60 * 24 * time.Hour)
+
+	// Old deleted task whose delete has already been dead-lettered
+	deadLettered, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Old dead-lettered"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(deadLettered.ID))
+	_, err = db.Exec("UPDATE sync_queue SET retry_count = 3 WHERE task_id = ?", deadLettered.ID)
+	require.NoError(t, err)
+
+	// Old deleted task that has already synced
+	synced, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Old synced"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(synced.ID))
+	_, err = db.Exec("DELETE FROM sync_queue WHERE task_id = ?", synced.ID)
+	require.NoError(t, err)
+
+	// Old deleted task that still has a pending sync
+	pending, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Old pending"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(pending.ID))
+
+	_, err = db.Exec("UPDATE tasks SET deleted_at = ? WHERE is_deleted = 1", oldTime)
+	require.NoError(t, err)
+
+	// Recently deleted task, last edited long ago
+	recent, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Recent"})
+	require.NoError(t, err)
+	require.NoError(t, taskService.DeleteTask(recent.ID))
+	_, err = db.Exec("DELETE FROM sync_queue WHERE task_id = ?", recent.ID)
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE tasks SET updated_at = ? WHERE id = ?", oldTime, recent.ID)
+	require.NoError(t, err)
+
+	// Live task
+	live, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Live"})
+	require.NoError(t, err)
+
+	purged, err := taskService.PurgeDeleted(30 * 24 * time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	var count int
+	for _, id := range []string{deadLettered.ID, synced.ID} {
+		err = db.QueryRow("SELECT COUNT(*) FROM tasks WHERE id = ?", id).Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 0, count, "task %s should be purged", id)
+	}
+
+	for _, id := range []string{pending.ID, recent.ID, live.ID} {
+		err = db.QueryRow("SELECT COUNT(*) FROM tasks WHERE id = ?", id).Scan(&count)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, "task %s should be kept", id)
+	}
+
+	// Dead-lettered items are removed, pending syncs are left intact
+	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", deadLettered.ID).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	err = db.QueryRow("SELECT COUNT(*) FROM sync_queue WHERE task_id = ?", pending.ID).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestTaskService_ListTasks_UpdatedWindow(t *testing.T) {
+	taskService, _, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	base := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
+
+	// Tasks updated at base, base+1h and base+2h
+	var ids []string
+	for i := 0; i < 3; i++ {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("Task %d", i)})
+		require.NoError(t, err)
+		_, err = db.Exec("UPDATE tasks SET updated_at = ? WHERE id = ?", base.Add(time.Duration(i)*time.Hour).Local(), task.ID)
+		require.NoError(t, err)
+		ids = append(ids, task.ID)
+	}
+
+	after := base.Add(time.Hour)
+	before := base.Add(2 * time.Hour)
+
+	// updated_after is inclusive, updated_before is exclusive
+	tasks, err := taskService.ListTasks(&models.TaskFilter{UpdatedAfter: &after, UpdatedBefore: &before})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, ids[1], tasks[0].ID)
+
+	tasks, err = taskService.ListTasks(&models.TaskFilter{UpdatedAfter: &after})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	tasks, err = taskService.ListTasks(&models.TaskFilter{UpdatedBefore: &after})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, ids[0], tasks[0].ID)
+
+	// Timestamps in other zones refer to the same instant
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	afterIST := after.In(ist)
+	tasks, err = taskService.ListTasks(&models.TaskFilter{UpdatedAfter: &afterIST})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+}
+
+func TestTaskService_ListTasks_DefaultSort(t *testing.T) {
+	cfg := &config.Config{DatabasePath: ":memory:", SyncBatchSize: 5, MaxRetries: 3, DefaultTaskSort: "title:asc"}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	taskService := services.NewTaskService(db, services.NewSyncService(db, cfg))
+	for _, title := range []string{"b", "c", "a"} {
+		_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: title})
+		require.NoError(t, err)
+	}
+
+	tasks, err := taskService.ListTasks(&models.TaskFilter{})
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{tasks[0].Title, tasks[1].Title, tasks[2].Title})
+
+	// The filter's order wins over the configured one
+	tasks, err = taskService.ListTasks(&models.TaskFilter{Sort: "title:desc"})
+	require.NoError(t, err)
+	assert.Equal(t, "c", tasks[0].Title)
+
+	_, err = taskService.ListTasks(&models.TaskFilter{Sort: "due_date:asc"})
+	assert.ErrorIs(t, err, services.ErrInvalidSort)
+	assert.NoError(t, services.ValidateTaskSort(""))
+	assert.ErrorIs(t, services.ValidateTaskSort("updated_at:up"), services.ErrInvalidSort)
+}
+
+func TestTaskService_Tags(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	// Adding tags on create
+	req := &models.CreateTaskRequest{Title: "Tagged", Tags: []string{" work ", "urgent", "work"}}
+	require.NoError(t, req.Validate())
+	task, err := taskService.CreateTask(req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work", "urgent"}, task.Tags)
+
+	other, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Home", Tags: []string{"home"}})
+	require.NoError(t, err)
+
+	retrieved, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"urgent", "work"}, retrieved.Tags)
+
+	// Filtering by tag
+	tasks, err := taskService.ListTasks(&models.TaskFilter{Tag: "work"})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, task.ID, tasks[0].ID)
+
+	tasks, err = taskService.ListTasks(&models.TaskFilter{Tag: "missing"})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 0)
+
+	// Replacing the tag set
+	updated, err := taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Tags: &[]string{"home"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"home"}, updated.Tags)
+
+	tasks, err = taskService.ListTasks(&models.TaskFilter{Tag: "work"})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 0)
+
+	tasks, err = taskService.ListTasks(&models.TaskFilter{Tag: "home"})
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	// Updating without tags leaves them alone
+	updated, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"home"}, updated.Tags)
+
+	// Tags are serialized into the sync queue payload
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	last := items[len(items)-1]
+	queued, err := last.GetTaskData()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"home"}, queued.Tags)
+
+	// Deleting a task removes its tag associations
+	require.NoError(t, taskService.DeleteTask(task.ID))
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM task_tags WHERE task_id = ?", task.ID).Scan(&count)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	tasks, err = taskService.ListTasks(&models.TaskFilter{Tag: "home"})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, other.ID, tasks[0].ID)
+}
+
+func TestTaskService_ConcurrentCreatesWithBusyTimeout(t *testing.T) {
+	cfg := &config.Config{SyncBatchSize: 5, MaxRetries: 3}
+
+	// Several connections write at once; the busy timeout makes them queue up
+	db, err := database.NewSQLiteDBWithPool(filepath.Join(t.TempDir(), "tasks.db"), database.PoolConfig{
+		BusyTimeoutMS: 5000,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	var busyTimeout int
+	require.NoError(t, db.QueryRow("PRAGMA busy_timeout").Scan(&busyTimeout))
+	assert.Equal(t, 5000, busyTimeout)
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+
+	const numTasks = 50
+	var wg sync.WaitGroup
+	errCh := make(chan error, numTasks)
+
+	for i := 0; i < numTasks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := taskService.CreateTask(&models.CreateTaskRequest{
+				Title: fmt.Sprintf("Concurrent Task %d", i),
+			})
+			if err != nil {
+				errCh <- err
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Errorf("Concurrent create error: %v", err)
+	}
+
+	allTasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Len(t, allTasks, numTasks)
+}
+
+func TestTaskService_ConcurrentOperationsWithPool(t *testing.T) {
+	cfg := &config.Config{SyncBatchSize: 5, MaxRetries: 3}
+
+	// A single connection serializes writers on a file database
+	db, err := database.NewSQLiteDBWithPool(filepath.Join(t.TempDir(), "tasks.db"), database.PoolConfig{
+		MaxOpenConns:    1,
+		MaxIdleConns:    1,
+		ConnMaxLifetime: time.Minute,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.Equal(t, 1, db.Stats().MaxOpenConnections)
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+
+	const numTasks = 20
+	var wg sync.WaitGroup
+	errCh := make(chan error, numTasks)
+
+	for i := 0; i < numTasks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			task, err := taskService.CreateTask(&models.CreateTaskRequest{
+				Title: fmt.Sprintf("Concurrent Task %d", i),
+			})
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			_, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+			if err != nil {
+				errCh <- err
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		t.Errorf("Concurrent operation error: %v", err)
+	}
+
+	allTasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	assert.Len(t, allTasks, numTasks)
+}
+
+// orderingSyncClient records the order of pushes per task and flags any two
+// pushes for the same task that overlap. It is safe for concurrent use.
+type orderingSyncClient struct {
+	mu       sync.Mutex
+	inFlight map[string]bool
+	byTask   map[string][]string
+	overlaps int
+}
+
+func (o *orderingSyncClient) push(task *models.Task) (*models.Task, error) {
+	o.mu.Lock()
+	if o.inFlight[task.ID] {
+		o.overlaps++
+	}
+	o.inFlight[task.ID] = true
+	o.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	o.mu.Lock()
+	o.inFlight[task.ID] = false
+	o.byTask[task.ID] = append(o.byTask[task.ID], task.Title)
+	o.mu.Unlock()
+
+	copied := *task
+	copied.ServerID = stringPtr("srv_" + task.ID)
+	return &copied, nil
+}
+
+func (o *orderingSyncClient) CreateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return o.push(task)
+}
+
+func (o *orderingSyncClient) UpdateTask(ctx context.Context, task *models.Task) (*models.Task, error) {
+	return o.push(task)
+}
+
+func (o *orderingSyncClient) DeleteTask(ctx context.Context, task *models.Task) error {
+	_, err := o.push(task)
+	return err
+}
+
+func TestSyncService_ConcurrentDrainPreservesTaskOrder(t *testing.T) {
+	cfg := &config.Config{SyncBatchSize: 100, MaxRetries: 3, SyncConcurrency: 4}
+
+	db, err := database.NewSQLiteDBWithPool(filepath.Join(t.TempDir(), "tasks.db"), database.PoolConfig{
+		MaxOpenConns: 1,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+
+	client := &orderingSyncClient{inFlight: map[string]bool{}, byTask: map[string][]string{}}
+	syncService.SetClient(client)
+
+	const numTasks, numUpdates = 6, 5
+	want := make(map[string][]string)
+	for i := 0; i < numTasks; i++ {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: fmt.Sprintf("task %d v0", i)})
+		require.NoError(t, err)
+		want[task.ID] = append(want[task.ID], task.Title)
+	}
+	for v := 1; v <= numUpdates; v++ {
+		for id, titles := range want {
+			title := fmt.Sprintf("%s v%d", strings.TrimSuffix(titles[0], " v0"), v)
+			_, err := taskService.UpdateTask(id, &models.UpdateTaskRequest{Title: &title})
+			require.NoError(t, err)
+			want[id] = append(want[id], title)
+		}
+	}
+
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	assert.Zero(t, client.overlaps, "operations for the same task overlapped")
+	assert.Equal(t, want, client.byTask)
+
+	var queueCount int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue").Scan(&queueCount))
+	assert.Equal(t, 0, queueCount)
+
+	tasks, err := taskService.GetAllTasks()
+	require.NoError(t, err)
+	for _, task := range tasks {
+		assert.Equal(t, models.SyncStatusSynced, task.SyncStatus)
+	}
+}
+
+func TestTaskService_Metadata(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{
+		Title:    "Annotated",
+		Metadata: map[string]interface{}{"source": "jira", "points": 3},
+	})
+	require.NoError(t, err)
+
+	retrieved, err := taskService.GetTaskByID(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"source": "jira", "points": float64(3)}, retrieved.Metadata)
+
+	// Merging keeps keys the update leaves out and drops keys set to null
+	updated, err := taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{
+		Metadata: map[string]interface{}{"points": 5, "source": nil, "sprint": "12"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"points": 5, "sprint": "12"}, updated.Metadata)
+
+	// Metadata round-trips through the sync queue payload
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	queued, err := items[len(items)-1].GetTaskData()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"points": float64(5), "sprint": "12"}, queued.Metadata)
+
+	// Updating without metadata leaves it alone
+	updated, err = taskService.UpdateTask(task.ID, &models.UpdateTaskRequest{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"points": float64(5), "sprint": "12"}, updated.Metadata)
+
+	// Tasks created without metadata read back with none
+	plain, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Plain"})
+	require.NoError(t, err)
+	retrieved, err = taskService.GetTaskByID(plain.ID)
+	require.NoError(t, err)
+	assert.Empty(t, retrieved.Metadata)
+}
+
+// pullSyncClient is a fakeSyncClient whose server lists the given tasks.
+type pullSyncClient struct {
+	fakeSyncClient
+	listed []*models.Task
+	since  time.Time
+}
+
+func (p *pullSyncClient) ListTasks(ctx context.Context, since time.Time) ([]*models.Task, error) {
+	p.since = since
+	return p.listed, nil
+}
+
+func TestSyncService_Pull(t *testing.T) {
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	client := &pullSyncClient{}
+	syncService.SetClient(client)
+
+	newer, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Newer on server"})
+	require.NoError(t, err)
+	older, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Older on server"})
+	require.NoError(t, err)
+	edited, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Edited on both"})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	// A local edit that hasn't been pushed yet
+	edited, err = taskService.UpdateTask(edited.ID, &models.UpdateTaskRequest{Title: stringPtr("Edited locally")})
+	require.NoError(t, err)
+
+	remoteCopy := func(task *models.Task, title string, updatedAt time.Time) *models.Task {
+		copied := *task
+		copied.Title = title
+		copied.UpdatedAt = updatedAt
+		copied.ServerID = stringPtr("srv_" + task.ID)
+		copied.SyncStatus = models.SyncStatusSynced
+		return &copied
+	}
+	later := time.Now().Add(time.Minute)
+	fresh := models.NewTask("Only on server", nil)
+	fresh.ServerID = stringPtr("srv_fresh")
+	fresh.Metadata = map[string]interface{}{"origin": "server"}
+	client.listed = []*models.Task{
+		fresh,
+		remoteCopy(newer, "Renamed on server", later),
+		remoteCopy(older, "Stale server title", older.UpdatedAt.Add(-time.Minute)),
+		remoteCopy(edited, "Edited on server", later),
+	}
+
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err := syncService.Pull(context.Background(), since)
+	require.NoError(t, err)
+	assert.Equal(t, since, client.since)
+	assert.Equal(t, &models.PullResult{Inserted: 1, Updated: 1, Conflicted: 1, Unchanged: 1}, result)
+
+	// New on the server: inserted as already synced, with nothing queued
+	inserted, err := taskService.GetTaskByID(fresh.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Only on server", inserted.Title)
+	assert.Equal(t, models.SyncStatusSynced, inserted.SyncStatus)
+	assert.Equal(t, "srv_fresh", *inserted.ServerID)
+	assert.Equal(t, map[string]interface{}{"origin": "server"}, inserted.Metadata)
+
+	// Newer on the server with no local changes: takes the server's copy
+	updated, err := taskService.GetTaskByID(newer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed on server", updated.Title)
+
+	// Older on the server: the local copy stands
+	kept, err := taskService.GetTaskByID(older.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Older on server", kept.Title)
+
+	// Newer on the server with local changes: last_write_wins picks the server
+	resolved, err := taskService.GetTaskByID(edited.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Edited on server", resolved.Title)
+
+	conflicts, total, err := syncService.GetConflicts(10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, edited.ID, conflicts[0].TaskID)
+
+	queue, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	for _, item := range queue {
+		assert.NotEqual(t, fresh.ID, item.TaskID)
+		assert.NotEqual(t, newer.ID, item.TaskID)
+	}
+}
+
+func TestSyncService_PullUnsupported(t *testing.T) {
+	_, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	syncService.SetClient(&fakeSyncClient{})
+	_, err := syncService.Pull(context.Background(), time.Time{})
+	assert.ErrorIs(t, err, syncclient.ErrPullUnsupported)
 }
@@ -0,0 +1,637 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/config"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/database"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/services"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/syncclient"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncClient_Methods(t *testing.T) {
+	var gotMethod, gotPath, gotContentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath, gotContentType = r.Method, r.URL.Path, r.Header.Get("Content-Type")
+
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var task models.Task
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&task))
+		task.ServerID = stringPtr("srv_" + task.ID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&task)
+	}))
+	defer server.Close()
+
+	client := syncclient.NewClient(server.URL+"/", server.Client())
+	task := models.NewTask("Remote task", nil)
+
+	created, err := client.CreateTask(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, "POST", gotMethod)
+	assert.Equal(t, "/tasks", gotPath)
+	assert.Equal(t, "application/json", gotContentType)
+	assert.Equal(t, "srv_"+task.ID, *created.ServerID)
+	assert.Equal(t, "Remote task", created.Title)
+
+	updated, err := client.UpdateTask(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, "PUT", gotMethod)
+	assert.Equal(t, "/tasks/"+task.ID, gotPath)
+	assert.Equal(t, task.ID, updated.ID)
+
+	err = client.DeleteTask(context.Background(), task)
+	require.NoError(t, err)
+	assert.Equal(t, "DELETE", gotMethod)
+	assert.Equal(t, "/tasks/"+task.ID, gotPath)
+}
+
+func TestSyncClient_ErrorMapping(t *testing.T) {
+	remote := models.NewTask("Server copy", nil)
+
+	cases := []struct {
+		name       string
+		status     int
+		body       interface{}
+		wantErr    error
+		wantRemote bool
+	}{
+		{name: "conflict", status: http.StatusConflict, body: remote, wantErr: syncclient.ErrConflict, wantRemote: true},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: syncclient.ErrServerUnavailable},
+		{name: "bad gateway", status: http.StatusBadGateway, wantErr: syncclient.ErrServerUnavailable},
+		{name: "not a task", status: http.StatusOK, body: []string{"garbage"}, wantErr: syncclient.ErrInvalidPayload},
+		{name: "missing id", status: http.StatusOK, body: map[string]string{"title": "No id"}, wantErr: syncclient.ErrInvalidPayload},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				if tc.body != nil {
+					json.NewEncoder(w).Encode(tc.body)
+				}
+			}))
+			defer server.Close()
+
+			client := syncclient.NewClient(server.URL, server.Client())
+			got, err := client.UpdateTask(context.Background(), models.NewTask("Local copy", nil))
+
+			assert.ErrorIs(t, err, tc.wantErr)
+			if tc.wantRemote {
+				require.NotNil(t, got)
+				assert.Equal(t, "Server copy", got.Title)
+			} else {
+				assert.Nil(t, got)
+			}
+		})
+	}
+
+	// Other client errors are not mistaken for conflicts or outages
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	_, err := syncclient.NewClient(server.URL, server.Client()).CreateTask(context.Background(), models.NewTask("Bad", nil))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, syncclient.ErrConflict)
+	assert.NotErrorIs(t, err, syncclient.ErrServerUnavailable)
+
+	// A delete's reply body is not read as a task
+	deleteServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"message": "deleted"})
+	}))
+	defer deleteServer.Close()
+	err = syncclient.NewClient(deleteServer.URL, deleteServer.Client()).DeleteTask(context.Background(), models.NewTask("Gone", nil))
+	assert.NoError(t, err)
+
+	// An unreachable server is reported as unavailable
+	server.Close()
+	_, err = syncclient.NewClient(server.URL, server.Client()).CreateTask(context.Background(), models.NewTask("Offline", nil))
+	assert.ErrorIs(t, err, syncclient.ErrServerUnavailable)
+}
+
+func TestSyncService_BatchSyncMixedResults(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	ok, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Succeeds"})
+	require.NoError(t, err)
+	conflicting, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Conflicts"})
+	require.NoError(t, err)
+	failing, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Fails"})
+	require.NoError(t, err)
+
+	var batchCalls, itemCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/batch" {
+			itemCalls++
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		batchCalls++
+
+		var req struct {
+			Items []syncclient.BatchItem `json:"items"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		var results []syncclient.BatchResult
+		for _, item := range req.Items {
+			result := syncclient.BatchResult{ID: item.ID, ClientID: item.TaskID}
+			switch item.TaskID {
+			case ok.ID:
+				result.Status = syncclient.BatchStatusSuccess
+				result.ServerID = "srv_ok"
+			case conflicting.ID:
+				remote := *item.Data
+				remote.Title = "Server title"
+				remote.UpdatedAt = remote.UpdatedAt.Add(time.Hour)
+				result.Status = syncclient.BatchStatusConflict
+				result.ResolvedData = &remote
+			case failing.ID:
+				result.Status = syncclient.BatchStatusError
+				result.Error = "validation failed"
+			}
+			results = append(results, result)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"processed_items": results})
+	}))
+	defer server.Close()
+
+	syncService.SetClient(syncclient.NewClient(server.URL, server.Client()))
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	assert.Equal(t, 1, batchCalls)
+	assert.Equal(t, 0, itemCalls)
+
+	stored, err := taskService.GetTaskByID(ok.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+	assert.Equal(t, "srv_ok", *stored.ServerID)
+
+	stored, err = taskService.GetTaskByID(conflicting.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Server title", stored.Title)
+
+	var retryCount int
+	var errorMessage string
+	err = db.QueryRow("SELECT retry_count, error_message FROM sync_queue WHERE task_id = ?", failing.ID).Scan(&retryCount, &errorMessage)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retryCount)
+	assert.Equal(t, "validation failed", errorMessage)
+
+	var queueCount int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue").Scan(&queueCount))
+	assert.Equal(t, 1, queueCount)
+}
+
+func TestSyncService_BatchSyncRequestFailure(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	corrupt, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Corrupt"})
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE sync_queue SET task_data = '{"id": "x", "title": ""}' WHERE task_id = ?`, corrupt.ID)
+	require.NoError(t, err)
+	sent, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Sent"})
+	require.NoError(t, err)
+
+	var batchSizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Items []syncclient.BatchItem `json:"items"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		batchSizes = append(batchSizes, len(req.Items))
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	syncService.SetClient(syncclient.NewClient(server.URL, server.Client()))
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, []int{1}, batchSizes)
+
+	// Only the item that was in the failed request is charged a retry; the one
+	// dead-lettered beforehand keeps its reason
+	var retryCount int
+	var errorMessage string
+	err = db.QueryRow("SELECT retry_count, error_message FROM sync_queue WHERE task_id = ?", sent.ID).Scan(&retryCount, &errorMessage)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retryCount)
+
+	err = db.QueryRow("SELECT retry_count, error_message FROM sync_queue WHERE task_id = ?", corrupt.ID).Scan(&retryCount, &errorMessage)
+	require.NoError(t, err)
+	assert.Equal(t, 3, retryCount)
+	assert.Contains(t, errorMessage, "invalid task data")
+}
+
+func TestSyncService_BatchSyncFallsBackPerItem(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	_, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "One"})
+	require.NoError(t, err)
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Two"})
+	require.NoError(t, err)
+
+	var batchCalls, itemCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/batch" {
+			batchCalls++
+			http.NotFound(w, r)
+			return
+		}
+		itemCalls++
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	syncService.SetClient(syncclient.NewClient(server.URL, server.Client()))
+	require.NoError(t, syncService.ProcessSyncQueue())
+
+	assert.Equal(t, 1, batchCalls)
+	assert.Equal(t, 2, itemCalls)
+
+	var queueCount int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_queue").Scan(&queueCount))
+	assert.Equal(t, 0, queueCount)
+
+	// The unsupported batch endpoint is not retried on the next run
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Three"})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, 1, batchCalls)
+	assert.Equal(t, 3, itemCalls)
+}
+
+func TestSyncService_ItemTimeoutIsRetried(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:    ":memory:",
+		SyncBatchSize:   5,
+		MaxRetries:      3,
+		SyncItemTimeout: 50 * time.Millisecond,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+
+	slow, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Slow"})
+	require.NoError(t, err)
+	fast, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Fast"})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/batch" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var task models.Task
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&task))
+		if task.Title == "Slow" {
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		task.ServerID = stringPtr("srv_" + task.ID)
+		json.NewEncoder(w).Encode(task)
+	}))
+	defer server.Close()
+
+	syncService.SetClient(syncclient.NewClient(server.URL, server.Client()))
+
+	started := time.Now()
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Less(t, time.Since(started), time.Second)
+
+	// The slow item failed and waits for a retry; the fast one still synced
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, slow.ID, items[0].TaskID)
+	assert.Equal(t, 1, items[0].RetryCount)
+	require.NotNil(t, items[0].ErrorMessage)
+	assert.Contains(t, *items[0].ErrorMessage, syncclient.ErrServerUnavailable.Error())
+
+	stored, err := taskService.GetTaskByID(fast.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+}
+
+func TestSyncClient_RetryAfter(t *testing.T) {
+	cases := []struct {
+		name      string
+		status    int
+		header    string
+		wantDelay time.Duration
+		wantHint  bool
+	}{
+		{name: "seconds on 503", status: http.StatusServiceUnavailable, header: "120", wantDelay: 2 * time.Minute, wantHint: true},
+		{name: "seconds on 429", status: http.StatusTooManyRequests, header: "5", wantDelay: 5 * time.Second, wantHint: true},
+		{name: "past date", status: http.StatusServiceUnavailable, header: "Mon, 02 Jan 2006 15:04:05 GMT", wantHint: true},
+		{name: "no header", status: http.StatusServiceUnavailable},
+		{name: "garbage", status: http.StatusTooManyRequests, header: "soon"},
+		{name: "ignored on 500", status: http.StatusInternalServerError, header: "120"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.header != "" {
+					w.Header().Set("Retry-After", tc.header)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			client := syncclient.NewClient(server.URL, server.Client())
+			_, err := client.UpdateTask(context.Background(), models.NewTask("Busy", nil))
+			assert.ErrorIs(t, err, syncclient.ErrServerUnavailable)
+
+			delay, ok := syncclient.RetryAfter(err)
+			assert.Equal(t, tc.wantHint, ok)
+			assert.Equal(t, tc.wantDelay, delay)
+		})
+	}
+
+	// An HTTP date in the future is turned into a delay
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := syncclient.NewClient(server.URL, server.Client()).CreateTask(context.Background(), models.NewTask("Later", nil))
+	delay, ok := syncclient.RetryAfter(err)
+	require.True(t, ok)
+	assert.InDelta(t, time.Hour.Seconds(), delay.Seconds(), 5)
+}
+
+func TestSyncService_HonorsRetryAfter(t *testing.T) {
+	cfg := &config.Config{
+		DatabasePath:   ":memory:",
+		SyncBatchSize:  5,
+		MaxRetries:     3,
+		RetryBaseDelay: time.Second,
+	}
+	db, err := database.NewSQLiteDB(cfg.DatabasePath)
+	require.NoError(t, err)
+	defer db.Close()
+
+	syncService := services.NewSyncService(db, cfg)
+	taskService := services.NewTaskService(db, syncService)
+
+	_, err = taskService.CreateTask(&models.CreateTaskRequest{Title: "Throttled"})
+	require.NoError(t, err)
+
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/batch" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		calls++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	syncService.SetClient(syncclient.NewClient(server.URL, server.Client()))
+
+	before := time.Now()
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, 1, calls)
+
+	// The server's two minutes win over our one second of backoff
+	items, err := syncService.GetSyncQueueContents()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.NotNil(t, items[0].ServerRetryAfter)
+	assert.Equal(t, 2*time.Minute, *items[0].ServerRetryAfter)
+	require.NotNil(t, items[0].NextAttemptAt)
+	assert.WithinDuration(t, before.Add(2*time.Minute), *items[0].NextAttemptAt, 5*time.Second)
+
+	// The item is skipped until the server's deadline passes
+	plan, err := syncService.DryRunSync()
+	require.NoError(t, err)
+	assert.Empty(t, plan)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, 1, calls)
+}
+
+func TestSyncService_InvalidPayloadsAreDeadLettered(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	t.Run("queued payload", func(t *testing.T) {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Corrupted in the queue"})
+		require.NoError(t, err)
+		_, err = db.Exec(`UPDATE sync_queue SET task_data = ? WHERE task_id = ?`,
+			`{"id": "`+task.ID+`", "title": ""}`, task.ID)
+		require.NoError(t, err)
+
+		fake := &fakeSyncClient{}
+		syncService.SetClient(fake)
+		require.NoError(t, syncService.ProcessSyncQueue())
+		assert.Empty(t, fake.calls)
+
+		items, err := syncService.ListSyncQueue(&models.SyncQueueFilter{MinRetries: 3})
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, task.ID, items[0].TaskID)
+		assert.Contains(t, *items[0].ErrorMessage, "invalid task data")
+
+		stored, err := taskService.GetTaskByID(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusError, stored.SyncStatus)
+	})
+
+	t.Run("server copy", func(t *testing.T) {
+		require.NoError(t, syncService.ResetQueue(false))
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Local copy"})
+		require.NoError(t, err)
+
+		// The server claims a conflict but sends back a copy with a bad status
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/batch" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			remote := *task
+			remote.Title = "Garbage"
+			remote.SyncStatus = "unknown"
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(&remote)
+		}))
+		defer server.Close()
+
+		syncService.SetClient(syncclient.NewClient(server.URL, server.Client()))
+		require.NoError(t, syncService.ProcessSyncQueue())
+
+		stored, err := taskService.GetTaskByID(task.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Local copy", stored.Title)
+		assert.Equal(t, models.SyncStatusError, stored.SyncStatus)
+
+		status, err := syncService.GetSyncStatus()
+		require.NoError(t, err)
+		assert.Equal(t, 1, status.DeadLetterCount)
+		assert.Equal(t, 0, status.PendingCount)
+
+		var conflicts int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM sync_conflicts").Scan(&conflicts))
+		assert.Zero(t, conflicts)
+	})
+}
+
+func TestSyncClient_ListTasks(t *testing.T) {
+	task := models.NewTask("Listed", nil)
+	var gotSince string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/tasks", r.URL.Path)
+		gotSince = r.URL.Query().Get("since")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode([]*models.Task{task})
+	}))
+	defer server.Close()
+
+	client := syncclient.NewClient(server.URL, server.Client())
+
+	tasks, err := client.ListTasks(context.Background(), time.Time{})
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, task.ID, tasks[0].ID)
+	assert.Empty(t, gotSince)
+
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	_, err = client.ListTasks(context.Background(), since)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-01T12:00:00Z", gotSince)
+
+	status = http.StatusNotFound
+	_, err = client.ListTasks(context.Background(), since)
+	assert.ErrorIs(t, err, syncclient.ErrPullUnsupported)
+
+	status = http.StatusServiceUnavailable
+	_, err = client.ListTasks(context.Background(), since)
+	assert.ErrorIs(t, err, syncclient.ErrServerUnavailable)
+}
+
+func TestSyncService_Transform(t *testing.T) {
+	redact := func(task *models.Task) (*models.Task, error) {
+		task.Description = stringPtr("[redacted]")
+		return task, nil
+	}
+
+	for _, batch := range []bool{false, true} {
+		name := "per item"
+		if batch {
+			name = "batch"
+		}
+		t.Run(name, func(t *testing.T) {
+			taskService, syncService, _, cleanup := setupTestServices()
+			defer cleanup()
+
+			var received []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.URL.Path == "/batch" {
+					if !batch {
+						http.NotFound(w, r)
+						return
+					}
+					var req struct {
+						Items []syncclient.BatchItem `json:"items"`
+					}
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+					var results []syncclient.BatchResult
+					for _, item := range req.Items {
+						received = append(received, *item.Data.Description)
+						// The server stamps its own write, which is not a conflict
+						remote := *item.Data
+						remote.UpdatedAt = remote.UpdatedAt.Add(time.Hour)
+						results = append(results, syncclient.BatchResult{
+							ID: item.ID, ServerID: "srv_" + item.TaskID, Status: syncclient.BatchStatusSuccess, ResolvedData: &remote,
+						})
+					}
+					json.NewEncoder(w).Encode(map[string]interface{}{"processed_items": results})
+					return
+				}
+
+				var task models.Task
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&task))
+				received = append(received, *task.Description)
+				task.ServerID = stringPtr("srv_" + task.ID)
+				task.UpdatedAt = task.UpdatedAt.Add(time.Hour)
+				json.NewEncoder(w).Encode(&task)
+			}))
+			defer server.Close()
+
+			syncService.SetClient(syncclient.NewClient(server.URL, server.Client()))
+			syncService.SetTransform(redact)
+
+			task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Private", Description: stringPtr("internal notes")})
+			require.NoError(t, err)
+			require.NoError(t, syncService.ProcessSyncQueue())
+
+			assert.Equal(t, []string{"[redacted]"}, received)
+
+			stored, err := taskService.GetTaskByID(task.ID)
+			require.NoError(t, err)
+			assert.Equal(t, models.SyncStatusSynced, stored.SyncStatus)
+			assert.Equal(t, "internal notes", *stored.Description, "only the pushed copy is redacted")
+		})
+	}
+}
+
+func TestSyncService_TransformError(t *testing.T) {
+	taskService, syncService, db, cleanup := setupTestServices()
+	defer cleanup()
+
+	fake := &fakeSyncClient{}
+	syncService.SetClient(fake)
+	syncService.SetTransform(func(task *models.Task) (*models.Task, error) {
+		return nil, errors.New("enrichment service down")
+	})
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Enriched"})
+	require.NoError(t, err)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Empty(t, fake.calls, "nothing is pushed when the transform fails")
+
+	var retryCount int
+	var errorMessage string
+	err = db.QueryRow("SELECT retry_count, error_message FROM sync_queue WHERE task_id = ?", task.ID).Scan(&retryCount, &errorMessage)
+	require.NoError(t, err)
+	assert.Equal(t, 1, retryCount)
+	assert.Contains(t, errorMessage, "enrichment service down")
+
+	// Without the hook the item goes through unchanged
+	syncService.SetTransform(nil)
+	require.NoError(t, syncService.ProcessSyncQueue())
+	assert.Equal(t, []string{"create:" + task.ID}, fake.calls)
+}
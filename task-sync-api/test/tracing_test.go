@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/syncclient"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useInMemoryTracing routes spans to a fresh in-memory exporter until the test ends.
+func useInMemoryTracing(t *testing.T) *tracing.InMemoryExporter {
+	exporter := tracing.NewInMemoryExporter()
+	tracing.SetProvider(tracing.NewProvider(exporter))
+	t.Cleanup(func() { tracing.SetProvider(nil) })
+	return exporter
+}
+
+// findSpan returns the exported span with the given name.
+func findSpan(t *testing.T, spans []tracing.SpanData, name string) tracing.SpanData {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not found", "no span named %q", name)
+	return tracing.SpanData{}
+}
+
+func TestTracing_CreateTaskSpanTree(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const callerSpanID = "00f067aa0ba902b7"
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Traced"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-"+callerSpanID+"-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	spans := exporter.Spans()
+	server := findSpan(t, spans, "POST /api/tasks")
+	assert.Equal(t, tracing.SpanKindServer, server.Kind)
+	assert.Equal(t, traceID, server.SpanContext.TraceID)
+	assert.Equal(t, callerSpanID, server.ParentSpanID)
+	assert.Equal(t, "201", server.Attributes["http.status_code"])
+	assert.Empty(t, server.Error)
+
+	create := findSpan(t, spans, "TaskService.CreateTask")
+	assert.Equal(t, tracing.SpanKindInternal, create.Kind)
+	assert.Equal(t, traceID, create.SpanContext.TraceID)
+	assert.Equal(t, server.SpanContext.SpanID, create.ParentSpanID)
+	assert.False(t, create.End.After(server.End))
+
+	// Every span belongs to the caller's trace
+	for _, span := range spans {
+		assert.Equal(t, traceID, span.SpanContext.TraceID, span.Name)
+	}
+}
+
+func TestTracing_UnsampledParent(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+	router, cleanup := setupTestApp()
+	defer cleanup()
+
+	const unsampled = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"
+
+	body, _ := json.Marshal(models.CreateTaskRequest{Title: "Not traced"})
+	req, _ := http.NewRequest("POST", "/api/tasks", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", unsampled)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, exporter.Spans(), "an unsampled trace exports nothing")
+
+	header := http.Header{}
+	header.Set("traceparent", unsampled)
+	ctx, span := tracing.Start(tracing.Extract(context.Background(), header), "child", tracing.SpanKindClient)
+	span.End()
+	sc := tracing.SpanContextFromContext(ctx)
+	assert.False(t, sc.Sampled, "a child keeps its parent's sampling decision")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+	assert.True(t, strings.HasSuffix(sc.Traceparent(), "-00"), sc.Traceparent())
+
+	ctx, root := tracing.Start(context.Background(), "root", tracing.SpanKindInternal)
+	root.End()
+	assert.True(t, tracing.SpanContextFromContext(ctx).Sampled, "a new trace is sampled")
+	assert.Len(t, exporter.Spans(), 1)
+}
+
+func TestTracing_SyncPropagatesTraceparent(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+
+	var traceparent string
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Without a batch endpoint each item is pushed by processSyncItem
+		if r.URL.Path == "/batch" {
+			http.NotFound(w, r)
+			return
+		}
+		traceparent = r.Header.Get("traceparent")
+		var task models.Task
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&task))
+		task.ServerID = stringPtr("srv_" + task.ID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&task)
+	}))
+	defer remote.Close()
+
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+	syncService.SetClient(syncclient.NewClient(remote.URL, remote.Client()))
+
+	task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: "Pushed"})
+	require.NoError(t, err)
+	exporter.Reset()
+
+	ctx, pass := tracing.Start(context.Background(), "sync pass", tracing.SpanKindInternal)
+	require.NoError(t, syncService.ProcessSyncQueueWithOptions(ctx, syncService.DefaultSyncOptions()))
+	pass.End()
+
+	spans := exporter.Spans()
+	item := findSpan(t, spans, "SyncService.processSyncItem")
+	root := findSpan(t, spans, "sync pass")
+	assert.Equal(t, root.SpanContext.SpanID, item.ParentSpanID)
+	assert.Equal(t, task.ID, item.Attributes["task.id"])
+	assert.Equal(t, "create", item.Attributes["sync.operation"])
+
+	var client tracing.SpanData
+	for _, span := range spans {
+		if span.Name == "sync server POST" && span.Attributes["http.url"] == remote.URL+"/tasks" {
+			client = span
+		}
+	}
+	assert.Equal(t, tracing.SpanKindClient, client.Kind)
+	assert.Equal(t, item.SpanContext.SpanID, client.ParentSpanID)
+
+	sc, ok := tracing.ParseTraceparent(traceparent)
+	require.True(t, ok, traceparent)
+	assert.Equal(t, client.SpanContext, sc)
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+
+	for _, value := range []string{
+		"",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+	} {
+		_, ok := tracing.ParseTraceparent(value)
+		assert.False(t, ok, value)
+	}
+}
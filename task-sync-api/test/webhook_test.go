@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/models"
+	"github.com/pearlthoughts/backend-interview-challenge-1/task-sync-api/internal/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier_DeliversWithRetry(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	attempts := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		if attempts == 1 {
+			// Fail the first delivery to exercise the retry
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received = append(received, payload)
+	}))
+	defer server.Close()
+
+	notifier := webhook.NewWebhookNotifier(server.URL, server.Client(), 3)
+	task := models.NewTask("Webhook task", nil)
+
+	notifier.Notify(models.TaskEventCreated, task)
+	notifier.Notify(models.TaskEventDeleted, task)
+	notifier.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.Equal(t, 3, attempts)
+	require.Len(t, received, 2)
+	assert.Equal(t, "task.created", received[0]["event"])
+	assert.Equal(t, "task.deleted", received[1]["event"])
+
+	payloadTask := received[0]["task"].(map[string]interface{})
+	assert.Equal(t, task.ID, payloadTask["id"])
+	assert.Equal(t, "Webhook task", payloadTask["title"])
+}
+
+func TestSyncService_RunWebhook(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	taskService, syncService, _, cleanup := setupTestServices()
+	defer cleanup()
+
+	notifier := webhook.NewWebhookNotifier(server.URL, server.Client(), 0)
+	syncService.SetRunNotifier(notifier)
+
+	var tasks []*models.Task
+	for _, title := range []string{"Pushed", "Also pushed", "Rejected"} {
+		task, err := taskService.CreateTask(&models.CreateTaskRequest{Title: title})
+		require.NoError(t, err)
+		tasks = append(tasks, task)
+	}
+	syncService.SetClient(&fakeSyncClient{failTasks: map[string]error{tasks[2].ID: errors.New("rejected")}})
+
+	// With one retry allowed, the rejected item is dead-lettered by this pass
+	opts := syncService.DefaultSyncOptions()
+	opts.MaxRetries = 1
+	require.NoError(t, syncService.ProcessSyncQueueWithOptions(context.Background(), opts))
+	notifier.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 1)
+	summary := received[0]
+	assert.Equal(t, models.SyncRunCompleted, summary["event"])
+	assert.Equal(t, float64(3), summary["processed"])
+	assert.Equal(t, float64(2), summary["succeeded"])
+	assert.Equal(t, float64(1), summary["failed"])
+	assert.Equal(t, float64(1), summary["dead_lettered"])
+	assert.NotEmpty(t, summary["started_at"])
+	assert.NotEmpty(t, summary["finished_at"])
+}